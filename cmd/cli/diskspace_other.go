@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+// freeDiskSpace 当前系统不支持检查磁盘空间，返回的第二个值为false
+func freeDiskSpace(dir string) (uint64, bool, error) {
+	return 0, false, nil
+}
@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package main
+
+import "syscall"
+
+// freeDiskSpace 返回目录所在文件系统中当前用户可用的字节数
+func freeDiskSpace(dir string) (uint64, bool, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, true, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/nerdneilsfield/go-mistral-ocr/internal/config"
+	"github.com/nerdneilsfield/go-mistral-ocr/pkg/ocr"
+)
+
+// doctorMinFreeBytes 输出目录所在磁盘的最小可用空间，低于该值时诊断不通过
+const doctorMinFreeBytes = 1 << 30
+
+// doctorStatus 表示单项检查的结果
+type doctorStatus int
+
+const (
+	doctorPass doctorStatus = iota // 通过
+	doctorWarn                     // 需要注意，但不影响使用
+	doctorFail                     // 未通过
+)
+
+// doctorCheck 表示 doctor 命令的一项检查
+type doctorCheck struct {
+	name   string
+	status doctorStatus
+	detail string
+	hint   string // 未通过或需要注意时的修复建议
+}
+
+// runDoctor 检查配置、API密钥、端点连通性、输出目录和磁盘空间，输出检查清单
+func runDoctor(cmd *cobra.Command, args []string) error {
+	var checks []doctorCheck
+
+	loaded, err := config.LoadConfigForDiagnosis(configFile)
+	checks = append(checks, checkConfigFile(err))
+	if err != nil {
+		// 配置文件无法解析时，后续检查使用默认值和命令行参数
+		loaded = &config.Config{BaseURLs: []string{"https://api.mistral.ai/v1/"}, OutputDir: "./output"}
+	}
+	cfg = loaded
+	if err := updateConfigFromFlags(cmd, zap.NewNop()); err != nil {
+		return err
+	}
+
+	checks = append(checks, checkAPIKeys(cfg.APIKeys))
+	if cfg.KeychainError != nil {
+		checks = append(checks, doctorCheck{
+			name:   "系统钥匙串",
+			status: doctorWarn,
+			detail: cfg.KeychainError.Error(),
+			hint:   "使用 config set-api-key --keychain 保存密钥；Linux和FreeBSD的发布版本及默认编译不包含钥匙串支持，需要使用 -tags keychain 重新编译",
+		})
+	}
+	checks = append(checks, checkEndpoints(cmd, cfg)...)
+	checks = append(checks, checkOutputDir(cfg.OutputDir))
+	checks = append(checks, checkDiskSpace(cfg.OutputDir))
+
+	failed := 0
+	for _, check := range checks {
+		mark := "✓"
+		switch check.status {
+		case doctorWarn:
+			mark = "!"
+		case doctorFail:
+			mark = "✗"
+			failed++
+		}
+		fmt.Printf("[%s] %s: %s\n", mark, check.name, check.detail)
+		if check.status != doctorPass && check.hint != "" {
+			fmt.Printf("    建议: %s\n", check.hint)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("发现 %d 个问题", failed)
+	}
+	fmt.Println("\n未发现问题")
+	return nil
+}
+
+// checkConfigFile 检查配置文件是否找到并能解析
+func checkConfigFile(loadErr error) doctorCheck {
+	check := doctorCheck{name: "配置文件"}
+	path := config.ConfigFileUsed()
+	switch {
+	case loadErr != nil:
+		check.status = doctorFail
+		check.detail = loadErr.Error()
+		check.hint = "检查配置文件的TOML语法，或运行 mistral-ocr config gen -o <路径> 重新生成配置文件"
+	case configFile == config.StdinConfigPath:
+		check.detail = "(标准输入)"
+	case path == "":
+		check.status = doctorWarn
+		check.detail = "未找到配置文件，使用默认值、环境变量和命令行参数"
+		check.hint = "运行 mistral-ocr config gen -o ~/.config/mistral-ocr/config.toml 生成配置文件"
+	default:
+		check.detail = path
+	}
+	return check
+}
+
+// checkAPIKeys 检查是否至少配置了一个非空的API密钥
+func checkAPIKeys(keys []string) doctorCheck {
+	check := doctorCheck{name: "API密钥"}
+	if !hasAPIKey(keys) {
+		check.status = doctorFail
+		check.detail = "没有配置非空的API密钥"
+		check.hint = "在配置文件的 api_keys 中设置，或使用 --api-keys 参数、MISTRAL_API_KEY 环境变量"
+		return check
+	}
+	n := 0
+	for _, key := range keys {
+		if strings.TrimSpace(key) != "" {
+			n++
+		}
+	}
+	check.detail = fmt.Sprintf("%d 个", n)
+	return check
+}
+
+// checkEndpoints 探测每个端点是否可以连接，端点返回认证错误也视为可以连接
+func checkEndpoints(cmd *cobra.Command, cfg *config.Config) []doctorCheck {
+	client, err := ocr.NewClientWithOptions(clientOptions(cfg))
+	if err != nil {
+		return []doctorCheck{{
+			name:   "API端点",
+			status: doctorFail,
+			detail: fmt.Sprintf("创建OCR客户端失败: %v", err),
+			hint:   "检查 ca_cert_file 指向的证书文件是否存在且为PEM格式",
+		}}
+	}
+
+	var checks []doctorCheck
+	for _, health := range client.WarmupEndpoints(cmd.Context()) {
+		check := doctorCheck{name: "API端点 " + health.BaseURL}
+		if health.Healthy {
+			check.detail = fmt.Sprintf("可以连接，耗时 %v", roundStat(health.Latency))
+		} else {
+			check.status = doctorFail
+			check.detail = health.Error
+			check.hint = "检查网络和代理设置，以及 base_urls 中的地址是否正确"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkOutputDir 检查输出目录是否可写，目录不存在时检查能否在其最近的已有上级目录中创建
+func checkOutputDir(outputDir string) doctorCheck {
+	check := doctorCheck{name: "输出目录"}
+	if outputDir == "" {
+		outputDir = "."
+	}
+	dir, err := existingParent(outputDir)
+	if err != nil {
+		check.status = doctorFail
+		check.detail = err.Error()
+		check.hint = "使用 --output-dir 参数或配置文件的 output_dir 指定其他目录"
+		return check
+	}
+
+	probe, err := os.CreateTemp(dir, ".mistral-ocr-doctor-*")
+	if err != nil {
+		check.status = doctorFail
+		check.detail = fmt.Sprintf("%s 不可写: %v", dir, err)
+		check.hint = "检查目录权限，或使用 --output-dir 参数指定其他目录"
+		return check
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	check.detail = outputDir + " 可写"
+	if dir != filepath.Clean(outputDir) {
+		check.detail = fmt.Sprintf("%s 不存在，将在 %s 中创建", outputDir, dir)
+	}
+	return check
+}
+
+// checkDiskSpace 检查输出目录所在磁盘的可用空间
+func checkDiskSpace(outputDir string) doctorCheck {
+	check := doctorCheck{name: "磁盘空间"}
+	if outputDir == "" {
+		outputDir = "."
+	}
+	dir, err := existingParent(outputDir)
+	if err != nil {
+		check.status = doctorWarn
+		check.detail = err.Error()
+		return check
+	}
+	free, ok, err := freeDiskSpace(dir)
+	switch {
+	case !ok:
+		check.status = doctorWarn
+		check.detail = "当前系统不支持检查磁盘空间"
+	case err != nil:
+		check.status = doctorWarn
+		check.detail = fmt.Sprintf("获取可用空间失败: %v", err)
+	case free < doctorMinFreeBytes:
+		check.status = doctorFail
+		check.detail = fmt.Sprintf("可用空间 %s，低于 %s", formatBytes(free), formatBytes(doctorMinFreeBytes))
+		check.hint = "清理磁盘，或使用 --output-dir 参数将输出保存到其他磁盘"
+	default:
+		check.detail = "可用空间 " + formatBytes(free)
+	}
+	return check
+}
+
+// existingParent 返回路径本身或其最近的已存在的上级目录
+func existingParent(path string) (string, error) {
+	dir := filepath.Clean(path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return "", fmt.Errorf("%s 不是目录", dir)
+			}
+			return dir, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("获取目录信息失败: %w", err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("找不到 %s 的上级目录", path)
+		}
+		dir = parent
+	}
+}
+
+// formatBytes 将字节数格式化为 MB 或 GB
+func formatBytes(n uint64) string {
+	if n >= 1<<30 {
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nerdneilsfield/go-mistral-ocr/pkg/ocr"
+)
+
+// 程序退出码
+const (
+	exitOK             = 0   // 处理成功
+	exitUsage          = 2   // 配置错误或命令行用法错误
+	exitPartialFailure = 3   // 批量处理中部分文件失败
+	exitFailure        = 4   // 处理失败（单个文件失败或批量处理中所有文件失败）
+	exitAuth           = 5   // API认证失败
+	exitInterrupted    = 130 // 收到中断信号
+)
+
+// usageError 表示配置错误或命令行用法错误
+type usageError struct {
+	err error
+}
+
+// Error 实现 error 接口
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// newUsageError 将错误标记为配置或用法错误，err为nil时返回nil
+func newUsageError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &usageError{err: err}
+}
+
+// partialFailureError 表示启用 continue_on_error 时批量处理中有文件失败
+type partialFailureError struct {
+	summary *ocr.BatchSummary
+}
+
+// Error 实现 error 接口
+func (e *partialFailureError) Error() string {
+	return fmt.Sprintf("部分文件处理失败，成功 %d 个，失败 %d 个", e.summary.Succeeded, e.summary.Failed)
+}
+
+// checkPartialFailure 批次摘要中有失败的文件时返回 *partialFailureError
+func checkPartialFailure(summary *ocr.BatchSummary) error {
+	if summary == nil || summary.Failed == 0 {
+		return nil
+	}
+	return &partialFailureError{summary: summary}
+}
+
+// markArgsErrorsAsUsage 将命令及其子命令的参数校验错误标记为用法错误
+func markArgsErrorsAsUsage(cmd *cobra.Command) {
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(c *cobra.Command, a []string) error {
+			return newUsageError(args(c, a))
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markArgsErrorsAsUsage(sub)
+	}
+}
+
+// exitCode 根据命令返回的错误确定退出码
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var usageErr *usageError
+	var partialErr *partialFailureError
+	var batchErr *ocr.BatchError
+	switch {
+	// 未知子命令的错误由cobra直接生成，只能通过错误信息识别
+	case errors.As(err, &usageErr), strings.HasPrefix(err.Error(), "unknown command"):
+		return exitUsage
+	case ocr.IsAuthError(err):
+		return exitAuth
+	case errors.As(err, &partialErr):
+		return exitPartialFailure
+	case errors.As(err, &batchErr) && len(batchErr.Results) > 0:
+		return exitPartialFailure
+	default:
+		return exitFailure
+	}
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -13,24 +22,86 @@ import (
 	"github.com/nerdneilsfield/go-mistral-ocr/internal/config"
 	"github.com/nerdneilsfield/go-mistral-ocr/internal/logger"
 	"github.com/nerdneilsfield/go-mistral-ocr/pkg/ocr"
+	"github.com/nerdneilsfield/go-mistral-ocr/pkg/utils"
 )
 
 var (
 	// 默认配置
-	cfg *config.Config
-	log *zap.Logger
+	cfg    *config.Config
+	log    *zap.Logger
+	ocrLog *zap.Logger // 传给处理器的 ocr 模块日志记录器
 
 	// 命令行参数
-	configFile    string
-	apiKeys       []string
-	baseURLs      []string
-	outputDir     string
-	includeImages bool
-	outputName    string
-	logLevel      string
-	dryRun        bool
-	timeout       int
-	maxRetries    int
+	configFile     string
+	apiKeys        []string
+	baseURLs       []string
+	outputDir      string
+	includeImages  bool
+	outputName     string
+	nameTemplate   string
+	indexDB        string
+	logLevel       string
+	verbose        bool
+	debugLog       bool
+	dryRun         bool
+	timeout        string
+	ocrTimeout     string
+	maxBackoff     int
+	maxUploadMB    float64
+	maxOpenFiles   int
+	maxRetries     int
+	splitPages     bool
+	urlExpiry      int
+	cacheDir       string
+	noCache        bool
+	costPerPage    float64
+	warmup         bool
+	probeParallel  int
+	minPageText    int
+	showProgress   bool
+	separateRaw    bool
+	stripImages    bool
+	linkImages     bool
+	noImages       bool
+	imageLinkBase  string
+	generateTOC    bool
+	keepUpload     bool
+	appendTo       string
+	since          string
+	sinceTime      time.Time
+	fileModeStr    string
+	dirModeStr     string
+	fileMode       os.FileMode
+	dirMode        os.FileMode
+	dehyphenate    bool
+	caCertFile     string
+	insecureTLS    bool
+	streamLarge    bool
+	normalizeText  bool
+	halfWidth      bool
+	resumeFrom     string
+	bundleImages   string
+	atomicOutput   bool
+	corpusFile     string
+	skipTextPDFs   bool
+	pdfPasswords   []string
+	showStats      bool
+	outputBOM      bool
+	chunkPages     int
+	imageNaming    string
+	onImageError   string
+	maxPages       int
+	outputFormats  []string
+	dedupImages    bool
+	startAt        int
+	startAfter     string
+	sortOrder      string
+	stripImageMeta bool
+	mdTemplate     string
+	failFast       bool
+	continueOnErr  bool
+	archiveSource  bool
+	wordBoxes      bool
 )
 
 // 配置生成相关参数
@@ -38,18 +109,55 @@ var (
 	outputToFile string
 )
 
+// 文件处理相关参数
+var (
+	inputList string
+)
+
+// URL处理相关参数
+var (
+	forceImageURL    bool
+	fallbackToUpload bool
+)
+
+// 模型列表相关参数
+var (
+	listAllModels bool
+)
+
+// JSON转换相关参数
+var (
+	nameFromMetadata bool
+)
+
+// 合并输出目录相关参数
+var (
+	mergeOut string
+)
+
+// 设置API密钥相关参数
+var (
+	useKeychain bool
+)
+
 func main() {
 	// 创建根命令
 	rootCmd := &cobra.Command{
 		Use:   "mistral-ocr",
 		Short: "使用Mistral API进行OCR处理",
-		Long:  `使用Mistral API识别PDF文件或URL中的文本，并将结果保存为Markdown和文本格式。`,
+		Long: `使用Mistral API识别PDF文件或URL中的文本，并将结果保存为Markdown和文本格式。
+
+退出码：0 成功，2 配置或用法错误，3 批量处理中部分文件失败，4 处理失败，5 API认证失败，130 被中断。`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// 跳过gen命令的配置加载
-			if cmd.Name() == "gen" && cmd.Parent().Name() == "config" {
+			// 跳过gen和where命令的配置加载，where命令需要在配置无效时也能运行
+			if (cmd.Name() == "gen" || cmd.Name() == "where") && cmd.Parent().Name() == "config" {
+				return nil
+			}
+			// inspect命令只读取本地文件，不需要配置和API密钥；doctor命令自行加载配置并报告问题
+			if cmd.Name() == "inspect" || cmd.Name() == "doctor" {
 				return nil
 			}
-			return setup()
+			return newUsageError(setup(cmd))
 		},
 	}
 
@@ -57,9 +165,14 @@ func main() {
 	processFileCmd := &cobra.Command{
 		Use:   "file [文件路径或目录...]",
 		Short: "处理本地PDF文件或目录",
-		Long:  `处理一个或多个本地PDF文件，或者处理目录中的所有PDF文件。`,
-		Args:  cobra.MinimumNArgs(1),
-		RunE:  processFile,
+		Long:  `处理一个或多个本地PDF文件，或者处理目录中的所有PDF文件。路径较多时可以通过 --input-list 从文件读取。`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && inputList == "" {
+				return fmt.Errorf("需要至少一个文件路径或目录，或使用 --input-list 指定路径列表文件")
+			}
+			return nil
+		},
+		RunE: processFile,
 	}
 
 	// 处理URL命令
@@ -70,12 +183,59 @@ func main() {
 		RunE:  processURL,
 	}
 
+	// 自动识别来源的处理命令
+	processCmd := &cobra.Command{
+		Use:   "process [文件、目录、JSON文件或URL]",
+		Short: "自动识别来源类型并处理",
+		Long:  "根据参数自动选择处理方式：本地存在的路径优先按文件处理，.json文件转换为Markdown，目录处理其中的所有PDF文件，其他文件进行OCR；本地不存在的http(s)地址按URL处理。",
+		Args:  cobra.ExactArgs(1),
+		RunE:  processAuto,
+	}
+
+	// 检查输出目录命令
+	inspectCmd := &cobra.Command{
+		Use:   "inspect [输出目录]",
+		Short: "检查输出目录并显示处理结果摘要",
+		Long:  "读取输出目录中的metadata.json，校验格式并显示来源、页数、图片数量、模型和耗时，output.md缺失或为空时给出警告",
+		Args:  cobra.ExactArgs(1),
+		RunE:  inspectOutput,
+	}
+
+	// 合并输出目录命令
+	mergeCmd := &cobra.Command{
+		Use:   "merge [输出目录...] --out [合并目录]",
+		Short: "将多个输出目录合并为一个markdown文件",
+		Long:  "读取各输出目录（或批量输出根目录下各子目录）中的output.md和图片，生成带有目录的单个output.md，图片按来源目录分别存放避免重名，无需调用API",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  mergeOutputs,
+	}
+
+	// 模型列表命令
+	modelsCmd := &cobra.Command{
+		Use:   "models",
+		Short: "列出可用的OCR模型",
+		Long:  "从配置的API端点获取模型列表，默认只显示支持OCR的模型",
+		Args:  cobra.NoArgs,
+		RunE:  listModels,
+	}
+
+	// 诊断命令
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "诊断常见的配置问题",
+		Long:  "检查配置文件能否解析、是否配置了API密钥、各API端点能否连接、输出目录是否可写以及磁盘空间是否充足，输出检查清单和修复建议，不需要PDF文件",
+		Args:  cobra.NoArgs,
+		RunE:  runDoctor,
+		// 检查清单已经说明了问题，不再输出用法
+		SilenceUsage: true,
+	}
+
 	// 转换JSON命令
 	convertCmd := &cobra.Command{
-		Use:   "convert [JSON文件路径]",
+		Use:   "convert [JSON文件路径或目录...]",
 		Short: "将JSON文件转换为Markdown文件",
-		Long:  `将已有的OCR JSON响应文件转换为Markdown文件，无需重新调用API。`,
-		Args:  cobra.ExactArgs(1),
+		Long:  `将已有的OCR JSON响应文件转换为Markdown文件，无需重新调用API。指定目录时转换目录中的所有JSON文件。`,
+		Args:  cobra.MinimumNArgs(1),
 		RunE:  convertJSON,
 	}
 
@@ -89,15 +249,11 @@ func main() {
 	setAPIKeyCmd := &cobra.Command{
 		Use:   "set-api-key [API密钥]",
 		Short: "设置Mistral API密钥",
+		Long:  "将API密钥写入配置文件；指定 --keychain 或配置了 api_key_source = \"keychain\" 时保存到系统钥匙串，不写入配置文件",
 		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := config.UpdateConfig("api_key", args[0]); err != nil {
-				return err
-			}
-			fmt.Println("API密钥已更新")
-			return nil
-		},
+		RunE:  setAPIKey,
 	}
+	setAPIKeyCmd.Flags().BoolVar(&useKeychain, "keychain", false, "将API密钥保存到系统钥匙串（macOS钥匙串、Windows凭据管理器或Linux的Secret Service），Linux和FreeBSD上需要使用 -tags keychain 编译")
 
 	// 生成默认配置命令
 	genConfigCmd := &cobra.Command{
@@ -107,38 +263,145 @@ func main() {
 		RunE:  generateConfig,
 	}
 
+	// 显示配置文件位置命令
+	whereConfigCmd := &cobra.Command{
+		Use:   "where",
+		Short: "显示实际加载的配置文件和合并后的配置",
+		Long:  "显示当前生效的配置文件路径，以及合并默认值、配置文件和环境变量后的配置项（密钥已打码）",
+		RunE:  whereConfig,
+	}
+
 	// 添加根命令标志
-	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "指定配置文件路径")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "指定配置文件路径，- 表示从标准输入读取TOML或YAML格式的配置")
 	rootCmd.PersistentFlags().StringSliceVar(&apiKeys, "api-keys", nil, "Mistral API密钥列表，用逗号分隔")
 	rootCmd.PersistentFlags().StringSliceVar(&baseURLs, "base-urls", nil, "Mistral API基础URL列表，用逗号分隔")
 	rootCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "", "输出目录")
 	rootCmd.PersistentFlags().BoolVar(&includeImages, "include-images", true, "是否包含图片")
 	rootCmd.PersistentFlags().StringVar(&outputName, "output-name", "", "输出文件名")
-	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "日志级别 (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&nameTemplate, "output-name-template", "", "输出名称模板，可用字段 {{.Base}} {{.Date}} {{.Index}} {{.Unix}}")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "日志级别 (debug, info, warn, error)，可按模块设置，如 info,ocr=debug,config=warn；模块有 cli、config、ocr、ocr.client")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "输出文档处理的详细日志，相当于 --log-level ocr=debug")
+	rootCmd.PersistentFlags().BoolVar(&debugLog, "debug", false, "所有模块都输出debug日志，相当于 --log-level debug")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "不执行实际操作，仅打印将要执行的操作")
-	rootCmd.PersistentFlags().IntVar(&timeout, "timeout", 10, "API请求超时时间（分钟）")
+	rootCmd.PersistentFlags().StringVar(&timeout, "timeout", "10m", "API请求超时时间，如 90s、5m、1h，不带单位的整数表示分钟")
+	rootCmd.PersistentFlags().StringVar(&ocrTimeout, "ocr-timeout", "", "OCR请求超时时间，格式同 --timeout，默认与 --timeout 相同")
 	rootCmd.PersistentFlags().IntVar(&maxRetries, "max-retries", 3, "API请求最大重试次数")
+	rootCmd.PersistentFlags().Float64Var(&maxUploadMB, "max-upload-size", 0, "上传文件的大小上限（MB），默认使用配置值（50）")
+	rootCmd.PersistentFlags().IntVar(&maxOpenFiles, "max-open-files", 0, "同时进行的文件操作（读取源文件、上传、写入输出和图片等）的上限，默认使用配置值（0表示不限制）")
+	rootCmd.PersistentFlags().IntVar(&maxBackoff, "max-backoff", 30, "重试等待时间的上限（秒），0表示不限制")
+	rootCmd.PersistentFlags().StringVar(&caCertFile, "ca-cert", "", "额外信任的CA证书文件（PEM格式），用于使用企业CA证书的自托管网关")
+	rootCmd.PersistentFlags().BoolVar(&insecureTLS, "insecure-skip-verify", false, "跳过TLS证书校验（不安全，仅用于测试）")
+	rootCmd.PersistentFlags().BoolVar(&failFast, "fail-fast", false, "批量处理时遇到第一个失败的文件就停止，覆盖配置中的 continue_on_error")
+	rootCmd.PersistentFlags().BoolVar(&continueOnErr, "continue-on-error", false, "批量处理时某个文件失败后继续处理其他文件，覆盖配置中的 continue_on_error")
+	rootCmd.PersistentFlags().IntVar(&urlExpiry, "signed-url-expiry", 0, "上传文件签名URL的有效期（小时），默认使用配置值")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "OCR响应缓存目录，相同文件再次处理时不调用API")
+	rootCmd.PersistentFlags().BoolVar(&archiveSource, "archive-source", false, "上传后通过签名URL下载Mistral实际收到的内容，保存到缓存目录并核对是否与上传的内容一致，需要 --cache-dir")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "禁用OCR响应缓存")
+	rootCmd.PersistentFlags().Float64Var(&costPerPage, "cost-per-page", 0, "每页单价，设置后输出预估费用")
+	rootCmd.PersistentFlags().BoolVar(&warmup, "warmup-endpoints", false, "处理前并发探测所有端点，优先使用响应最快的端点")
+	rootCmd.PersistentFlags().IntVar(&probeParallel, "probe-concurrency", 0, "探测端点的最大并发数，默认使用配置值（0表示同时探测所有端点）")
+	rootCmd.PersistentFlags().IntVar(&minPageText, "min-page-text-length", 0, "文本长度低于该值的页面不加入合并输出（用于去除空白页）")
+	rootCmd.PersistentFlags().BoolVar(&showProgress, "progress", false, "处理单个文件或URL时显示进度条")
+	rootCmd.PersistentFlags().BoolVar(&separateRaw, "separate-raw-response", false, "将原始响应单独保存到response.json，保持metadata.json精简")
+	rootCmd.PersistentFlags().BoolVar(&stripImages, "strip-images-from-text", false, "保存图片，但从输出的markdown中移除图片链接")
+	rootCmd.PersistentFlags().BoolVar(&noImages, "no-images", false, "不请求、不保存图片，并从输出的markdown中移除所有图片链接，得到纯文本版本")
+	rootCmd.PersistentFlags().BoolVar(&linkImages, "link-images-only", false, "不重新下载图片，将图片链接指向images目录下已有的图片")
+	rootCmd.PersistentFlags().StringVar(&appendTo, "append-to", "", "将每个文档的内容追加到指定的markdown文件，图片复制到其所在目录的images子目录")
+	rootCmd.PersistentFlags().BoolVar(&keepUpload, "keep-upload", false, "输出上传文件的ID和签名URL，便于检查Mistral实际收到的文件")
+	rootCmd.PersistentFlags().BoolVar(&generateTOC, "toc", false, "根据标题生成目录文件toc.md")
+	rootCmd.PersistentFlags().StringVar(&since, "since", "", "扫描目录时只处理在此之后修改的文件，可以是时长（如 24h）或时间（如 2024-06-01、RFC3339）")
+	rootCmd.PersistentFlags().StringVar(&fileModeStr, "file-mode", "", "输出文件的权限（八进制），如 0600，默认 0644")
+	rootCmd.PersistentFlags().StringVar(&dirModeStr, "dir-mode", "", "输出目录的权限（八进制），如 0700，默认 0755")
+	rootCmd.PersistentFlags().BoolVar(&dehyphenate, "dehyphenate", false, "合并output.txt中因排版在行尾用连字符断开的单词")
+	rootCmd.PersistentFlags().BoolVar(&streamLarge, "stream", false, "流式解析OCR响应，图片直接写入磁盘，适合页数很多的大文档")
+	rootCmd.PersistentFlags().BoolVar(&normalizeText, "normalize-unicode", false, "对输出进行NFC规范化，并将不换行空格替换为普通空格")
+	rootCmd.PersistentFlags().BoolVar(&halfWidth, "half-width", false, "将输出中的全角字母和数字转换为半角（全角标点保持不变）")
+	rootCmd.PersistentFlags().StringVar(&resumeFrom, "resume", "", "读取之前的批量处理清单（manifest.json），跳过其中已完成的文件")
+	rootCmd.PersistentFlags().StringVar(&indexDB, "index-db", "", "批量处理时将每个文件的来源、输出目录、页数、图片数、模型、时间和SHA-256写入该SQLite数据库")
+	rootCmd.PersistentFlags().StringVar(&sortOrder, "sort", "", "批量处理时文件的排序方式：lexical（默认，按字节顺序）或 natural（按数字大小，如 file2.pdf 排在 file10.pdf 之前）")
+	rootCmd.PersistentFlags().IntVar(&startAt, "start-at", 0, "批量处理时从按路径排序后的第N个文件（从1开始）开始处理，跳过之前的文件")
+	rootCmd.PersistentFlags().StringVar(&startAfter, "start-after", "", "批量处理时从指定文件（路径或文件名）之后开始处理，不能与 --start-at 同时使用")
+	rootCmd.PersistentFlags().BoolVar(&stripImageMeta, "strip-image-metadata", false, "保存图片前重新编码JPEG和PNG图片，去除EXIF、XMP等元数据（JPEG会重新压缩）")
+	rootCmd.PersistentFlags().BoolVar(&dedupImages, "dedup-images", false, "按内容对图片去重，每页重复的标志等图片只保存一次，节省的空间记录在metadata.json中")
+	rootCmd.PersistentFlags().StringVar(&bundleImages, "bundle-images", "", "将提取的图片按页面顺序额外打包，可选 pdf（生成images.pdf）或 none")
+	rootCmd.PersistentFlags().BoolVar(&atomicOutput, "atomic-output", false, "先在本地临时目录中生成输出，完成后再移动到输出目录，适用于NFS/SMB等网络文件系统")
+	rootCmd.PersistentFlags().StringVar(&corpusFile, "corpus-file", "", "将每个文档提取的文本追加到指定文件，文档之间以 ---DOC: <文件名>--- 分隔，便于构建检索语料")
+	rootCmd.PersistentFlags().BoolVar(&skipTextPDFs, "skip-text-pdfs", false, "处理目录或多个文件时跳过已包含文本层的PDF（数字文档不必OCR），可先用 --dry-run 查看检测结果")
+	rootCmd.PersistentFlags().StringArrayVar(&pdfPasswords, "password", nil, "加密PDF的密码，在本地解密后再上传；可多次指定，<文件名>.pdf=<密码> 为单个文件指定密码")
+	rootCmd.PersistentFlags().BoolVar(&showStats, "stats", false, "处理结束后输出每个文件上传、获取签名URL、OCR和保存各阶段的耗时")
+	rootCmd.PersistentFlags().BoolVar(&outputBOM, "bom", false, "在output.md和output.txt开头添加UTF-8 BOM，便于旧版Windows编辑器正确显示非ASCII字符")
+	rootCmd.PersistentFlags().StringVar(&mdTemplate, "markdown-template", "", "生成output.md的Go模板文件，可用 {{.Name}} {{.Date}} {{.PageCount}} {{.Pages}} {{.Content}} 等字段，如添加YAML front matter")
+	rootCmd.PersistentFlags().StringVar(&imageLinkBase, "image-link-base", "", "加在markdown图片链接之前的路径，如 /assets/doc1/ 得到 /assets/doc1/images/x.jpeg，默认使用相对路径")
+	rootCmd.PersistentFlags().StringVar(&imageNaming, "image-naming", "", "保存图片的命名方式：id（默认，使用响应中的图片ID）或 positional（如 page0003-img001.jpeg，文件名顺序与文档顺序一致）")
+	rootCmd.PersistentFlags().StringVar(&onImageError, "on-image-error", "", "响应中的图片无法解码时的处理方式：skip（默认，保留原链接）、placeholder（链接到占位图片）或 removelink（移除链接）")
+	rootCmd.PersistentFlags().StringSliceVar(&outputFormats, "format", nil, "额外生成的输出格式，可重复指定：latex（将合并的markdown转换为独立的output.tex，保留数学公式）")
+	rootCmd.PersistentFlags().IntVar(&maxPages, "max-pages", 0, "每个文档最多处理的页数，只OCR前N页，用于正式处理前抽样检查质量、控制费用，0表示不限制")
+	rootCmd.PersistentFlags().IntVar(&chunkPages, "chunk-pages", 0, "PDF页数超过该值时按每批该页数分别请求OCR再合并结果，避免大文档请求超时，0表示不分批")
+	rootCmd.PersistentFlags().BoolVar(&wordBoxes, "word-boxes", false, "将每页的单词、位置和置信度写入 words/page-N.json，用于生成可搜索的PDF；仅在API返回单词级信息时生成")
+	rootCmd.PersistentFlags().BoolVar(&splitPages, "split-pages", false, "额外将每页保存为单独的markdown文件")
+
+	// 添加file命令标志
+	processFileCmd.Flags().StringVar(&inputList, "input-list", "", "从文件读取要处理的文件或目录路径（每行一个，忽略空行和以#开头的注释），与命令行参数合并；- 表示从标准输入读取")
+
+	// 添加url命令标志
+	processURLCmd.Flags().BoolVar(&forceImageURL, "force-image-url", false, "强制按图片（image_url）处理URL")
+	processURLCmd.Flags().BoolVar(&fallbackToUpload, "fallback-upload", false, "API无法访问该URL时，在本地下载后上传处理")
+	processCmd.Flags().BoolVar(&forceImageURL, "force-image-url", false, "来源为URL时，强制按图片（image_url）处理")
+	processCmd.Flags().BoolVar(&fallbackToUpload, "fallback-upload", false, "来源为URL且API无法访问时，在本地下载后上传处理")
+
+	// 添加convert命令标志
+	convertCmd.Flags().BoolVar(&nameFromMetadata, "output-name-from-metadata", false, "按JSON中记录的原始来源（如metadata.json中的源文件名）命名输出，没有记录时使用JSON文件名")
+
+	// 添加merge命令标志
+	mergeCmd.Flags().StringVar(&mergeOut, "out", "", "合并结果的输出目录")
+	mergeCmd.MarkFlagRequired("out")
+
+	// 添加models命令标志
+	modelsCmd.Flags().BoolVar(&listAllModels, "all", false, "显示所有模型，而不只是支持OCR的模型")
 
 	// 添加genConfig命令标志
 	genConfigCmd.Flags().StringVarP(&outputToFile, "output", "o", "", "将配置输出到文件而非标准输出")
 
 	// 添加子命令
+	rootCmd.AddCommand(processCmd)
 	rootCmd.AddCommand(processFileCmd)
 	rootCmd.AddCommand(processURLCmd)
 	rootCmd.AddCommand(convertCmd)
+	rootCmd.AddCommand(modelsCmd)
+	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(mergeCmd)
+	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(setAPIKeyCmd)
 	configCmd.AddCommand(genConfigCmd)
+	configCmd.AddCommand(whereConfigCmd)
+
+	// 参数和标志错误属于用法错误，使用单独的退出码
+	markArgsErrorsAsUsage(rootCmd)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return newUsageError(err)
+	})
+
+	// 收到中断信号时取消上下文，让批量处理写出清单后退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// 执行命令
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		// 先判断是否被中断，stop()本身也会取消上下文
+		interrupted := ctx.Err() != nil
+		stop()
+		if interrupted {
+			fmt.Println("处理已中断")
+			os.Exit(exitInterrupted)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
 // setup 初始化应用程序
-func setup() error {
+func setup(cmd *cobra.Command) error {
 	var err error
 
 	// 先初始化一个基本日志记录器，用于记录配置加载过程
@@ -164,36 +427,110 @@ func setup() error {
 	}
 
 	// 从命令行参数更新配置
-	updateConfigFromFlags(tempLogger)
+	if err := updateConfigFromFlags(cmd, tempLogger); err != nil {
+		return err
+	}
+	if cfg.SignedURLExpiryHours <= 0 {
+		return fmt.Errorf("签名URL有效期必须为正数: %d", cfg.SignedURLExpiryHours)
+	}
+	if since != "" {
+		sinceTime, err = parseSince(since, time.Now())
+		if err != nil {
+			return fmt.Errorf("无效的 --since 参数: %w", err)
+		}
+	}
+	if fileMode, err = parseMode(fileModeStr); err != nil {
+		return fmt.Errorf("无效的 --file-mode 参数: %w", err)
+	}
+	if dirMode, err = parseMode(dirModeStr); err != nil {
+		return fmt.Errorf("无效的 --dir-mode 参数: %w", err)
+	}
 
-	// 初始化正式日志
+	// 初始化正式日志，--debug 优先于按模块设置的级别
 	tempLogger.Debug("初始化日志系统", zap.String("level", cfg.LogLevel))
-	log, err = logger.InitLogger(cfg.LogLevel, cfg.LogFormat, cfg.LogFile)
+	level, moduleLevels, err := logger.ParseLevels(cfg.LogLevel)
+	if err != nil {
+		return newUsageError(err)
+	}
+	if debugLog {
+		level, moduleLevels = "debug", nil
+	} else if verbose {
+		if _, ok := moduleLevels[logger.ModuleOCR]; !ok {
+			moduleLevels[logger.ModuleOCR] = "debug"
+		}
+	}
+	rootLog, err := logger.InitLogger(level, cfg.LogFormat, cfg.LogFile, moduleLevels)
 	if err != nil {
 		tempLogger.Error("初始化日志系统失败", zap.Error(err))
 		return fmt.Errorf("初始化日志失败: %w", err)
 	}
+	log = rootLog.Named(logger.ModuleCLI)
+	ocrLog = rootLog.Named(logger.ModuleOCR)
 
 	// 记录配置加载完成
-	log.Info("配置加载完成",
+	configLog := rootLog.Named(logger.ModuleConfig)
+	configLog.Debug("使用的配置文件", zap.String("path", config.ConfigFileUsed()))
+	configLog.Info("配置加载完成",
 		zap.Strings("baseURLs", cfg.BaseURLs),
 		zap.String("outputDir", cfg.OutputDir),
 		zap.Bool("includeImages", cfg.IncludeImages),
 		zap.String("logLevel", cfg.LogLevel))
 
+	if cfg.KeychainError != nil {
+		configLog.Warn("系统钥匙串不可用，使用配置文件和环境变量中的密钥", zap.Error(cfg.KeychainError))
+	}
+
 	// 检查API密钥是否存在
-	// 对于convert命令，不需要API密钥
-	cmd := os.Args[1]
-	if cmd != "convert" && cmd != "help" && cmd != "version" && (len(cfg.APIKeys) == 0 || cfg.APIKeys[0] == "") {
-		log.Error("缺少API密钥")
-		return fmt.Errorf("缺少API密钥，请使用 --api-keys 参数或设置 MISTRAL_API_KEY 环境变量")
+	// 对于convert和merge命令，不需要API密钥；process命令在识别来源后再检查；set-api-key命令用于设置密钥
+	name := cmd.Name()
+	if name != "convert" && name != "merge" && name != "process" && name != "set-api-key" && name != "help" && name != "version" {
+		return requireAPIKey()
 	}
 
 	return nil
 }
 
+// setAPIKey 保存API密钥，使用钥匙串时不写入配置文件
+func setAPIKey(cmd *cobra.Command, args []string) error {
+	if useKeychain || cfg.APIKeySource == config.APIKeySourceKeychain {
+		if err := config.StoreAPIKeyInKeychain(args[0]); err != nil {
+			log.Error("保存API密钥到系统钥匙串失败", zap.Error(err))
+			return err
+		}
+		fmt.Println("API密钥已保存到系统钥匙串")
+		if cfg.APIKeySource != config.APIKeySourceKeychain {
+			fmt.Println("请在配置文件中设置 api_key_source = \"keychain\" 以使用钥匙串中的密钥")
+		}
+		return nil
+	}
+	if err := config.UpdateConfig("api_key", args[0]); err != nil {
+		return err
+	}
+	fmt.Println("API密钥已更新")
+	return nil
+}
+
+// requireAPIKey 检查是否配置了API密钥
+func requireAPIKey() error {
+	if !hasAPIKey(cfg.APIKeys) {
+		log.Error("缺少API密钥")
+		return newUsageError(fmt.Errorf("缺少API密钥，请使用 --api-keys 参数或设置 MISTRAL_API_KEY 环境变量"))
+	}
+	return nil
+}
+
+// hasAPIKey 判断是否至少有一个非空的API密钥
+func hasAPIKey(keys []string) bool {
+	for _, key := range keys {
+		if strings.TrimSpace(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // updateConfigFromFlags 根据命令行参数更新配置
-func updateConfigFromFlags(logger *zap.Logger) {
+func updateConfigFromFlags(cmd *cobra.Command, logger *zap.Logger) error {
 	if len(apiKeys) > 0 {
 		logger.Debug("从命令行参数更新API密钥")
 		cfg.APIKeys = apiKeys
@@ -210,10 +547,139 @@ func updateConfigFromFlags(logger *zap.Logger) {
 		logger.Debug("从命令行参数更新日志级别", zap.String("logLevel", logLevel))
 		cfg.LogLevel = logLevel
 	}
+	if nameTemplate != "" {
+		logger.Debug("从命令行参数更新输出名称模板", zap.String("outputNameTemplate", nameTemplate))
+		cfg.OutputNameTemplate = nameTemplate
+	}
+	if indexDB != "" {
+		logger.Debug("从命令行参数更新索引数据库", zap.String("indexDB", indexDB))
+		cfg.IndexDB = indexDB
+	}
+	if urlExpiry != 0 {
+		logger.Debug("从命令行参数更新签名URL有效期", zap.Int("signedURLExpiryHours", urlExpiry))
+		cfg.SignedURLExpiryHours = urlExpiry
+	}
+	if cmd.Flags().Changed("max-retries") {
+		logger.Debug("从命令行参数更新最大重试次数", zap.Int("maxRetries", maxRetries))
+		cfg.MaxRetries = maxRetries
+	}
+	if cmd.Flags().Changed("timeout") {
+		minutes, d, err := parseTimeout(timeout)
+		if err != nil {
+			return fmt.Errorf("无效的 --timeout 参数: %w", err)
+		}
+		logger.Debug("从命令行参数更新超时时间", zap.String("timeout", timeout))
+		cfg.TimeoutMinutes, cfg.Timeout = minutes, d
+	}
+	if cmd.Flags().Changed("ocr-timeout") {
+		minutes, d, err := parseTimeout(ocrTimeout)
+		if err != nil {
+			return fmt.Errorf("无效的 --ocr-timeout 参数: %w", err)
+		}
+		logger.Debug("从命令行参数更新OCR超时时间", zap.String("ocrTimeout", ocrTimeout))
+		cfg.OCRTimeoutMinutes, cfg.OCRTimeout = minutes, d
+	}
+	if probeParallel > 0 {
+		logger.Debug("从命令行参数更新端点探测并发数", zap.Int("probeConcurrency", probeParallel))
+		cfg.ProbeConcurrency = probeParallel
+	}
+	if maxUploadMB > 0 {
+		logger.Debug("从命令行参数更新上传文件大小上限", zap.Float64("maxUploadSizeMB", maxUploadMB))
+		cfg.MaxUploadSizeMB = maxUploadMB
+	}
+	if maxOpenFiles > 0 {
+		logger.Debug("从命令行参数更新文件操作数上限", zap.Int("maxOpenFiles", maxOpenFiles))
+		cfg.MaxOpenFiles = maxOpenFiles
+	}
+	if caCertFile != "" {
+		logger.Debug("从命令行参数更新CA证书文件", zap.String("caCertFile", caCertFile))
+		cfg.CACertFile = caCertFile
+	}
+	if cmd.Flags().Changed("insecure-skip-verify") {
+		logger.Debug("从命令行参数更新是否跳过TLS证书校验", zap.Bool("insecureSkipVerify", insecureTLS))
+		cfg.InsecureSkipVerify = insecureTLS
+	}
+	if cmd.Flags().Changed("max-backoff") {
+		logger.Debug("从命令行参数更新最大重试等待时间", zap.Int("maxBackoffSeconds", maxBackoff))
+		cfg.MaxBackoffSeconds = maxBackoff
+	}
+	// 只有显式指定时才覆盖配置文件中的continue_on_error
+	if failFast && continueOnErr {
+		return fmt.Errorf("--fail-fast 和 --continue-on-error 不能同时指定")
+	}
+	if cmd.Flags().Changed("fail-fast") {
+		logger.Debug("从命令行参数更新是否继续处理", zap.Bool("continueOnError", !failFast))
+		cfg.ContinueOnError = !failFast
+	}
+	if cmd.Flags().Changed("continue-on-error") {
+		logger.Debug("从命令行参数更新是否继续处理", zap.Bool("continueOnError", continueOnErr))
+		cfg.ContinueOnError = continueOnErr
+	}
+	// 只有显式指定时才覆盖配置文件中的include_images
+	if cmd.Flags().Changed("include-images") {
+		logger.Debug("从命令行参数更新是否包含图片", zap.Bool("includeImages", includeImages))
+		cfg.IncludeImages = includeImages
+	}
+	return nil
+}
+
+// parseMode 解析八进制权限参数，如 0600，空字符串表示使用默认值
+func parseMode(value string) (os.FileMode, error) {
+	if value == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("需要八进制权限，如 0600: %s", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// parseTimeout 解析 --timeout 和 --ocr-timeout 参数
+//
+// 为兼容之前的用法，不带单位的整数表示分钟，以minutes返回；其他值按Go时长格式（如 90s、5m、1h）解析，以d返回。
+func parseTimeout(value string) (minutes int, d time.Duration, err error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 {
+			return 0, 0, fmt.Errorf("超时时间不能为负数: %s", value)
+		}
+		return n, 0, nil
+	}
+	d, err = time.ParseDuration(value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("需要时长（如 90s、5m）或分钟数: %s", value)
+	}
+	if d <= 0 {
+		return 0, 0, fmt.Errorf("超时时间必须为正数: %s", value)
+	}
+	return 0, d, nil
+}
+
+// parseSince 解析 --since 参数，时长表示相对于当前时间之前，也可以是日期或RFC3339时间
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("时长不能为负数: %s", value)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", value)
 }
 
-// loadCustomConfig 从指定路径加载配置
+// loadCustomConfig 从指定路径加载配置，路径为 - 时从标准输入读取
 func loadCustomConfig(configPath string) (*config.Config, error) {
+	if configPath == config.StdinConfigPath {
+		if inputList == "-" {
+			return nil, fmt.Errorf("--config 和 --input-list 不能同时从标准输入读取")
+		}
+		return config.LoadConfigFromReader(os.Stdin)
+	}
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("配置文件不存在: %s", configPath)
 	}
@@ -247,27 +713,413 @@ func generateConfig(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// whereConfig 显示实际加载的配置文件和合并后的配置
+func whereConfig(cmd *cobra.Command, args []string) error {
+	_, err := config.LoadConfigForDiagnosis(configFile)
+
+	path := config.ConfigFileUsed()
+	if configFile == config.StdinConfigPath {
+		path = "(标准输入)"
+	} else if path == "" {
+		path = "(未找到配置文件，使用默认值)"
+	}
+	fmt.Printf("配置文件: %s\n", path)
+	if err != nil {
+		fmt.Printf("配置无效: %v\n", err)
+	}
+
+	settings := config.AllSettings()
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Println()
+	for _, key := range keys {
+		fmt.Printf("%s = %v\n", key, maskSetting(key, settings[key]))
+	}
+	return nil
+}
+
+// inspectOutput 显示输出目录的处理结果摘要
+func inspectOutput(cmd *cobra.Command, args []string) error {
+	outputDir := args[0]
+
+	metadata, warnings, err := ocr.LoadMetadata(outputDir)
+	if err != nil {
+		return err
+	}
+
+	model := "-"
+	if m, ok := metadata.OCRResponseInfo["model"].(string); ok && m != "" {
+		model = m
+	}
+	duration := metadata.ProcessingTime
+	if duration == "" {
+		duration = "-"
+	}
+
+	fmt.Printf("来源: %s (%s)\n", metadata.SourcePath, metadata.SourceType)
+	fmt.Printf("页数: %d\n", metadata.PagesProcessed)
+	if metadata.PagesDropped > 0 {
+		fmt.Printf("移除的页数: %d\n", metadata.PagesDropped)
+	}
+	fmt.Printf("保存的图片: %d\n", metadata.ImagesSaved)
+	fmt.Printf("模型: %s\n", model)
+	fmt.Printf("处理时间: %s\n", metadata.ProcessedAt)
+	fmt.Printf("耗时: %s\n", duration)
+	if metadata.FromCache {
+		fmt.Printf("使用缓存: 是\n")
+	}
+
+	// 检查合并输出是否存在
+	mdPath := filepath.Join(outputDir, "output.md")
+	if info, err := os.Stat(mdPath); err != nil {
+		warnings = append(warnings, fmt.Sprintf("缺少 %s", mdPath))
+	} else if info.Size() == 0 {
+		warnings = append(warnings, fmt.Sprintf("%s 为空", mdPath))
+	}
+
+	for _, warning := range warnings {
+		fmt.Printf("警告: %s\n", warning)
+	}
+	return nil
+}
+
+// mergeOutputs 合并多个输出目录
+func mergeOutputs(cmd *cobra.Command, args []string) error {
+	log.Info("合并输出目录", zap.Strings("dirs", args), zap.String("out", mergeOut))
+
+	if dryRun {
+		log.Info("空运行模式，不执行实际操作")
+		return nil
+	}
+
+	// 合并不需要API密钥，但处理器需要客户端实例
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	processor := ocr.NewProcessor(client, ocrLog)
+	processor.SetMaxOpenFiles(cfg.MaxOpenFiles)
+
+	result, err := processor.MergeOutputs(args, mergeOut, newProcessOptions())
+	if err != nil {
+		log.Error("合并输出目录失败", zap.Error(err))
+		return err
+	}
+
+	fmt.Printf("合并了 %d 个文档、%d 张图片，结果保存在: %s\n", len(result.Documents), result.Images, result.OutputPath)
+	return nil
+}
+
+// listModels 列出可用的OCR模型
+func listModels(cmd *cobra.Command, args []string) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	models, err := client.ListModels(cmd.Context(), client.NextAPIKey())
+	if err != nil {
+		log.Error("获取模型列表失败", zap.Error(err))
+		return fmt.Errorf("获取模型列表失败: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "模型ID\t别名\t创建时间")
+	count := 0
+	for _, model := range models {
+		if !listAllModels && !model.SupportsOCR() {
+			continue
+		}
+		created := "-"
+		if model.Created > 0 {
+			created = time.Unix(model.Created, 0).Format("2006-01-02")
+		}
+		aliases := strings.Join(model.Aliases, ", ")
+		if aliases == "" {
+			aliases = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", model.ID, aliases, created)
+		count++
+	}
+	w.Flush()
+
+	if count == 0 {
+		fmt.Println("没有找到支持OCR的模型，可以使用 --all 查看所有模型")
+	}
+	return nil
+}
+
+// maskSetting 对配置项中的API密钥打码
+func maskSetting(key string, value interface{}) interface{} {
+	// 密钥文件的路径不是密钥本身，原样显示
+	if !strings.HasPrefix(key, "api_key") || key == "api_key_file" || key == "api_key_source" {
+		return value
+	}
+	switch v := value.(type) {
+	case string:
+		return logger.MaskSensitiveInfo(v, logger.APIKey)
+	case []interface{}:
+		masked := make([]string, len(v))
+		for i, item := range v {
+			masked[i] = logger.MaskSensitiveInfo(fmt.Sprint(item), logger.APIKey)
+		}
+		return masked
+	case []string:
+		masked := make([]string, len(v))
+		for i, item := range v {
+			masked[i] = logger.MaskSensitiveInfo(item, logger.APIKey)
+		}
+		return masked
+	default:
+		return "****"
+	}
+}
+
+// newClient 根据配置和命令行参数创建OCR客户端
+func newClient() (*ocr.Client, error) {
+	if cfg.InsecureSkipVerify {
+		log.Warn("已禁用TLS证书校验，连接可能被中间人窃听或篡改，请勿在生产环境中使用")
+	}
+	client, err := ocr.NewClientWithOptions(clientOptions(cfg))
+	if err != nil {
+		log.Error("创建OCR客户端失败", zap.Error(err))
+		// 客户端只会因配置（如CA证书）无效而创建失败
+		return nil, newUsageError(fmt.Errorf("创建OCR客户端失败: %w", err))
+	}
+	client.SetLogger(ocrLog.Named("client"))
+	return client, nil
+}
+
+// clientOptions 将配置转换为创建客户端的选项
+func clientOptions(cfg *config.Config) ocr.ClientOptions {
+	return ocr.ClientOptions{
+		APIKeys:                cfg.APIKeys,
+		BaseURLs:               cfg.BaseURLs,
+		Timeout:                configTimeout(cfg.Timeout, cfg.TimeoutMinutes),
+		OCRTimeout:             configTimeout(cfg.OCRTimeout, cfg.OCRTimeoutMinutes),
+		MaxRetries:             cfg.MaxRetries,
+		MaxBackoff:             time.Duration(cfg.MaxBackoffSeconds) * time.Second,
+		AdaptiveTimeoutBase:    time.Duration(cfg.AdaptiveTimeoutBaseSeconds) * time.Second,
+		AdaptiveTimeoutPerMB:   time.Duration(cfg.AdaptiveTimeoutPerMBSeconds) * time.Second,
+		AdaptiveTimeoutMax:     time.Duration(cfg.AdaptiveTimeoutMaxMinutes) * time.Minute,
+		RetryDifferentEndpoint: cfg.RetryDifferentEndpoint,
+		ProbeConcurrency:       cfg.ProbeConcurrency,
+		SignedURLExpiryHours:   cfg.SignedURLExpiryHours,
+		MaxUploadSizeMB:        cfg.MaxUploadSizeMB,
+		EndpointPaths: ocr.EndpointPaths{
+			Files:     cfg.EndpointPaths.Files,
+			SignedURL: cfg.EndpointPaths.SignedURL,
+			OCR:       cfg.EndpointPaths.OCR,
+			Models:    cfg.EndpointPaths.Models,
+		},
+		UploadURL:          cfg.EndpointURLs.Files,
+		SignedURLEndpoint:  cfg.EndpointURLs.SignedURL,
+		OCRURL:             cfg.EndpointURLs.OCR,
+		QueryParams:        cfg.QueryParams,
+		CACertFile:         cfg.CACertFile,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+}
+
+// configTimeout 返回以时长指定的超时时间，未指定时使用以分钟指定的超时时间
+func configTimeout(d time.Duration, minutes int) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// validateProcessOptions 在创建客户端和空运行之前校验处理选项，矛盾的参数组合按用法错误退出
+//
+// 错误由main统一输出，这里不记录日志。
+func validateProcessOptions() error {
+	if err := newProcessOptions().Validate(); err != nil {
+		return newUsageError(err)
+	}
+	return nil
+}
+
+// newProcessOptions 根据配置和命令行参数创建处理选项
+func newProcessOptions() ocr.ProcessOptions {
+	opts := ocr.ProcessOptions{
+		IncludeImages:        cfg.IncludeImages,
+		OutputDir:            cfg.OutputDir,
+		CustomOutputName:     outputName,
+		OutputNameTemplate:   cfg.OutputNameTemplate,
+		ContinueOnError:      cfg.ContinueOnError,
+		SplitPages:           splitPages,
+		CacheDir:             cacheDir,
+		MinPageTextLength:    minPageText,
+		SeparateRawResponse:  separateRaw,
+		GenerateTOC:          generateTOC,
+		KeepUpload:           keepUpload,
+		AppendTo:             appendTo,
+		ModifiedSince:        sinceTime,
+		FileMode:             fileMode,
+		DirMode:              dirMode,
+		DehyphenateText:      dehyphenate,
+		StreamLargeResponses: streamLarge,
+		NormalizeUnicode:     normalizeText,
+		FullWidthToHalfWidth: halfWidth,
+		ResumeManifest:       resumeFrom,
+		IndexDB:              cfg.IndexDB,
+		BundleImages:         bundleImages,
+		AtomicOutput:         atomicOutput,
+		CorpusFile:           corpusFile,
+		SkipTextPDFs:         skipTextPDFs,
+		OutputBOM:            outputBOM,
+		ChunkPages:           chunkPages,
+		MaxPages:             maxPages,
+		ImageNaming:          imageNaming,
+		OnImageError:         onImageError,
+		ImageLinkBase:        imageLinkBase,
+		OutputFormats:        outputFormats,
+		DedupImages:          dedupImages,
+		StripImageMetadata:   stripImageMeta,
+		SortOrder:            sortOrder,
+		StartAt:              startAt,
+		StartAfter:           startAfter,
+		MarkdownTemplate:     mdTemplate,
+		ArchiveSource:        archiveSource,
+		WordBoxes:            wordBoxes,
+		NameFromMetadata:     nameFromMetadata,
+	}
+	opts.PDFPassword, opts.PDFPasswords = parsePDFPasswords(pdfPasswords)
+	if noCache {
+		opts.CacheDir = ""
+	}
+	// 细分的图片选项优先于include_images
+	switch {
+	case noImages:
+		opts.IncludeImages = false
+		opts.NoImages = true
+	case stripImages:
+		opts.IncludeImages = false
+		opts.SaveImages = true
+		opts.KeepImagesInText = false
+	case linkImages:
+		opts.IncludeImages = false
+		opts.LinkImages = true
+		opts.KeepImagesInText = true
+	}
+	return opts
+}
+
+// readInputList 读取 --input-list 指定的路径列表，每行一个路径，忽略空行和以 # 开头的注释，path为 - 时从标准输入读取
+func readInputList(path string) ([]string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取路径列表失败: %w", err)
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	return paths, nil
+}
+
+// parsePDFPasswords 解析 --password 参数，<文件>.pdf=<密码> 形式的为单个文件的密码，其余作为所有文件的默认密码
+func parsePDFPasswords(values []string) (string, map[string]string) {
+	var password string
+	var perFile map[string]string
+	for _, value := range values {
+		name, secret, ok := strings.Cut(value, "=")
+		if ok && strings.HasSuffix(strings.ToLower(name), ".pdf") {
+			if perFile == nil {
+				perFile = make(map[string]string)
+			}
+			perFile[name] = secret
+			continue
+		}
+		password = value
+	}
+	return password, perFile
+}
+
+// attachProgress 在启用进度显示且运行于终端时，为处理选项设置进度回调
+func attachProgress(opts *ocr.ProcessOptions, title string) *utils.ProgressTracker {
+	if !showProgress || !utils.IsTerminal() {
+		return nil
+	}
+
+	// 上传和OCR阶段显示旋转指示器，得知页数后切换为按页显示的进度条
+	tracker := utils.NewSpinnerTracker(title)
+	opts.OnProgress = func(event ocr.ProgressEvent) {
+		switch event.Stage {
+		case ocr.StageUpload:
+			tracker.Describe("上传文件")
+		case ocr.StageSignedURL:
+			tracker.Describe("获取签名URL")
+		case ocr.StageOCR:
+			tracker.Describe("OCR处理中")
+		case ocr.StageSave:
+			if event.Current == 0 {
+				tracker.SetTotal(event.Total)
+			} else {
+				tracker.Step(fmt.Sprintf("保存第 %d/%d 页", event.Current, event.Total))
+			}
+		}
+	}
+	return tracker
+}
+
 // processFile 处理本地PDF文件
 func processFile(cmd *cobra.Command, args []string) error {
+	if inputList != "" {
+		listed, err := readInputList(inputList)
+		if err != nil {
+			return err
+		}
+		log.Info("从路径列表读取了待处理的路径", zap.String("list", inputList), zap.Int("count", len(listed)))
+		args = append(args, listed...)
+		if len(args) == 0 {
+			return fmt.Errorf("路径列表中没有路径: %s", inputList)
+		}
+	}
+
 	if len(args) == 1 {
 		log.Info("处理单个文件或目录", zap.String("path", args[0]))
 	} else {
 		log.Info("处理多个文件或目录", zap.Strings("paths", args))
 	}
 
+	if err := validateProcessOptions(); err != nil {
+		return err
+	}
+
 	if dryRun {
 		log.Info("空运行模式，不执行实际操作")
+		reportTextLayers(args)
 		return nil
 	}
 
 	// 创建OCR客户端
-	client := ocr.NewClient(cfg.APIKeys, cfg.BaseURLs)
-	client.SetTimeout(time.Duration(timeout) * time.Minute)
-	client.SetMaxRetries(maxRetries)
-	client.SetRetryDifferentEndpoint(cfg.RetryDifferentEndpoint)
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	if warmup {
+		warmupEndpoints(cmd.Context(), client)
+	}
 
 	// 创建处理器
-	processor := ocr.NewProcessor(client, log)
+	processor := ocr.NewProcessor(client, ocrLog)
+	processor.SetMaxOpenFiles(cfg.MaxOpenFiles)
 
 	if len(args) == 1 {
 		// 检查是否为目录
@@ -280,28 +1132,31 @@ func processFile(cmd *cobra.Command, args []string) error {
 		if fileInfo.IsDir() {
 			// 处理目录
 			log.Info("处理目录中的所有PDF文件", zap.String("dir", args[0]))
-			results, err := processor.ProcessMultipleFiles(args, ocr.ProcessOptions{
-				IncludeImages:    cfg.IncludeImages,
-				OutputDir:        cfg.OutputDir,
-				CustomOutputName: outputName,
-				ContinueOnError:  cfg.ContinueOnError,
-			})
+			// 记录批次摘要，用于判断是否有文件失败
+			var summary *ocr.BatchSummary
+			opts := newProcessOptions()
+			opts.OnBatchDone = func(s *ocr.BatchSummary) { summary = s }
+			results, err := processor.ProcessMultipleFiles(cmd.Context(), args, opts)
+			printStats(summary)
 			if err != nil {
 				log.Error("处理目录失败", zap.Error(err))
+				reportBatchError(err)
 				return err
 			}
 
 			log.Info("目录处理完成", zap.Int("processed", len(results)))
-			fmt.Printf("处理完成，共处理 %d 个文件\n", len(results))
-			return nil
+			fmt.Println(batchDoneMessage(summary, len(results)))
+			printUsage(results)
+			return checkPartialFailure(summary)
 		}
 
 		// 处理单个文件
-		result, err := processor.ProcessFile(args[0], ocr.ProcessOptions{
-			IncludeImages:    cfg.IncludeImages,
-			OutputDir:        cfg.OutputDir,
-			CustomOutputName: outputName,
-		})
+		opts := newProcessOptions()
+		tracker := attachProgress(&opts, filepath.Base(args[0]))
+		result, err := processor.Run(cmd.Context(), args[0], opts)
+		if tracker != nil {
+			tracker.Complete()
+		}
 		if err != nil {
 			log.Error("处理文件失败", zap.Error(err))
 			return err
@@ -309,23 +1164,187 @@ func processFile(cmd *cobra.Command, args []string) error {
 
 		log.Info("处理完成", zap.String("outputDir", result.OutputDir))
 		fmt.Printf("处理完成，结果保存在: %s\n", result.OutputDir)
+		printUsage([]*ocr.ProcessResult{result})
+		if showStats {
+			printTimings([]string{filepath.Base(args[0])}, []ocr.PhaseTimings{result.Timings})
+		}
 		return nil
 	} else {
 		// 处理多个文件或目录
-		results, err := processor.ProcessMultipleFiles(args, ocr.ProcessOptions{
-			IncludeImages:    cfg.IncludeImages,
-			OutputDir:        cfg.OutputDir,
-			CustomOutputName: outputName,
-			ContinueOnError:  cfg.ContinueOnError,
-		})
+		// 记录批次摘要，用于判断是否有文件失败
+		var summary *ocr.BatchSummary
+		opts := newProcessOptions()
+		opts.OnBatchDone = func(s *ocr.BatchSummary) { summary = s }
+		results, err := processor.ProcessMultipleFiles(cmd.Context(), args, opts)
+		printStats(summary)
 		if err != nil {
 			log.Error("处理多个文件或目录失败", zap.Error(err))
+			reportBatchError(err)
 			return err
 		}
 
 		log.Info("所有文件处理完成", zap.Int("processed", len(results)))
-		fmt.Printf("处理完成，共处理 %d 个文件\n", len(results))
-		return nil
+		fmt.Println(batchDoneMessage(summary, len(results)))
+		printUsage(results)
+		return checkPartialFailure(summary)
+	}
+}
+
+// warmupEndpoints 探测所有端点并记录结果
+func warmupEndpoints(ctx context.Context, client *ocr.Client) {
+	log.Info("探测API端点", zap.Int("endpoints", len(cfg.BaseURLs)))
+	for _, health := range client.WarmupEndpoints(ctx) {
+		if health.Healthy {
+			log.Info("端点可用", zap.String("baseURL", health.BaseURL), zap.Duration("latency", health.Latency))
+		} else {
+			log.Warn("端点不可用", zap.String("baseURL", health.BaseURL), zap.String("error", health.Error))
+		}
+	}
+}
+
+// printUsage 输出API用量，设置了每页单价时同时输出预估费用
+func printUsage(results []*ocr.ProcessResult) {
+	usage := ocr.SummarizeUsage(results)
+	if usage.PagesProcessed == 0 {
+		return
+	}
+	log.Info("API用量",
+		zap.Int("files", usage.Files),
+		zap.Int("pagesProcessed", usage.PagesProcessed),
+		zap.Int64("docSizeBytes", usage.DocSizeBytes))
+	fmt.Printf("API用量: %d 页, %.2f MB\n", usage.PagesProcessed, float64(usage.DocSizeBytes)/1024/1024)
+	if costPerPage > 0 {
+		fmt.Printf("预估费用: %.4f\n", usage.EstimatedCost(costPerPage))
+	}
+}
+
+// printStats 启用 --stats 时输出批量处理中每个成功文件的阶段耗时
+func printStats(summary *ocr.BatchSummary) {
+	if !showStats || summary == nil {
+		return
+	}
+	var names []string
+	var timings []ocr.PhaseTimings
+	for _, entry := range summary.Files {
+		if entry.Timings != nil && !entry.Resumed {
+			names = append(names, entry.Path)
+			timings = append(timings, *entry.Timings)
+		}
+	}
+	printTimings(names, timings)
+}
+
+// printTimings 以表格输出各文件的阶段耗时，多个文件时追加合计和平均行
+func printTimings(names []string, timings []ocr.PhaseTimings) {
+	if len(timings) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "文件\t上传\t签名URL\tOCR\t保存\t合计")
+	row := func(name string, t ocr.PhaseTimings) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name,
+			roundStat(t.Upload), roundStat(t.SignedURL), roundStat(t.OCR), roundStat(t.Save), roundStat(t.Total()))
+	}
+	var total ocr.PhaseTimings
+	for i, t := range timings {
+		row(names[i], t)
+		total.Add(t)
+	}
+	if n := time.Duration(len(timings)); n > 1 {
+		row("合计", total)
+		row("平均", ocr.PhaseTimings{Upload: total.Upload / n, SignedURL: total.SignedURL / n, OCR: total.OCR / n, Save: total.Save / n})
+	}
+	w.Flush()
+}
+
+// roundStat 将耗时精确到毫秒
+func roundStat(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
+
+// batchDoneMessage 返回批量处理完成时的提示，跳过和从清单恢复的文件不计入处理数量
+func batchDoneMessage(summary *ocr.BatchSummary, results int) string {
+	if summary == nil {
+		return fmt.Sprintf("处理完成，共处理 %d 个文件", results)
+	}
+	msg := fmt.Sprintf("处理完成，共处理 %d 个文件", summary.Succeeded)
+	if summary.Skipped > 0 {
+		msg += fmt.Sprintf("，跳过 %d 个输出已存在的文件", summary.Skipped)
+	}
+	if summary.Resumed > 0 {
+		msg += fmt.Sprintf("，%d 个文件在之前的清单中已完成", summary.Resumed)
+	}
+	return msg
+}
+
+// reportBatchError 输出批量处理失败前已完成的文件
+func reportBatchError(err error) {
+	var batchErr *ocr.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Results) == 0 {
+		return
+	}
+	fmt.Printf("处理中断，已成功处理 %d 个文件:\n", len(batchErr.Results))
+	for _, result := range batchErr.Results {
+		fmt.Printf("  %s\n", result.OutputDir)
+	}
+}
+
+// reportTextLayers 输出参数中每个PDF（目录中递归查找）是否已包含文本层，用于在OCR前估计是否有必要付费处理
+func reportTextLayers(paths []string) {
+	var files []string
+	for _, path := range paths {
+		filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && strings.ToLower(filepath.Ext(filePath)) == ".pdf" {
+				files = append(files, filePath)
+			}
+			return nil
+		})
+	}
+
+	withText := 0
+	for _, file := range files {
+		layer, err := ocr.ProbePDFTextLayer(file)
+		switch {
+		case err != nil:
+			fmt.Printf("%s: 无法检测文本层: %v\n", file, err)
+		case layer.Encrypted:
+			fmt.Printf("%s: 已加密，无法检测文本层\n", file)
+		case layer.HasText():
+			withText++
+			fmt.Printf("%s: 已包含文本层（%d 页，约 %d 个字符），可能不需要OCR\n", file, layer.Pages, layer.TextChars)
+		default:
+			fmt.Printf("%s: 未检测到文本层（%d 页，%d 张图片），可能是扫描件\n", file, layer.Pages, layer.Images)
+		}
+	}
+	if withText > 0 {
+		fmt.Printf("共 %d 个PDF，其中 %d 个已包含文本层，可使用 --skip-text-pdfs 跳过\n", len(files), withText)
+	}
+}
+
+// processAuto 根据参数类型分派到file、url或convert命令的处理函数
+//
+// 先检查本地文件系统，避免把名称像URL的本地文件当作URL处理。
+func processAuto(cmd *cobra.Command, args []string) error {
+	source := args[0]
+	fileInfo, err := os.Stat(source)
+	switch {
+	case err == nil && !fileInfo.IsDir() && strings.EqualFold(filepath.Ext(source), ".json"):
+		log.Info("识别为JSON文件", zap.String("path", source))
+		return convertJSON(cmd, args)
+	case err == nil:
+		if err := requireAPIKey(); err != nil {
+			return err
+		}
+		return processFile(cmd, args)
+	case ocr.IsHTTPURL(source):
+		log.Info("识别为URL", zap.String("url", source))
+		if err := requireAPIKey(); err != nil {
+			return err
+		}
+		return processURL(cmd, args)
+	default:
+		log.Error("无法识别的来源", zap.String("source", source), zap.Error(err))
+		return fmt.Errorf("无法识别的来源，既不是本地文件也不是http(s)地址: %w", err)
 	}
 }
 
@@ -345,22 +1364,29 @@ func processURL(cmd *cobra.Command, args []string) error {
 		log.Error("无效的URL", zap.Error(err))
 		return fmt.Errorf("无效的URL: %w", err)
 	}
+	if err := validateProcessOptions(); err != nil {
+		return err
+	}
 
 	// 创建OCR客户端
-	client := ocr.NewClient(cfg.APIKeys, cfg.BaseURLs)
-	client.SetTimeout(time.Duration(timeout) * time.Minute)
-	client.SetMaxRetries(maxRetries)
-	client.SetRetryDifferentEndpoint(cfg.RetryDifferentEndpoint)
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
 
 	// 创建处理器
-	processor := ocr.NewProcessor(client, log)
+	processor := ocr.NewProcessor(client, ocrLog)
+	processor.SetMaxOpenFiles(cfg.MaxOpenFiles)
 
 	// 处理URL
-	result, err := processor.ProcessURL(urlStr, ocr.ProcessOptions{
-		IncludeImages:    cfg.IncludeImages,
-		OutputDir:        cfg.OutputDir,
-		CustomOutputName: outputName,
-	})
+	opts := newProcessOptions()
+	opts.ForceImageURL = forceImageURL
+	opts.FallbackToUpload = fallbackToUpload
+	tracker := attachProgress(&opts, "URL")
+	result, err := processor.ProcessURL(cmd.Context(), urlStr, opts)
+	if tracker != nil {
+		tracker.Complete()
+	}
 	if err != nil {
 		log.Error("处理URL失败", zap.Error(err))
 		return err
@@ -375,6 +1401,9 @@ func processURL(cmd *cobra.Command, args []string) error {
 func convertJSON(cmd *cobra.Command, args []string) error {
 	jsonPath := args[0]
 	log.Info("转换JSON文件", zap.String("file", jsonPath))
+	if err := validateProcessOptions(); err != nil {
+		return err
+	}
 
 	if dryRun {
 		log.Info("空运行模式，不执行实际操作")
@@ -382,18 +1411,25 @@ func convertJSON(cmd *cobra.Command, args []string) error {
 	}
 
 	// 创建OCR客户端 (转换不需要API密钥，但处理器需要客户端实例)
-	client := ocr.NewClient(cfg.APIKeys, cfg.BaseURLs)
-	client.SetRetryDifferentEndpoint(cfg.RetryDifferentEndpoint)
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
 
 	// 创建处理器
-	processor := ocr.NewProcessor(client, log)
+	processor := ocr.NewProcessor(client, ocrLog)
+	processor.SetMaxOpenFiles(cfg.MaxOpenFiles)
+
+	// 多个文件或目录时批量转换
+	if len(args) > 1 {
+		return convertMultipleJSON(cmd, processor, args)
+	}
+	if fileInfo, err := os.Stat(jsonPath); err == nil && fileInfo.IsDir() {
+		return convertMultipleJSON(cmd, processor, args)
+	}
 
 	// 转换JSON
-	result, err := processor.ConvertJSONToMarkdown(jsonPath, ocr.ProcessOptions{
-		IncludeImages:    cfg.IncludeImages,
-		OutputDir:        cfg.OutputDir,
-		CustomOutputName: outputName,
-	})
+	result, err := processor.ConvertJSONToMarkdown(jsonPath, newProcessOptions())
 	if err != nil {
 		log.Error("转换JSON失败", zap.Error(err))
 		return err
@@ -403,3 +1439,22 @@ func convertJSON(cmd *cobra.Command, args []string) error {
 	fmt.Printf("转换完成，结果保存在: %s\n", result.OutputDir)
 	return nil
 }
+
+// convertMultipleJSON 批量转换多个JSON文件或目录
+func convertMultipleJSON(cmd *cobra.Command, processor *ocr.Processor, args []string) error {
+	log.Info("批量转换JSON文件", zap.Strings("paths", args))
+	// 记录批次摘要，用于判断是否有文件失败
+	var summary *ocr.BatchSummary
+	opts := newProcessOptions()
+	opts.OnBatchDone = func(s *ocr.BatchSummary) { summary = s }
+	results, err := processor.ConvertMultipleJSON(cmd.Context(), args, opts)
+	if err != nil {
+		log.Error("批量转换JSON失败", zap.Error(err))
+		reportBatchError(err)
+		return err
+	}
+
+	log.Info("批量转换完成", zap.Int("converted", len(results)))
+	fmt.Printf("转换完成，共转换 %d 个文件\n", len(results))
+	return checkPartialFailure(summary)
+}
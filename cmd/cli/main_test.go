@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nerdneilsfield/go-mistral-ocr/internal/config"
+	"github.com/nerdneilsfield/go-mistral-ocr/pkg/ocr"
+)
+
+func TestClientOptions(t *testing.T) {
+	cfg := &config.Config{
+		APIKeys:                     []string{"key"},
+		BaseURLs:                    []string{"https://api.example.com/v1/"},
+		TimeoutMinutes:              10,
+		OCRTimeoutMinutes:           20,
+		OCRTimeout:                  90 * time.Second,
+		MaxRetries:                  4,
+		MaxBackoffSeconds:           15,
+		AdaptiveTimeoutBaseSeconds:  60,
+		AdaptiveTimeoutPerMBSeconds: 20,
+		AdaptiveTimeoutMaxMinutes:   30,
+		RetryDifferentEndpoint:      true,
+		ProbeConcurrency:            3,
+		SignedURLExpiryHours:        12,
+		MaxUploadSizeMB:             80,
+		EndpointPaths:               config.EndpointPaths{OCR: "v2/ocr", Models: "v2/models"},
+		EndpointURLs:                config.EndpointURLs{Files: "https://upload.example.com/files", SignedURL: "https://sign.example.com/{id}"},
+		CACertFile:                  "/etc/ssl/ca.pem",
+	}
+
+	opts := clientOptions(cfg)
+	if opts.Timeout != 10*time.Minute {
+		t.Errorf("Timeout = %v，期望以分钟指定的 10m", opts.Timeout)
+	}
+	if opts.OCRTimeout != 90*time.Second {
+		t.Errorf("OCRTimeout = %v，期望命令行以时长指定的 90s 优先", opts.OCRTimeout)
+	}
+	if opts.MaxRetries != 4 || opts.MaxBackoff != 15*time.Second || opts.ProbeConcurrency != 3 {
+		t.Errorf("重试选项 = %d/%v/%d，期望 4/15s/3", opts.MaxRetries, opts.MaxBackoff, opts.ProbeConcurrency)
+	}
+	if opts.AdaptiveTimeoutBase != time.Minute || opts.AdaptiveTimeoutPerMB != 20*time.Second || opts.AdaptiveTimeoutMax != 30*time.Minute {
+		t.Errorf("按大小计算的超时 = %v/%v/%v", opts.AdaptiveTimeoutBase, opts.AdaptiveTimeoutPerMB, opts.AdaptiveTimeoutMax)
+	}
+	if !opts.RetryDifferentEndpoint || opts.SignedURLExpiryHours != 12 || opts.MaxUploadSizeMB != 80 {
+		t.Errorf("端点切换、签名URL有效期或上传大小上限没有转换")
+	}
+	if opts.EndpointPaths.OCR != "v2/ocr" || opts.EndpointPaths.Models != "v2/models" {
+		t.Errorf("EndpointPaths = %+v", opts.EndpointPaths)
+	}
+	if opts.UploadURL != cfg.EndpointURLs.Files || opts.SignedURLEndpoint != cfg.EndpointURLs.SignedURL || opts.OCRURL != "" {
+		t.Errorf("完整请求URL = %q/%q/%q", opts.UploadURL, opts.SignedURLEndpoint, opts.OCRURL)
+	}
+	if opts.CACertFile != cfg.CACertFile || opts.InsecureSkipVerify {
+		t.Errorf("TLS选项 = %q/%t", opts.CACertFile, opts.InsecureSkipVerify)
+	}
+}
+
+func TestBatchDoneMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary *ocr.BatchSummary
+		results int
+		want    string
+	}{
+		{name: "没有摘要", summary: nil, results: 2, want: "处理完成，共处理 2 个文件"},
+		{name: "全部新处理", summary: &ocr.BatchSummary{Succeeded: 3}, results: 3, want: "处理完成，共处理 3 个文件"},
+		{
+			name:    "跳过和恢复的文件不计入处理数量",
+			summary: &ocr.BatchSummary{Succeeded: 1, Skipped: 2, Resumed: 4},
+			results: 3,
+			want:    "处理完成，共处理 1 个文件，跳过 2 个输出已存在的文件，4 个文件在之前的清单中已完成",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := batchDoneMessage(tt.summary, tt.results); got != tt.want {
+				t.Errorf("batchDoneMessage() = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
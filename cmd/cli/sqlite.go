@@ -0,0 +1,4 @@
+package main
+
+// 注册纯Go的SQLite驱动（名称为 ocr.IndexDBDriver），供 --index-db 使用，无需cgo
+import _ "modernc.org/sqlite"
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -12,17 +13,43 @@ import (
 // Config 应用程序配置
 type Config struct {
 	// API配置
-	APIKeys  []string `mapstructure:"api_keys"`
-	BaseURLs []string `mapstructure:"base_urls"`
+	APIKeys       []string `mapstructure:"api_keys"`
+	APIKeyFile    string   `mapstructure:"api_key_file"`    // 从文件读取API密钥，每行一个
+	APIKeyCommand string   `mapstructure:"api_key_command"` // 执行命令并从标准输出读取API密钥，每行一个
+	APIKeySource  string   `mapstructure:"api_key_source"`  // 为 keychain 时额外从系统钥匙串读取密钥，见 APIKeySourceKeychain
+	KeychainError error    `mapstructure:"-"`               // 从钥匙串读取密钥失败的原因，此时只使用其他来源的密钥
+	BaseURLs      []string `mapstructure:"base_urls"`
 
 	// 错误处理配置
 	ContinueOnError        bool `mapstructure:"continue_on_error"`
 	RetryDifferentEndpoint bool `mapstructure:"retry_different_endpoint"`
+	ProbeConcurrency       int  `mapstructure:"probe_concurrency"` // 探测端点的最大并发数，0表示同时探测所有端点
+	MaxRetries             int  `mapstructure:"max_retries"`
+
+	// 请求配置
+	SignedURLExpiryHours        int               `mapstructure:"signed_url_expiry_hours"`
+	TimeoutMinutes              int               `mapstructure:"timeout_minutes"`
+	OCRTimeoutMinutes           int               `mapstructure:"ocr_timeout_minutes"`
+	Timeout                     time.Duration     `mapstructure:"-"` // 命令行以时长指定的超时时间（如 90s），不为0时代替 TimeoutMinutes
+	OCRTimeout                  time.Duration     `mapstructure:"-"` // 命令行以时长指定的OCR超时时间，不为0时代替 OCRTimeoutMinutes
+	MaxBackoffSeconds           int               `mapstructure:"max_backoff_seconds"`
+	AdaptiveTimeoutBaseSeconds  int               `mapstructure:"adaptive_timeout_base_seconds"`
+	AdaptiveTimeoutPerMBSeconds int               `mapstructure:"adaptive_timeout_per_mb_seconds"`
+	AdaptiveTimeoutMaxMinutes   int               `mapstructure:"adaptive_timeout_max_minutes"`
+	MaxUploadSizeMB             float64           `mapstructure:"max_upload_size_mb"`
+	EndpointPaths               EndpointPaths     `mapstructure:"endpoint_paths"`
+	EndpointURLs                EndpointURLs      `mapstructure:"endpoint_urls"`
+	QueryParams                 map[string]string `mapstructure:"query_params"`
+	CACertFile                  string            `mapstructure:"ca_cert_file"`
+	InsecureSkipVerify          bool              `mapstructure:"insecure_skip_verify"`
 
 	// 输出配置
 	OutputDir           string `mapstructure:"output_dir"`
 	IncludeImages       bool   `mapstructure:"include_images"`
 	DefaultOutputFormat string `mapstructure:"default_output_format"`
+	OutputNameTemplate  string `mapstructure:"output_name_template"`
+	IndexDB             string `mapstructure:"index_db"`       // 批量处理时记录每个文件处理结果的SQLite数据库路径
+	MaxOpenFiles        int    `mapstructure:"max_open_files"` // 同时进行的文件操作数的上限，0表示不限制
 
 	// 日志配置
 	LogLevel  string `mapstructure:"log_level"`
@@ -33,6 +60,21 @@ type Config struct {
 	Theme string `mapstructure:"theme"`
 }
 
+// EndpointPaths API接口路径配置，留空的字段使用Mistral默认路径
+type EndpointPaths struct {
+	Files     string `mapstructure:"files"`
+	SignedURL string `mapstructure:"signed_url"`
+	OCR       string `mapstructure:"ocr"`
+	Models    string `mapstructure:"models"`
+}
+
+// EndpointURLs 上传、获取签名URL和OCR请求的完整URL，设置后代替基础URL和接口路径，也不在端点之间切换
+type EndpointURLs struct {
+	Files     string `mapstructure:"files"`
+	SignedURL string `mapstructure:"signed_url"`
+	OCR       string `mapstructure:"ocr"`
+}
+
 // LoadConfig 从viper加载配置
 func LoadConfig() (*Config, error) {
 	// 设置默认值
@@ -53,6 +95,26 @@ func LoadConfig() (*Config, error) {
 	// 从环境变量加载配置
 	loadFromEnv()
 
+	return decodeConfig()
+}
+
+// decodeConfig 将viper中的配置解析到结构体，处理旧版配置项并验证
+func decodeConfig() (*Config, error) {
+	config, err := unmarshalConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证配置
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// unmarshalConfig 将viper中的配置解析到结构体并处理旧版配置项，不验证配置
+func unmarshalConfig() (*Config, error) {
 	// 解析配置到结构体
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
@@ -69,12 +131,43 @@ func LoadConfig() (*Config, error) {
 		config.BaseURLs = append(config.BaseURLs, baseURL)
 	}
 
-	// 验证配置
-	if err := validateConfig(&config); err != nil {
-		return nil, err
+	expandEnvValues(&config)
+	return &config, nil
+}
+
+// LoadConfigForDiagnosis 加载配置用于诊断：找不到配置文件时不创建默认配置，也不验证配置
+//
+// configPath 为空时按默认路径查找配置文件，找不到时只使用默认值和环境变量；为 StdinConfigPath 时从标准输入读取。
+// 配置文件存在但无法读取或解析时返回错误。返回的配置已补充环境变量中的API密钥和默认端点。
+func LoadConfigForDiagnosis(configPath string) (*Config, error) {
+	setDefaults()
+
+	if configPath == StdinConfigPath {
+		if err := readConfigFrom(os.Stdin); err != nil {
+			return nil, err
+		}
+	} else if configPath != "" {
+		viper.SetConfigFile(configPath)
+		if err := viper.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("读取配置文件失败: %w", err)
+		}
+	} else if err := loadConfigFile(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, fmt.Errorf("加载配置文件出错: %w", err)
+		}
 	}
 
-	return &config, nil
+	loadFromEnv()
+
+	config, err := unmarshalConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveAPIKeySources(config); err != nil {
+		return nil, err
+	}
+	applyImplicitDefaults(config)
+	return config, nil
 }
 
 // setDefaults 设置默认配置
@@ -88,6 +181,11 @@ func setDefaults() {
 	viper.SetDefault("theme", "light")
 	viper.SetDefault("continue_on_error", true)
 	viper.SetDefault("retry_different_endpoint", true)
+	viper.SetDefault("max_retries", 3)
+	viper.SetDefault("signed_url_expiry_hours", 24)
+	viper.SetDefault("timeout_minutes", 10)
+	viper.SetDefault("max_backoff_seconds", 30)
+	viper.SetDefault("max_upload_size_mb", 50)
 }
 
 // loadConfigFile 尝试加载配置文件
@@ -101,16 +199,23 @@ func loadConfigFile() error {
 	viper.AddConfigPath(".")
 
 	// 2. 用户配置目录
+	var trustedDirs []string
 	homeDir, err := os.UserHomeDir()
 	if err == nil {
-		viper.AddConfigPath(filepath.Join(homeDir, ".config", "mistral-ocr"))
+		trustedDirs = append(trustedDirs, filepath.Join(homeDir, ".config", "mistral-ocr"))
 	}
 
 	// 3. 系统配置目录
-	viper.AddConfigPath("/etc/mistral-ocr")
+	trustedDirs = append(trustedDirs, "/etc/mistral-ocr")
+	for _, dir := range trustedDirs {
+		viper.AddConfigPath(dir)
+	}
 
 	// 加载配置文件
-	return viper.ReadInConfig()
+	if err := viper.ReadInConfig(); err != nil {
+		return err
+	}
+	return checkWorkDirConfig(viper.ConfigFileUsed(), trustedDirs)
 }
 
 // createDefaultConfig 创建默认配置文件
@@ -127,38 +232,8 @@ func createDefaultConfig() error {
 
 	configPath := filepath.Join(configDir, "config.toml")
 
-	// 默认配置内容
-	defaultConfig := `# Mistral OCR 配置文件
-
-# API配置
-# 支持多个API密钥轮询，程序会在每次API调用时随机选择一个密钥开始，然后轮流使用
-# 这有助于负载均衡和提高可靠性，当一个API密钥达到速率限制时可以自动切换到下一个
-api_keys = [""]  # 在这里设置你的API密钥，或者使用MISTRAL_API_KEY环境变量，支持多个API密钥轮询
-
-# 支持多个API基础URL轮询，程序会在每次API调用时随机选择一个URL开始，然后轮流使用
-# 这有助于在某个API端点不可用时自动切换到备用端点
-base_urls = ["https://api.mistral.ai/v1/"]  # 可以添加多个备用API端点
-
-# 错误处理配置
-continue_on_error = true  # 当处理多个文件时，如果一个文件处理失败，是否继续处理其他文件
-retry_different_endpoint = true  # 当一个API端点失败时，是否尝试使用不同的端点重试
-
-# 输出配置
-output_dir = "./output"
-include_images = true
-default_output_format = "markdown"  # markdown 或 text
-
-# 日志配置
-log_level = "info"  # debug, info, warn, error
-log_file = ""      # 留空表示输出到控制台
-log_format = "console"  # console 或 json
-
-# GUI配置
-theme = "light"  # light 或 dark
-`
-
 	// 写入默认配置文件
-	return os.WriteFile(configPath, []byte(defaultConfig), 0644)
+	return os.WriteFile(configPath, []byte(GetDefaultConfig()), 0644)
 }
 
 // loadFromEnv 从环境变量加载配置
@@ -174,8 +249,8 @@ func loadFromEnv() {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 }
 
-// validateConfig 验证配置
-func validateConfig(config *Config) error {
+// applyImplicitDefaults 补充环境变量中的API密钥和默认端点，并确保每个端点以 / 结尾
+func applyImplicitDefaults(config *Config) {
 	// 如果API密钥为空，查找环境变量
 	if len(config.APIKeys) == 0 {
 		apiKey := os.Getenv("MISTRAL_API_KEY")
@@ -184,11 +259,6 @@ func validateConfig(config *Config) error {
 		}
 	}
 
-	// 确保至少有一个 API 密钥
-	if len(config.APIKeys) == 0 {
-		return fmt.Errorf("至少需要一个 API 密钥")
-	}
-
 	// 确保至少有一个 BaseURL
 	if len(config.BaseURLs) == 0 {
 		config.BaseURLs = append(config.BaseURLs, "https://api.mistral.ai/v1/")
@@ -200,6 +270,65 @@ func validateConfig(config *Config) error {
 			config.BaseURLs[i] = baseURL + "/"
 		}
 	}
+}
+
+// validateConfig 验证配置
+func validateConfig(config *Config) error {
+	if err := resolveAPIKeySources(config); err != nil {
+		return err
+	}
+	applyImplicitDefaults(config)
+
+	// 确保至少有一个 API 密钥
+	if len(config.APIKeys) == 0 {
+		return fmt.Errorf("至少需要一个 API 密钥")
+	}
+
+	if config.ProbeConcurrency < 0 {
+		return fmt.Errorf("端点探测并发数不能为负数: %d", config.ProbeConcurrency)
+	}
+
+	// 签名URL有效期未设置时使用默认值，设置时必须为正数
+	if config.SignedURLExpiryHours == 0 {
+		config.SignedURLExpiryHours = 24
+	} else if config.SignedURLExpiryHours < 0 {
+		return fmt.Errorf("签名URL有效期必须为正数: %d", config.SignedURLExpiryHours)
+	}
+
+	if config.MaxRetries < 0 {
+		return fmt.Errorf("最大重试次数不能为负数: %d", config.MaxRetries)
+	}
+
+	// 超时时间未设置时使用默认值，OCR超时为0表示与普通请求相同
+	if config.TimeoutMinutes == 0 {
+		config.TimeoutMinutes = 10
+	} else if config.TimeoutMinutes < 0 {
+		return fmt.Errorf("超时时间必须为正数: %d", config.TimeoutMinutes)
+	}
+	if config.OCRTimeoutMinutes < 0 {
+		return fmt.Errorf("OCR超时时间不能为负数: %d", config.OCRTimeoutMinutes)
+	}
+	if config.MaxBackoffSeconds < 0 {
+		return fmt.Errorf("最大重试等待时间不能为负数: %d", config.MaxBackoffSeconds)
+	}
+	if config.AdaptiveTimeoutBaseSeconds < 0 || config.AdaptiveTimeoutPerMBSeconds < 0 || config.AdaptiveTimeoutMaxMinutes < 0 {
+		return fmt.Errorf("按文件大小计算的超时时间参数不能为负数")
+	}
+	if config.MaxUploadSizeMB == 0 {
+		config.MaxUploadSizeMB = 50
+	} else if config.MaxUploadSizeMB < 0 {
+		return fmt.Errorf("上传文件大小上限必须为正数: %g", config.MaxUploadSizeMB)
+	}
+
+	if config.CACertFile != "" {
+		if _, err := os.Stat(config.CACertFile); err != nil {
+			return fmt.Errorf("无法读取CA证书文件: %w", err)
+		}
+	}
+
+	if config.MaxOpenFiles < 0 {
+		return fmt.Errorf("同时进行的文件操作数上限不能为负数: %d", config.MaxOpenFiles)
+	}
 
 	// 确保输出目录存在
 	if config.OutputDir != "" {
@@ -213,6 +342,16 @@ func validateConfig(config *Config) error {
 	return nil
 }
 
+// ConfigFileUsed 返回实际加载的配置文件路径，未加载任何配置文件时返回空字符串
+func ConfigFileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
+// AllSettings 返回合并默认值、配置文件和环境变量后的全部配置项
+func AllSettings() map[string]interface{} {
+	return viper.AllSettings()
+}
+
 // UpdateConfig 更新配置
 func UpdateConfig(key string, value interface{}) error {
 	viper.Set(key, value)
@@ -220,19 +359,38 @@ func UpdateConfig(key string, value interface{}) error {
 }
 
 // SaveConfig 保存当前配置到文件
+//
+// 值没有修改（与配置文件中的原值展开后相同）的项按原值写入，保留其中的环境变量引用；
+// 修改过的项按新值写入。
 func SaveConfig(config *Config) error {
 	for k, v := range map[string]interface{}{
-		"api_keys":              config.APIKeys,
-		"base_urls":             config.BaseURLs,
-		"output_dir":            config.OutputDir,
-		"include_images":        config.IncludeImages,
-		"default_output_format": config.DefaultOutputFormat,
-		"log_level":             config.LogLevel,
-		"log_file":              config.LogFile,
-		"log_format":            config.LogFormat,
-		"theme":                 config.Theme,
+		"api_keys":                        config.APIKeys,
+		"api_key_source":                  config.APIKeySource,
+		"base_urls":                       config.BaseURLs,
+		"output_dir":                      config.OutputDir,
+		"include_images":                  config.IncludeImages,
+		"output_name_template":            config.OutputNameTemplate,
+		"index_db":                        config.IndexDB,
+		"max_open_files":                  config.MaxOpenFiles,
+		"default_output_format":           config.DefaultOutputFormat,
+		"log_level":                       config.LogLevel,
+		"log_file":                        config.LogFile,
+		"log_format":                      config.LogFormat,
+		"theme":                           config.Theme,
+		"signed_url_expiry_hours":         config.SignedURLExpiryHours,
+		"probe_concurrency":               config.ProbeConcurrency,
+		"max_retries":                     config.MaxRetries,
+		"timeout_minutes":                 config.TimeoutMinutes,
+		"ocr_timeout_minutes":             config.OCRTimeoutMinutes,
+		"max_backoff_seconds":             config.MaxBackoffSeconds,
+		"adaptive_timeout_base_seconds":   config.AdaptiveTimeoutBaseSeconds,
+		"adaptive_timeout_per_mb_seconds": config.AdaptiveTimeoutPerMBSeconds,
+		"adaptive_timeout_max_minutes":    config.AdaptiveTimeoutMaxMinutes,
+		"max_upload_size_mb":              config.MaxUploadSizeMB,
+		"ca_cert_file":                    config.CACertFile,
+		"insecure_skip_verify":            config.InsecureSkipVerify,
 	} {
-		viper.Set(k, v)
+		viper.Set(k, unexpandedValue(k, v))
 	}
 
 	return viper.WriteConfig()
@@ -244,11 +402,16 @@ func LoadConfigFromFile(configPath string) (*Config, error) {
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
+	return decodeFileConfig()
+}
 
+// decodeFileConfig 将viper中读取的指定配置解析到结构体并验证
+func decodeFileConfig() (*Config, error) {
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("解析配置文件失败: %w", err)
 	}
+	expandEnvValues(&config)
 
 	if err := validateConfig(&config); err != nil {
 		return nil, err
@@ -262,9 +425,15 @@ func GetDefaultConfig() string {
 	return `# Mistral OCR 配置文件
 
 # API配置
-# 支持多个API密钥轮询，程序会在每次API调用时随机选择一个密钥开始，然后轮流使用
+# 支持多个API密钥轮询，程序启动时随机选择一个密钥开始，之后每个文件轮流使用下一个密钥
+# 同一文件的上传、获取签名URL和OCR请求使用同一个密钥（签名URL必须用上传时的密钥获取）
 # 这有助于负载均衡和提高可靠性，当一个API密钥达到速率限制时可以自动切换到下一个
 api_keys = [""]  # 在这里设置你的API密钥，或者使用MISTRAL_API_KEY环境变量，支持多个API密钥轮询
+# 不希望在配置文件中保存明文密钥时，可以从文件或密钥管理工具读取，每行一个密钥，与api_keys合并
+# api_key_file = "/run/secrets/mistral"
+# api_key_command = "vault kv get -field=api_key secret/mistral"
+# 从系统钥匙串读取 config set-api-key --keychain 保存的密钥（需要使用 -tags keychain 编译），不可用时使用其他来源的密钥
+# api_key_source = "keychain"
 
 # 支持多个API基础URL轮询，程序会在每次API调用时随机选择一个URL开始，然后轮流使用
 # 这有助于在某个API端点不可用时自动切换到备用端点
@@ -273,14 +442,55 @@ base_urls = ["https://api.mistral.ai/v1/"]  # 可以添加多个备用API端点
 # 错误处理配置
 continue_on_error = true  # 当处理多个文件时，如果一个文件处理失败，是否继续处理其他文件
 retry_different_endpoint = true  # 当一个API端点失败时，是否尝试使用不同的端点重试
+probe_concurrency = 0  # 使用 --warmup-endpoints 探测端点时的最大并发数，0表示同时探测所有端点
+max_retries = 3  # API调用失败时的最大重试次数
+
+# 请求配置
+signed_url_expiry_hours = 24  # 上传文件签名URL的有效期（小时）
+timeout_minutes = 10          # 上传、获取签名URL等请求的超时时间（分钟）
+ocr_timeout_minutes = 0       # OCR请求的超时时间（分钟），大文档可适当调大，0表示与timeout_minutes相同
+max_backoff_seconds = 30      # 重试等待时间的上限（秒），等待时间按1秒、2秒、4秒……递增，0表示不限制
+# 按文件大小计算上传和OCR请求的超时时间：基础时间 + 每MB时间，不超过上限，代替timeout_minutes；
+# ocr_timeout_minutes不为0时OCR请求仍使用该值。基础时间和每MB时间都为0表示不启用
+adaptive_timeout_base_seconds = 0    # 基础超时时间（秒）
+adaptive_timeout_per_mb_seconds = 0  # 每MB增加的超时时间（秒）
+adaptive_timeout_max_minutes = 0     # 超时时间上限（分钟），0表示不限制
+max_upload_size_mb = 50       # 上传文件的大小上限（MB），Mistral官方API为50MB，自托管网关可按实际限制调整
+ca_cert_file = ""             # 额外信任的CA证书（PEM格式），用于使用企业CA证书的自托管网关
+insecure_skip_verify = false  # 跳过TLS证书校验，存在安全风险，仅用于测试环境
 
 # 输出配置
 output_dir = "./output"
 include_images = true
 default_output_format = "markdown"  # markdown 或 text
+# 输出目录名称模板（Go模板），可用字段 {{.Base}} {{.Date}} {{.Index}} {{.Unix}}，留空使用源文件名
+# output_name_template = "{{.Date}}_{{.Base}}_{{printf \"%03d\" .Index}}"
+# 批量处理时将每个文件的来源、输出目录、页数、图片数、模型、时间和SHA-256写入SQLite数据库，重复运行时更新原有记录
+# index_db = "./output/index.db"
+# 同时进行的文件操作（读取源文件、上传、写入输出和图片等）的上限，系统打开文件数限制较低时设置，0表示不限制
+# max_open_files = 64
+
+# API接口路径（相对于base_urls），用于路由规则不同的自托管网关，留空使用默认值
+# [endpoint_paths]
+# files = "files"
+# signed_url = "files/{id}/url"  # {id} 会被替换为文件ID
+# ocr = "ocr"
+# models = "models"
+
+# 上传、获取签名URL和OCR请求的完整URL，用于将这些请求路由到其他主机的代理；
+# 设置后该请求直接使用此URL，不再拼接base_urls和endpoint_paths，也不在端点之间切换
+# [endpoint_urls]
+# files = "https://upload.example.com/v1/files"
+# signed_url = "https://upload.example.com/v1/files/{id}/url"  # {id} 会被替换为文件ID
+# ocr = "https://ocr.example.com/v1/ocr"
+
+# 附加到每个API请求URL的查询参数，用于要求API版本、区域等参数的网关（参数名会被转换为小写）
+# [query_params]
+# api-version = "2024-05-01"
+# region = "eu"
 
 # 日志配置
-log_level = "info"  # debug, info, warn, error
+log_level = "info"  # debug, info, warn, error；可按模块设置，如 "info,ocr.client=debug"，模块有 cli、config、ocr、ocr.client
 log_file = ""      # 留空表示输出到控制台
 log_format = "console"  # console 或 json
 
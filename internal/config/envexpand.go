@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+// envRefPattern 匹配整个值只是一个环境变量引用（$VAR 或 ${VAR}）的情况
+var envRefPattern = regexp.MustCompile(`^\$(?:([A-Za-z_][A-Za-z0-9_]*)|\{([A-Za-z_][A-Za-z0-9_]*)\})$`)
+
+// expandEnv 展开字符串中的 ${VAR} 和 $VAR 环境变量引用，未设置的变量展开为空字符串，$$ 表示字面的 $
+func expandEnv(value string) string {
+	return os.Expand(value, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
+}
+
+// expandEnvRef 整个值只是一个环境变量引用（$VAR 或 ${VAR}）时返回变量的值，否则原样返回
+//
+// 用于API密钥：密钥本身可能包含 $，只有完整的引用才展开，避免密钥被部分替换。
+func expandEnvRef(value string) string {
+	m := envRefPattern.FindStringSubmatch(value)
+	if m == nil {
+		return value
+	}
+	return os.Getenv(m[1] + m[2])
+}
+
+// expandEnvSlice 展开字符串列表中每一项的环境变量引用
+func expandEnvSlice(values []string) []string {
+	return mapSlice(values, expandEnv)
+}
+
+// mapSlice 对字符串列表的每一项调用expand，结果写回原列表
+func mapSlice(values []string, expand func(string) string) []string {
+	for i, value := range values {
+		values[i] = expand(value)
+	}
+	return values
+}
+
+// unexpandedValue 返回保存配置时写入的值：value与配置文件中原值展开后的结果相同时返回原值，
+// 保留其中的环境变量引用，避免把展开后的路径或密钥写入配置文件
+func unexpandedValue(key string, value interface{}) interface{} {
+	if !viper.IsSet(key) {
+		return value
+	}
+	expand := expandEnv
+	if key == "api_keys" {
+		expand = expandEnvRef
+	}
+	switch v := value.(type) {
+	case string:
+		if raw := viper.GetString(key); expand(raw) == v {
+			return raw
+		}
+	case []string:
+		raw := viper.GetStringSlice(key)
+		if slices.Equal(mapSlice(slices.Clone(raw), expand), v) {
+			return raw
+		}
+	}
+	return value
+}
+
+// expandEnvValues 展开配置中字符串值的环境变量引用，如 output_dir = "${DATA_DIR}/ocr"、api_keys = ["${MISTRAL_KEY}"]
+//
+// api_key_command 由shell执行，其中的变量由shell展开；output_name_template 是Go模板，$ 用于模板变量，
+// 这两项保持原样。api_keys 中只展开整个值为 $VAR 或 ${VAR} 的项，其他密钥原样使用。
+func expandEnvValues(config *Config) {
+	config.APIKeys = mapSlice(config.APIKeys, expandEnvRef)
+	config.APIKeyFile = expandEnv(config.APIKeyFile)
+	config.BaseURLs = expandEnvSlice(config.BaseURLs)
+
+	config.EndpointPaths.Files = expandEnv(config.EndpointPaths.Files)
+	config.EndpointPaths.SignedURL = expandEnv(config.EndpointPaths.SignedURL)
+	config.EndpointPaths.OCR = expandEnv(config.EndpointPaths.OCR)
+	config.EndpointPaths.Models = expandEnv(config.EndpointPaths.Models)
+	config.EndpointURLs.Files = expandEnv(config.EndpointURLs.Files)
+	config.EndpointURLs.SignedURL = expandEnv(config.EndpointURLs.SignedURL)
+	config.EndpointURLs.OCR = expandEnv(config.EndpointURLs.OCR)
+	for key, value := range config.QueryParams {
+		config.QueryParams[key] = expandEnv(value)
+	}
+	config.CACertFile = expandEnv(config.CACertFile)
+
+	config.OutputDir = expandEnv(config.OutputDir)
+	config.DefaultOutputFormat = expandEnv(config.DefaultOutputFormat)
+
+	config.LogLevel = expandEnv(config.LogLevel)
+	config.LogFile = expandEnv(config.LogFile)
+	config.LogFormat = expandEnv(config.LogFormat)
+
+	config.Theme = expandEnv(config.Theme)
+}
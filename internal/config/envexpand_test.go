@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("OCR_TEST_DIR", "/data")
+	t.Setenv("OCR_TEST_KEY", "secret")
+
+	tests := []struct {
+		value   string
+		want    string
+		wantRef string // expandEnvRef 的结果
+	}{
+		{value: "${OCR_TEST_DIR}/ocr", want: "/data/ocr", wantRef: "${OCR_TEST_DIR}/ocr"},
+		{value: "$OCR_TEST_KEY", want: "secret", wantRef: "secret"},
+		{value: "${OCR_TEST_KEY}", want: "secret", wantRef: "secret"},
+		{value: "${OCR_TEST_UNSET}", want: "", wantRef: ""},
+		{value: "price$$5", want: "price$5", wantRef: "price$$5"},
+		{value: "ab$OCR_TEST_KEYcd", want: "ab", wantRef: "ab$OCR_TEST_KEYcd"},
+		{value: "plain-key", want: "plain-key", wantRef: "plain-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := expandEnv(tt.value); got != tt.want {
+				t.Errorf("expandEnv(%q) = %q，期望 %q", tt.value, got, tt.want)
+			}
+			if got := expandEnvRef(tt.value); got != tt.wantRef {
+				t.Errorf("expandEnvRef(%q) = %q，期望 %q", tt.value, got, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestExpandEnvValuesAPIKeys(t *testing.T) {
+	t.Setenv("OCR_TEST_KEY", "secret")
+	config := &Config{APIKeys: []string{"${OCR_TEST_KEY}", "k3y$with$dollars", "$OCR_TEST_KEY"}}
+	expandEnvValues(config)
+
+	want := []string{"secret", "k3y$with$dollars", "secret"}
+	if !reflect.DeepEqual(config.APIKeys, want) {
+		t.Errorf("api_keys = %v，期望 %v", config.APIKeys, want)
+	}
+}
+
+// TestSaveConfigKeepsEnvReferences 保存配置时没有修改的项保留环境变量引用，修改过的项写入新值
+func TestSaveConfigKeepsEnvReferences(t *testing.T) {
+	_, userDir := setupConfigDirs(t)
+	t.Setenv("OCR_TEST_DIR", "/data")
+	t.Setenv("OCR_TEST_KEY", "secret")
+	path := writeConfig(t, userDir, `api_keys = ["${OCR_TEST_KEY}"]
+output_dir = "${OCR_TEST_DIR}/ocr"
+log_file = "${OCR_TEST_DIR}/ocr.log"
+`)
+
+	config, err := LoadConfigFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFromFile 返回错误: %v", err)
+	}
+	if config.OutputDir != "/data/ocr" || !reflect.DeepEqual(config.APIKeys, []string{"secret"}) {
+		t.Fatalf("加载的配置 output_dir = %q、api_keys = %v，期望已展开", config.OutputDir, config.APIKeys)
+	}
+
+	config.LogFile = "/var/log/ocr.log"
+	if err := SaveConfig(config); err != nil {
+		t.Fatalf("SaveConfig 返回错误: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	for _, want := range []string{"${OCR_TEST_KEY}", "${OCR_TEST_DIR}/ocr", "/var/log/ocr.log"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("保存的配置中缺少 %q:\n%s", want, saved)
+		}
+	}
+	for _, unwanted := range []string{"secret", "/data/ocr", "${OCR_TEST_DIR}/ocr.log"} {
+		if strings.Contains(saved, unwanted) {
+			t.Errorf("保存的配置中不应出现 %q:\n%s", unwanted, saved)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// api_key_source 的可选值
+const (
+	APIKeySourceConfig   = "config"   // 只使用配置文件、环境变量、api_key_file 和 api_key_command 中的密钥（默认）
+	APIKeySourceKeychain = "keychain" // 额外从系统钥匙串读取 config set-api-key 保存的密钥
+)
+
+// 钥匙串中保存API密钥的服务名和账户名
+const (
+	KeychainService = "go-mistral-ocr"
+	KeychainUser    = "api_key"
+)
+
+// ErrKeychainUnsupported 当前程序未启用系统钥匙串支持
+var ErrKeychainUnsupported = errors.New("未启用系统钥匙串支持，请使用 -tags keychain 重新编译")
+
+// StoreAPIKeyInKeychain 将API密钥保存到系统钥匙串，覆盖之前保存的密钥；多个密钥每行一个
+func StoreAPIKeyInKeychain(key string) error {
+	if err := keychainSet(key); err != nil {
+		return fmt.Errorf("保存API密钥到系统钥匙串失败: %w", err)
+	}
+	return nil
+}
+
+// validateAPIKeySource 校验 api_key_source 的取值
+func validateAPIKeySource(source string) error {
+	switch source {
+	case "", APIKeySourceConfig, APIKeySourceKeychain:
+		return nil
+	default:
+		return fmt.Errorf("无效的api_key_source: %s，可选值为 %s 或 %s", source, APIKeySourceConfig, APIKeySourceKeychain)
+	}
+}
+
+// readKeychainKeys 从系统钥匙串读取API密钥，没有保存密钥时返回错误
+func readKeychainKeys() ([]string, error) {
+	secret, err := keychainGet()
+	if err != nil {
+		return nil, fmt.Errorf("从系统钥匙串读取API密钥失败: %w", err)
+	}
+	keys := parseAPIKeys([]byte(secret))
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("系统钥匙串中没有API密钥")
+	}
+	return keys, nil
+}
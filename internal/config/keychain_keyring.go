@@ -0,0 +1,17 @@
+//go:build keychain
+
+package config
+
+import "github.com/zalando/go-keyring"
+
+// keychainGet 读取钥匙串中保存的API密钥
+//
+// 使用 -tags keychain 编译时通过 go-keyring 访问系统钥匙串（macOS钥匙串、Windows凭据管理器或Linux的Secret Service）。
+func keychainGet() (string, error) {
+	return keyring.Get(KeychainService, KeychainUser)
+}
+
+// keychainSet 将API密钥写入钥匙串
+func keychainSet(secret string) error {
+	return keyring.Set(KeychainService, KeychainUser, secret)
+}
@@ -0,0 +1,13 @@
+//go:build !keychain
+
+package config
+
+// keychainGet 默认编译不包含钥匙串支持，服务器部署不需要引入相关依赖，返回 ErrKeychainUnsupported
+func keychainGet() (string, error) {
+	return "", ErrKeychainUnsupported
+}
+
+// keychainSet 未启用钥匙串支持，返回 ErrKeychainUnsupported
+func keychainSet(string) error {
+	return ErrKeychainUnsupported
+}
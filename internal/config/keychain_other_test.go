@@ -0,0 +1,18 @@
+//go:build !keychain
+
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestKeychainUnsupported 默认编译不包含钥匙串支持，读写钥匙串都返回 ErrKeychainUnsupported
+func TestKeychainUnsupported(t *testing.T) {
+	if err := StoreAPIKeyInKeychain("key"); !errors.Is(err, ErrKeychainUnsupported) {
+		t.Errorf("StoreAPIKeyInKeychain 返回 %v，期望 ErrKeychainUnsupported", err)
+	}
+	if _, err := readKeychainKeys(); !errors.Is(err, ErrKeychainUnsupported) {
+		t.Errorf("readKeychainKeys 返回 %v，期望 ErrKeychainUnsupported", err)
+	}
+}
@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestValidateAPIKeySource(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr bool
+	}{
+		{source: ""},
+		{source: APIKeySourceConfig},
+		{source: APIKeySourceKeychain},
+		{source: "vault", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if err := validateAPIKeySource(tt.source); (err != nil) != tt.wantErr {
+				t.Errorf("validateAPIKeySource(%q) = %v，期望出错: %v", tt.source, err, tt.wantErr)
+			}
+		})
+	}
+}
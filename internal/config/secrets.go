@@ -0,0 +1,139 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// apiKeyCommandTimeout 执行 api_key_command 的超时时间
+const apiKeyCommandTimeout = 30 * time.Second
+
+// resolveAPIKeySources 读取 api_key_file、执行 api_key_command，api_key_source 为 keychain 时读取系统钥匙串，
+// 将得到的密钥追加到 APIKeys
+//
+// 文件不可读、命令返回非0退出码或没有得到任何密钥时返回错误，避免静默地在没有密钥的情况下运行。
+// 钥匙串不可用（未启用支持、没有保存密钥或无法访问）时不返回错误，原因记录在 KeychainError 中，
+// 使用配置文件和环境变量中的密钥。
+func resolveAPIKeySources(config *Config) error {
+	if err := validateAPIKeySource(config.APIKeySource); err != nil {
+		return err
+	}
+	if config.APIKeySource == APIKeySourceKeychain {
+		config.KeychainError = nil
+		if keys, err := readKeychainKeys(); err != nil {
+			config.KeychainError = err
+		} else {
+			config.APIKeys = appendNewKeys(config.APIKeys, keys)
+		}
+	}
+
+	if config.APIKeyFile != "" {
+		data, err := os.ReadFile(config.APIKeyFile)
+		if err != nil {
+			return fmt.Errorf("读取API密钥文件失败: %w", err)
+		}
+		keys := parseAPIKeys(data)
+		if len(keys) == 0 {
+			return fmt.Errorf("API密钥文件中没有密钥: %s", config.APIKeyFile)
+		}
+		config.APIKeys = appendNewKeys(config.APIKeys, keys)
+	}
+
+	if config.APIKeyCommand != "" {
+		output, err := runAPIKeyCommand(config.APIKeyCommand)
+		if err != nil {
+			return err
+		}
+		keys := parseAPIKeys(output)
+		if len(keys) == 0 {
+			return fmt.Errorf("API密钥命令没有输出密钥")
+		}
+		config.APIKeys = appendNewKeys(config.APIKeys, keys)
+	}
+	return nil
+}
+
+// checkWorkDirConfig 在当前工作目录中找到的配置文件设置了 api_key_command 时返回错误
+//
+// 在他人提供的目录（例如克隆的仓库）中运行时，当前目录的配置文件不可信，其中的命令会以当前用户的身份执行。
+// 只执行 --config 显式指定的配置文件、用户配置目录或系统配置目录（trustedDirs）中的配置文件设置的命令。
+func checkWorkDirConfig(configFile string, trustedDirs []string) error {
+	if !viper.InConfig("api_key_command") {
+		return nil
+	}
+	configDir, err := filepath.Abs(filepath.Dir(configFile))
+	if err != nil {
+		return fmt.Errorf("解析配置文件路径失败: %w", err)
+	}
+	for _, dir := range trustedDirs {
+		if trusted, err := filepath.Abs(dir); err == nil && trusted == configDir {
+			return nil
+		}
+	}
+	return fmt.Errorf("当前目录中的配置文件 %s 设置了 api_key_command，出于安全考虑不会执行；请将其移到用户配置目录，或使用 --config 显式指定该文件", configFile)
+}
+
+// runAPIKeyCommand 通过系统shell执行命令并返回标准输出，错误信息中包含标准错误的内容
+func runAPIKeyCommand(command string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeyCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("执行API密钥命令超时（%v）", apiKeyCommandTimeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("执行API密钥命令失败: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("执行API密钥命令失败: %w", err)
+	}
+	return output, nil
+}
+
+// parseAPIKeys 按行解析密钥，忽略空行和以 # 开头的注释行
+func parseAPIKeys(data []byte) []string {
+	var keys []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	return keys
+}
+
+// appendNewKeys 将keys中尚未出现的密钥追加到existing，并去除existing中的空密钥
+func appendNewKeys(existing []string, keys []string) []string {
+	seen := make(map[string]bool, len(existing)+len(keys))
+	var result []string
+	for _, list := range [][]string{existing, keys} {
+		for _, key := range list {
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// setupConfigDirs 重置viper并切换到空的工作目录，HOME指向空目录，返回工作目录和用户配置目录
+func setupConfigDirs(t *testing.T) (string, string) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("MISTRAL_API_KEY", "")
+
+	workDir := t.TempDir()
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(oldDir) })
+
+	userDir := filepath.Join(home, ".config", "mistral-ocr")
+	if err := os.MkdirAll(userDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return workDir, userDir
+}
+
+// commandConfig 设置了 api_key_command 的配置文件内容，命令输出 command-key
+const commandConfig = `api_keys = ["file-key"]
+api_key_command = "echo command-key"
+`
+
+func writeConfig(t *testing.T, dir string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAPIKeyCommandFromWorkDirConfigIsRejected(t *testing.T) {
+	workDir, _ := setupConfigDirs(t)
+	writeConfig(t, workDir, commandConfig)
+
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "api_key_command") {
+		t.Errorf("LoadConfig 返回 %v，期望拒绝当前目录配置文件中的 api_key_command", err)
+	}
+
+	viper.Reset()
+	if _, err := LoadConfigForDiagnosis(""); err == nil || !strings.Contains(err.Error(), "api_key_command") {
+		t.Errorf("LoadConfigForDiagnosis 返回 %v，期望拒绝当前目录配置文件中的 api_key_command", err)
+	}
+}
+
+func TestWorkDirConfigWithoutCommandIsLoaded(t *testing.T) {
+	workDir, _ := setupConfigDirs(t)
+	writeConfig(t, workDir, `api_keys = ["file-key"]`+"\n")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig 返回错误: %v", err)
+	}
+	if len(cfg.APIKeys) != 1 || cfg.APIKeys[0] != "file-key" {
+		t.Errorf("API密钥 = %v，期望 [file-key]", cfg.APIKeys)
+	}
+}
+
+func TestAPIKeyCommandFromTrustedConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		load func(t *testing.T, workDir, userDir string) (*Config, error)
+	}{
+		{
+			name: "用户配置目录",
+			load: func(t *testing.T, workDir, userDir string) (*Config, error) {
+				writeConfig(t, userDir, commandConfig)
+				return LoadConfig()
+			},
+		},
+		{
+			name: "用户配置目录（诊断）",
+			load: func(t *testing.T, workDir, userDir string) (*Config, error) {
+				writeConfig(t, userDir, commandConfig)
+				return LoadConfigForDiagnosis("")
+			},
+		},
+		{
+			name: "显式指定当前目录的配置文件",
+			load: func(t *testing.T, workDir, userDir string) (*Config, error) {
+				return LoadConfigFromFile(writeConfig(t, workDir, commandConfig))
+			},
+		},
+		{
+			name: "显式指定当前目录的配置文件（诊断）",
+			load: func(t *testing.T, workDir, userDir string) (*Config, error) {
+				return LoadConfigForDiagnosis(writeConfig(t, workDir, commandConfig))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir, userDir := setupConfigDirs(t)
+			cfg, err := tt.load(t, workDir, userDir)
+			if err != nil {
+				t.Fatalf("加载配置返回错误: %v", err)
+			}
+			want := []string{"file-key", "command-key"}
+			if strings.Join(cfg.APIKeys, ",") != strings.Join(want, ",") {
+				t.Errorf("API密钥 = %v，期望 %v", cfg.APIKeys, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// StdinConfigPath 作为配置文件路径时表示从标准输入读取配置
+const StdinConfigPath = "-"
+
+// readerConfigTypes 从reader读取配置时依次尝试的格式，YAML同时可以解析JSON
+var readerConfigTypes = []string{"toml", "yaml"}
+
+// readConfigFrom 将r中的配置读入viper，没有文件扩展名可以判断格式，依次按TOML和YAML解析
+func readConfigFrom(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("读取配置失败: %w", err)
+	}
+
+	var errs []string
+	for _, configType := range readerConfigTypes {
+		viper.SetConfigType(configType)
+		err := viper.ReadConfig(bytes.NewReader(data))
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", configType, strings.ReplaceAll(err.Error(), "\n", " ")))
+	}
+	return fmt.Errorf("解析配置失败，内容既不是有效的TOML也不是有效的YAML: %s", strings.Join(errs, "; "))
+}
+
+// LoadConfigFromReader 从r（如标准输入）读取TOML或YAML格式的配置，解析和验证方式与 LoadConfigFromFile 相同
+//
+// 适用于以流的方式挂载配置、无法写入临时文件的只读容器。没有对应的配置文件，因此不能使用 WatchConfig 和 UpdateConfig。
+func LoadConfigFromReader(r io.Reader) (*Config, error) {
+	if err := readConfigFrom(r); err != nil {
+		return nil, err
+	}
+	return decodeFileConfig()
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// watchMu 保证配置变化的回调依次执行
+var watchMu sync.Mutex
+
+// WatchConfig 监视当前使用的配置文件，文件变化时重新解析和验证配置，验证通过后以新配置调用onChange
+//
+// 需要先通过 LoadConfig 或 LoadConfigFromFile 加载配置文件，未加载配置文件时返回错误。
+// 新配置无效时（如删除了所有API密钥）不调用onChange，调用方继续使用原有配置，错误输出到标准错误。
+// 回调中可以通过 Client.UpdateKeys、Client.UpdateEndpoints 更新正在使用的客户端。
+func WatchConfig(onChange func(*Config)) error {
+	if viper.ConfigFileUsed() == "" {
+		return fmt.Errorf("没有加载配置文件，无法监视配置变化")
+	}
+
+	viper.OnConfigChange(func(event fsnotify.Event) {
+		watchMu.Lock()
+		defer watchMu.Unlock()
+
+		config, err := decodeConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "重新加载配置失败，继续使用原有配置: %v\n", err)
+			return
+		}
+		onChange(config)
+	})
+	viper.WatchConfig()
+	return nil
+}
@@ -11,25 +11,24 @@ import (
 )
 
 // InitLogger 初始化日志记录器
-func InitLogger(level, format, logFile string) (*zap.Logger, error) {
-	// 解析日志级别
-	var zapLevel zapcore.Level
-	switch strings.ToLower(level) {
-	case "debug":
-		zapLevel = zapcore.DebugLevel
-	case "info":
-		zapLevel = zapcore.InfoLevel
-	case "warn", "warning":
-		zapLevel = zapcore.WarnLevel
-	case "error":
-		zapLevel = zapcore.ErrorLevel
-	default:
-		zapLevel = zapcore.InfoLevel
+//
+// level 为全局日志级别，moduleLevels 为各模块（日志记录器名称，见 ModuleOCR 等）的级别，
+// 未在其中设置的模块使用全局级别，为nil时所有日志使用全局级别。
+func InitLogger(level, format, logFile string, moduleLevels map[string]string) (*zap.Logger, error) {
+	// 解析日志级别，底层使用所有级别中最低的一个，再按模块过滤
+	zapLevel := GetLevel(level)
+	minLevel := zapLevel
+	modules := make(map[string]zapcore.Level, len(moduleLevels))
+	for module, moduleLevel := range moduleLevels {
+		modules[module] = GetLevel(moduleLevel)
+		if modules[module] < minLevel {
+			minLevel = modules[module]
+		}
 	}
 
 	// 创建基本配置
 	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Level = zap.NewAtomicLevelAt(minLevel)
 
 	// 设置输出格式
 	switch strings.ToLower(format) {
@@ -73,6 +72,13 @@ func InitLogger(level, format, logFile string) (*zap.Logger, error) {
 	// 使用敏感信息打码包装器
 	logger = NewMaskedLogger(logger)
 
+	// 打码包装器直接将自身加入待写入的Core，模块过滤需要在其外层进行
+	if len(modules) > 0 {
+		logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &moduleCore{Core: core, global: zapLevel, modules: modules}
+		}))
+	}
+
 	return logger, nil
 }
 
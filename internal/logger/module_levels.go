@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 各模块的日志记录器名称，可在日志级别中按模块设置，如 "ocr=debug,config=warn"
+const (
+	ModuleCLI       = "cli"        // 命令行程序
+	ModuleConfig    = "config"     // 配置加载
+	ModuleOCR       = "ocr"        // 文档处理和输出
+	ModuleOCRClient = "ocr.client" // API请求，设置 "ocr" 时同样生效
+)
+
+// ParseLevels 解析日志级别设置，返回全局级别和各模块的级别
+//
+// 设置为逗号分隔的列表，不含 "=" 的项为全局级别，"模块=级别" 为该模块的级别，
+// 如 "info,ocr=debug,config=warn"。只有一个级别时与旧版本相同，作为全局级别。
+// 模块名为点分隔的层级，未单独设置的子模块使用上一级模块的级别。
+// 全局级别和模块级别都必须是 LevelNames 中的名称（另外接受 warning），否则返回错误；多次设置全局级别时使用最后一个。
+func ParseLevels(spec string) (string, map[string]string, error) {
+	global := ""
+	modules := make(map[string]string)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		module, level, found := strings.Cut(item, "=")
+		if !found {
+			if !isLevelName(item) {
+				return "", nil, invalidLevelError(item)
+			}
+			global = item
+			continue
+		}
+		module = strings.ToLower(strings.TrimSpace(module))
+		level = strings.TrimSpace(level)
+		if module == "" {
+			return "", nil, fmt.Errorf("日志级别设置缺少模块名: %s", item)
+		}
+		if !isLevelName(level) {
+			return "", nil, invalidLevelError(level)
+		}
+		modules[module] = level
+	}
+	return global, modules, nil
+}
+
+// invalidLevelError 返回无效日志级别的错误，列出可用的级别
+func invalidLevelError(level string) error {
+	return fmt.Errorf("无效的日志级别 %q，可选 %s", level, strings.Join(LevelNames(), ", "))
+}
+
+// isLevelName 判断是否为可用的日志级别名称
+func isLevelName(level string) bool {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "warning", "error":
+		return true
+	}
+	return false
+}
+
+// moduleCore 按日志记录器名称对应的模块级别过滤日志，未设置级别的模块使用全局级别
+//
+// 底层Core的级别为所有级别中最低的一个，过滤在 Check 中进行。
+type moduleCore struct {
+	zapcore.Core
+	global  zapcore.Level
+	modules map[string]zapcore.Level
+}
+
+// levelFor 返回日志记录器名称对应的级别，依次查找名称本身和各级上级模块
+func (c *moduleCore) levelFor(name string) zapcore.Level {
+	for name != "" {
+		if level, ok := c.modules[name]; ok {
+			return level
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return c.global
+}
+
+// With 实现 zapcore.Core 接口，添加字段后保留模块级别
+func (c *moduleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &moduleCore{Core: c.Core.With(fields), global: c.global, modules: c.modules}
+}
+
+// Check 实现 zapcore.Core 接口，低于所属模块级别的日志不输出
+func (c *moduleCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < c.levelFor(entry.LoggerName) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
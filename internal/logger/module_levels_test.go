@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		spec        string
+		wantGlobal  string
+		wantModules map[string]string
+		wantErr     bool
+	}{
+		{spec: "info", wantGlobal: "info", wantModules: map[string]string{}},
+		{spec: "", wantGlobal: "", wantModules: map[string]string{}},
+		{spec: "warning", wantGlobal: "warning", wantModules: map[string]string{}},
+		{spec: "info, OCR.client=debug ,config=warn", wantGlobal: "info", wantModules: map[string]string{"ocr.client": "debug", "config": "warn"}},
+		{spec: "ocr=debug", wantGlobal: "", wantModules: map[string]string{"ocr": "debug"}},
+		{spec: "inof", wantErr: true},
+		{spec: "verbose,ocr=debug", wantErr: true},
+		{spec: "info,ocr=loud", wantErr: true},
+		{spec: "info,=debug", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			global, modules, err := ParseLevels(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseLevels(%q) 没有返回错误", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLevels(%q) 返回错误: %v", tt.spec, err)
+			}
+			if global != tt.wantGlobal {
+				t.Errorf("全局级别 = %q，期望 %q", global, tt.wantGlobal)
+			}
+			if !reflect.DeepEqual(modules, tt.wantModules) {
+				t.Errorf("模块级别 = %v，期望 %v", modules, tt.wantModules)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nerdneilsfield/go-mistral-ocr/pkg/ocr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus 将客户端的API请求指标导出到Prometheus，实现了 ocr.MetricsCollector
+//
+// 本包是单独的Go模块（github.com/nerdneilsfield/go-mistral-ocr/pkg/metrics），只有导入本包的程序才会依赖Prometheus客户端库，
+// 主模块和命令行工具不依赖它。
+type Prometheus struct {
+	requests  *prometheus.CounterVec
+	retries   *prometheus.CounterVec
+	failovers *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+}
+
+var _ ocr.MetricsCollector = (*Prometheus)(nil)
+
+// NewPrometheus 创建指标并注册到reg，reg为nil时注册到 prometheus.DefaultRegisterer
+//
+// 导出的指标：
+//   - mistral_ocr_requests_total{operation,endpoint,status}：发送的请求数，status为 2xx、4xx、5xx 等状态码类别，未收到响应时为 error
+//   - mistral_ocr_retries_total{operation,endpoint}：在同一端点上的重试次数
+//   - mistral_ocr_failovers_total{operation,from,to}：切换端点的次数
+//   - mistral_ocr_request_duration_seconds{operation}：请求耗时
+func NewPrometheus(reg prometheus.Registerer) (*Prometheus, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	p := &Prometheus{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mistral_ocr_requests_total",
+			Help: "发送到Mistral OCR API的请求数",
+		}, []string{"operation", "endpoint", "status"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mistral_ocr_retries_total",
+			Help: "在同一端点上重试请求的次数",
+		}, []string{"operation", "endpoint"}),
+		failovers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mistral_ocr_failovers_total",
+			Help: "请求失败后切换端点的次数",
+		}, []string{"operation", "from", "to"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "mistral_ocr_request_duration_seconds",
+			Help: "Mistral OCR API请求的耗时",
+			// OCR请求可能持续数分钟，在默认区间的基础上增加更长的区间
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		}, []string{"operation"}),
+	}
+
+	for _, c := range []prometheus.Collector{p.requests, p.retries, p.failovers, p.latency} {
+		if err := reg.Register(c); err != nil {
+			var already prometheus.AlreadyRegisteredError
+			if errors.As(err, &already) {
+				return nil, fmt.Errorf("指标已注册，每个Registerer只能创建一个 Prometheus: %w", err)
+			}
+			return nil, fmt.Errorf("注册指标失败: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// ObserveRequest 记录一次请求的状态码类别和耗时
+func (p *Prometheus) ObserveRequest(op, endpoint string, statusCode int, latency time.Duration) {
+	p.requests.WithLabelValues(op, endpoint, statusClass(statusCode)).Inc()
+	p.latency.WithLabelValues(op).Observe(latency.Seconds())
+}
+
+// ObserveRetry 记录一次在同一端点上的重试
+func (p *Prometheus) ObserveRetry(op, endpoint string) {
+	p.retries.WithLabelValues(op, endpoint).Inc()
+}
+
+// ObserveFailover 记录一次端点切换
+func (p *Prometheus) ObserveFailover(op, from, to string) {
+	p.failovers.WithLabelValues(op, from, to).Inc()
+}
+
+// statusClass 返回状态码的类别，如 404 返回 4xx，0 表示未收到响应，返回 error
+func statusClass(statusCode int) string {
+	if statusCode <= 0 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
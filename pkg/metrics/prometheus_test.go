@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	p, err := NewPrometheus(reg)
+	if err != nil {
+		t.Fatalf("NewPrometheus 返回错误: %v", err)
+	}
+	if _, err := NewPrometheus(reg); err == nil {
+		t.Error("在同一个Registerer上重复创建没有返回错误")
+	}
+
+	p.ObserveRequest("ocr", "https://api.example.com", 200, time.Second)
+	p.ObserveRequest("ocr", "https://api.example.com", 503, time.Second)
+	p.ObserveRequest("ocr", "https://api.example.com", 0, time.Second)
+	p.ObserveRetry("ocr", "https://api.example.com")
+	p.ObserveFailover("ocr", "https://api.example.com", "https://backup.example.com")
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetCounter() != nil:
+				counts[family.GetName()] += metric.GetCounter().GetValue()
+			case metric.GetHistogram() != nil:
+				counts[family.GetName()] += float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	want := map[string]float64{
+		"mistral_ocr_requests_total":           3,
+		"mistral_ocr_retries_total":            1,
+		"mistral_ocr_failovers_total":          1,
+		"mistral_ocr_request_duration_seconds": 3,
+	}
+	for name, value := range want {
+		if counts[name] != value {
+			t.Errorf("%s = %v，期望 %v", name, counts[name], value)
+		}
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := map[int]string{0: "error", -1: "error", 200: "2xx", 404: "4xx", 503: "5xx"}
+	for code, want := range tests {
+		if got := statusClass(code); got != want {
+			t.Errorf("statusClass(%d) = %s，期望 %s", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ocr
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
+// AnnotationsFileName 结构化标注的文件名
+const AnnotationsFileName = "annotations.json"
+
+// DocumentAnnotations 表示从OCR响应中提取的结构化标注，保存为annotations.json
+type DocumentAnnotations struct {
+	DocumentAnnotation json.RawMessage   `json:"document_annotation,omitempty"` // 文档级标注
+	Pages              []PageAnnotations `json:"pages"`                         // 各页面的标注
+}
+
+// PageAnnotations 表示单个页面的标注
+type PageAnnotations struct {
+	Index  int                `json:"index"`            // 页面索引
+	Tables []json.RawMessage  `json:"tables,omitempty"` // 表格
+	Images []ImageAnnotations `json:"images,omitempty"` // 图片及其位置
+}
+
+// ImageAnnotations 表示单个图片的位置和标注
+type ImageAnnotations struct {
+	ID           string          `json:"id"`
+	TopLeftX     int             `json:"top_left_x"`
+	TopLeftY     int             `json:"top_left_y"`
+	BottomRightX int             `json:"bottom_right_x"`
+	BottomRightY int             `json:"bottom_right_y"`
+	Annotation   json.RawMessage `json:"annotation,omitempty"`
+}
+
+// hasAnnotations 判断响应中是否包含结构化标注（文档标注、图片标注或表格）
+func (r *OCRResponse) hasAnnotations() bool {
+	if r.DocumentAnnotation != nil {
+		return true
+	}
+	for _, page := range r.Pages {
+		if len(page.Tables) > 0 {
+			return true
+		}
+		for _, img := range page.Images {
+			if img.ImageAnnotation != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Annotations 提取响应中的结构化标注，没有标注时返回nil
+func (r *OCRResponse) Annotations() *DocumentAnnotations {
+	if !r.hasAnnotations() {
+		return nil
+	}
+
+	annotations := &DocumentAnnotations{
+		Pages: make([]PageAnnotations, 0, len(r.Pages)),
+	}
+	if r.DocumentAnnotation != nil {
+		annotations.DocumentAnnotation = annotationJSON(*r.DocumentAnnotation)
+	}
+
+	for _, page := range r.Pages {
+		pageAnnotations := PageAnnotations{
+			Index:  page.Index,
+			Tables: page.Tables,
+		}
+		for _, img := range page.Images {
+			imageAnnotations := ImageAnnotations{
+				ID:           img.ID,
+				TopLeftX:     img.TopLeftX,
+				TopLeftY:     img.TopLeftY,
+				BottomRightX: img.BottomRightX,
+				BottomRightY: img.BottomRightY,
+			}
+			if img.ImageAnnotation != nil {
+				imageAnnotations.Annotation = annotationJSON(*img.ImageAnnotation)
+			}
+			pageAnnotations.Images = append(pageAnnotations.Images, imageAnnotations)
+		}
+		annotations.Pages = append(annotations.Pages, pageAnnotations)
+	}
+
+	return annotations
+}
+
+// annotationJSON API以字符串形式返回标注，内容是合法JSON时直接嵌入，否则保存为JSON字符串
+func annotationJSON(annotation string) json.RawMessage {
+	if json.Valid([]byte(annotation)) {
+		return json.RawMessage(annotation)
+	}
+	data, _ := json.Marshal(annotation)
+	return data
+}
+
+// saveAnnotations 将结构化标注写入输出目录，响应中没有标注时不生成文件
+func (p *Processor) saveAnnotations(resp *OCRResponse, outputDir string, opts ProcessOptions) (bool, error) {
+	annotations := resp.Annotations()
+	if annotations == nil {
+		return false, nil
+	}
+
+	data, err := json.MarshalIndent(annotations, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("生成标注数据失败: %w", err)
+	}
+
+	annotationsPath := filepath.Join(outputDir, AnnotationsFileName)
+	if err := opts.writeFile(annotationsPath, data); err != nil {
+		return false, fmt.Errorf("保存标注文件错误: %w", err)
+	}
+	return true, nil
+}
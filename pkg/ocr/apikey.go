@@ -0,0 +1,108 @@
+package ocr
+
+import (
+	"context"
+	"sync"
+)
+
+// apiKeyPinKey 上下文中固定的API密钥的键
+type apiKeyPinKey struct{}
+
+// apiKeyPin 单个文件的处理流程共用的API密钥，上传成功前换用其他密钥后请求成功时更新
+type apiKeyPin struct {
+	mu       sync.Mutex
+	key      string
+	uploaded bool // 文件已使用该密钥上传，之后不再更换
+}
+
+// WithAPIKey 返回固定使用apiKey的上下文，apiKey为空时返回原上下文
+//
+// 使用该上下文的上传、获取签名URL和OCR请求（包括重试、分批请求和签名URL失效后的重新获取）都使用这个密钥，
+// 优先于作为参数传入的密钥；签名URL必须使用上传时的密钥获取，因此一个文件的所有请求应使用同一个密钥。
+// 上传成功前某个请求认证失败、换用其他密钥后成功时，后续请求改用成功的密钥；
+// 上传成功后文件只能用上传时的密钥访问，获取签名URL和OCR请求认证失败时直接返回错误，不再换用其他密钥。
+// Processor 处理每个文件前从轮询中取一个密钥并固定，使各密钥按文件数平均分配。
+func WithAPIKey(ctx context.Context, apiKey string) context.Context {
+	if apiKey == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, apiKeyPinKey{}, &apiKeyPin{key: apiKey})
+}
+
+// pinnedAPIKey 返回上下文中固定的API密钥，没有固定时返回apiKey
+func pinnedAPIKey(ctx context.Context, apiKey string) string {
+	pin, ok := ctx.Value(apiKeyPinKey{}).(*apiKeyPin)
+	if !ok {
+		return apiKey
+	}
+	pin.mu.Lock()
+	defer pin.mu.Unlock()
+	return pin.key
+}
+
+// repinAPIKey 请求成功后更新上下文中固定的API密钥，使后续请求使用认证成功的密钥，文件已上传时不再更新
+func repinAPIKey(ctx context.Context, apiKey string) {
+	pin, ok := ctx.Value(apiKeyPinKey{}).(*apiKeyPin)
+	if !ok || apiKey == "" {
+		return
+	}
+	pin.mu.Lock()
+	defer pin.mu.Unlock()
+	if !pin.uploaded {
+		pin.key = apiKey
+	}
+}
+
+// pinUploadAPIKey 上传成功后将上下文中固定的API密钥设为上传使用的密钥，之后不再更换
+func pinUploadAPIKey(ctx context.Context, apiKey string) {
+	pin, ok := ctx.Value(apiKeyPinKey{}).(*apiKeyPin)
+	if !ok || apiKey == "" {
+		return
+	}
+	pin.mu.Lock()
+	defer pin.mu.Unlock()
+	pin.key = apiKey
+	pin.uploaded = true
+}
+
+// uploadKeyPinned 判断上下文中固定的API密钥是否已用于上传文件，此时认证失败不能换用其他密钥
+func uploadKeyPinned(ctx context.Context) bool {
+	pin, ok := ctx.Value(apiKeyPinKey{}).(*apiKeyPin)
+	if !ok {
+		return false
+	}
+	pin.mu.Lock()
+	defer pin.mu.Unlock()
+	return pin.uploaded
+}
+
+// fileAPIKey 返回上下文中固定的API密钥，没有固定时从轮询中取下一个密钥
+func (c *Client) fileAPIKey(ctx context.Context) string {
+	if apiKey := pinnedAPIKey(ctx, ""); apiKey != "" {
+		return apiKey
+	}
+	return c.NextAPIKey()
+}
+
+// nextKeyAfter 返回密钥列表中apiKey之后第一个不在tried中的密钥，所有密钥都已尝试时返回false
+//
+// 认证失败时换用密钥不改变 NextAPIKey 的轮询位置，避免个别失败的请求使密钥的分配不均。
+func (c *Client) nextKeyAfter(apiKey string, tried map[string]bool) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := 0
+	for i, key := range c.apiKeys {
+		if key == apiKey {
+			start = i + 1
+			break
+		}
+	}
+	for i := 0; i < len(c.apiKeys); i++ {
+		key := c.apiKeys[(start+i)%len(c.apiKeys)]
+		if !tried[key] {
+			return key, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,109 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// keyRecordingServer 记录每个请求使用的API密钥的测试服务器，signed URL和OCR请求只接受 goodKey
+type keyRecordingServer struct {
+	goodKey string
+
+	mu   sync.Mutex
+	keys map[string][]string // 请求类型 -> 按顺序使用的密钥
+}
+
+func (s *keyRecordingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	op := "ocr"
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/files"):
+		op = "upload"
+	case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/files/"):
+		op = "signed_url"
+	}
+	s.mu.Lock()
+	s.keys[op] = append(s.keys[op], key)
+	s.mu.Unlock()
+
+	if op == "upload" {
+		w.Write([]byte(`{"id":"file-1"}`))
+		return
+	}
+	if key != s.goodKey {
+		http.Error(w, `{"detail":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	if op == "signed_url" {
+		w.Write([]byte(`{"url":"https://files.example.com/file-1"}`))
+		return
+	}
+	w.Write([]byte(`{"pages":[],"model":"mistral-ocr-latest","usage_info":{"pages_processed":0}}`))
+}
+
+// TestUploadPinsAPIKey 文件上传后，获取签名URL和OCR请求认证失败时不换用其他密钥
+func TestUploadPinsAPIKey(t *testing.T) {
+	pdfPath := filepath.Join(t.TempDir(), "doc.pdf")
+	writeTestPDFs(t, filepath.Dir(pdfPath), "doc.pdf")
+
+	recorder := &keyRecordingServer{goodKey: "key-bbbbbbbb", keys: make(map[string][]string)}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	client := newTestClient(t, server.URL, "key-aaaaaaaa", "key-bbbbbbbb")
+
+	ctx := WithAPIKey(context.Background(), "key-aaaaaaaa")
+	fileID, apiKey, err := client.UploadPDF(ctx, pdfPath)
+	if err != nil {
+		t.Fatalf("UploadPDF 返回错误: %v", err)
+	}
+	if apiKey != "key-aaaaaaaa" {
+		t.Errorf("上传使用了 %s，期望固定的 key-aaaaaaaa", apiKey)
+	}
+
+	_, err = client.GetSignedURL(ctx, fileID, apiKey)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("GetSignedURL 返回 %v，期望401错误", err)
+	}
+	_, err = client.ProcessOCRWithType(ctx, "https://files.example.com/file-1", "document_url", false, apiKey)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("ProcessOCRWithType 返回 %v，期望401错误", err)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	for _, op := range []string{"signed_url", "ocr"} {
+		keys := recorder.keys[op]
+		if len(keys) != 1 || keys[0] != "key-aaaaaaaa" {
+			t.Errorf("%s 请求使用的密钥 = %v，期望只用上传时的 key-aaaaaaaa 请求一次", op, keys)
+		}
+	}
+}
+
+// TestAuthFailureRotatesKeyBeforeUpload 没有上传文件时（如处理公开URL），认证失败仍换用其他密钥，成功的密钥用于后续请求
+func TestAuthFailureRotatesKeyBeforeUpload(t *testing.T) {
+	recorder := &keyRecordingServer{goodKey: "key-bbbbbbbb", keys: make(map[string][]string)}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	client := newTestClient(t, server.URL, "key-aaaaaaaa", "key-bbbbbbbb")
+
+	ctx := WithAPIKey(context.Background(), "key-aaaaaaaa")
+	if _, err := client.ProcessOCRWithType(ctx, "https://example.com/doc.pdf", "document_url", false, ""); err != nil {
+		t.Fatalf("ProcessOCRWithType 返回错误: %v", err)
+	}
+	if got := pinnedAPIKey(ctx, ""); got != "key-bbbbbbbb" {
+		t.Errorf("固定的密钥 = %s，期望换用成功的 key-bbbbbbbb", got)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if keys := recorder.keys["ocr"]; len(keys) != 2 || keys[0] != "key-aaaaaaaa" || keys[1] != "key-bbbbbbbb" {
+		t.Errorf("OCR 请求使用的密钥 = %v，期望先 key-aaaaaaaa 再 key-bbbbbbbb", keys)
+	}
+}
@@ -0,0 +1,123 @@
+package ocr
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// appendToCombined 将单个文档的markdown追加到合并输出文件中
+//
+// 图片复制到合并文件所在目录的images子目录，与已有图片重名时自动添加序号，
+// 并将markdown中的图片链接改写为新的路径。每个文档前添加分隔线和以来源命名的标题。
+func (p *Processor) appendToCombined(target string, markdown string, imageMap map[string]string, outputDir string, title string, opts ProcessOptions) error {
+	targetDir := filepath.Dir(target)
+	sharedImagesDir := filepath.Join(targetDir, "images")
+
+	if len(imageMap) > 0 {
+		if err := opts.mkdirAll(sharedImagesDir); err != nil {
+			return fmt.Errorf("创建共享images目录错误: %w", err)
+		}
+
+		// 已存在的图片文件名，避免覆盖之前追加的文档的图片
+		usedFilenames := make(map[string]bool)
+		if entries, err := os.ReadDir(sharedImagesDir); err == nil {
+			for _, entry := range entries {
+				usedFilenames[entry.Name()] = true
+			}
+		}
+
+		linkMap := make(map[string]string, len(imageMap))
+		for _, relPath := range imageMap {
+			// 图片去重后多个ID可能指向同一个文件，只复制一次
+			link := opts.imageLink(relPath)
+			if _, ok := linkMap[link]; ok {
+				continue
+			}
+			name := uniqueFilename(filepath.Base(relPath), usedFilenames)
+			if err := opts.copyFile(filepath.Join(outputDir, relPath), filepath.Join(sharedImagesDir, name)); err != nil {
+				p.logger.Warn("复制图片到共享目录失败", zap.String("image", relPath), zap.Error(err))
+				continue
+			}
+			linkMap[link] = path.Join("images", name)
+		}
+		markdown = rewriteImageLinks(markdown, linkMap)
+	}
+
+	release := p.openFiles.acquire()
+	defer release()
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, opts.fileMode())
+	if err != nil {
+		return fmt.Errorf("打开合并输出文件错误: %w", err)
+	}
+	defer file.Close()
+
+	// 文件已有内容时先添加分隔线，新文件按需写入BOM
+	hasContent, err := opts.writeBOMIfEmpty(file)
+	if err != nil {
+		return fmt.Errorf("追加合并输出错误: %w", err)
+	}
+	separator := ""
+	if hasContent {
+		separator = "\n---\n\n"
+	}
+	if _, err := fmt.Fprintf(file, "%s## %s\n\n%s\n", separator, title, markdown); err != nil {
+		return fmt.Errorf("追加合并输出错误: %w", err)
+	}
+
+	p.logger.Info("已追加到合并输出", zap.String("target", target), zap.String("title", title))
+	return nil
+}
+
+// corpusDocumentDelimiter 语料文件中每个文档前的分隔行，%s为文档名称
+const corpusDocumentDelimiter = "\n---DOC: %s---\n"
+
+// appendToCorpus 将单个文档提取的文本追加到语料文件，文档前添加带有文档名称的分隔行
+func (p *Processor) appendToCorpus(target string, name string, text string, opts ProcessOptions) error {
+	if err := opts.mkdirAll(filepath.Dir(target)); err != nil {
+		return fmt.Errorf("创建语料文件目录错误: %w", err)
+	}
+
+	release := p.openFiles.acquire()
+	defer release()
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, opts.fileMode())
+	if err != nil {
+		return fmt.Errorf("打开语料文件错误: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := opts.writeBOMIfEmpty(file); err != nil {
+		return fmt.Errorf("追加语料文件错误: %w", err)
+	}
+	if _, err := fmt.Fprintf(file, corpusDocumentDelimiter+"%s", name, text); err != nil {
+		return fmt.Errorf("追加语料文件错误: %w", err)
+	}
+
+	p.logger.Debug("已追加到语料文件", zap.String("target", target), zap.String("name", name))
+	return nil
+}
+
+// copyFile 复制文件内容，新建的文件使用设置的权限
+func (o ProcessOptions) copyFile(src, dst string) error {
+	release := o.openFiles.acquire()
+	defer release()
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, o.fileMode())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
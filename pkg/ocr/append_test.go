@@ -0,0 +1,46 @@
+package ocr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAppendToRewritesImageLinks 追加到合并文件时图片复制到共享images目录，链接始终以 / 分隔，重名的图片添加序号
+func TestAppendToRewritesImageLinks(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "first.pdf", "second.pdf")
+
+	target := filepath.Join(t.TempDir(), "kb", "knowledge.md")
+	processor := newTestProcessor(&fakeBackend{pages: 1})
+	for _, name := range []string{"first.pdf", "second.pdf"} {
+		if _, err := processor.ProcessFile(context.Background(), filepath.Join(inputDir, name), ProcessOptions{
+			OutputDir:     t.TempDir(),
+			IncludeImages: true,
+			AppendTo:      target,
+		}); err != nil {
+			t.Fatalf("处理 %s 失败: %v", name, err)
+		}
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	combined := string(data)
+	for _, want := range []string{"](images/img-0.png)", "](images/img-0-1.png)"} {
+		if !strings.Contains(combined, want) {
+			t.Errorf("合并文件中缺少链接 %q:\n%s", want, combined)
+		}
+	}
+	if strings.Contains(combined, `\`) {
+		t.Errorf("合并文件的链接中包含反斜杠:\n%s", combined)
+	}
+	for _, name := range []string{"img-0.png", "img-0-1.png"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(target), "images", name)); err != nil {
+			t.Errorf("共享images目录中缺少 %s: %v", name, err)
+		}
+	}
+}
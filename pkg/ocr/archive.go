@@ -0,0 +1,69 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// validateArchiveSource 校验 ArchiveSource 只在启用缓存时使用，归档文件与缓存的响应保存在一起
+func (o ProcessOptions) validateArchiveSource() error {
+	if o.ArchiveSource && o.CacheDir == "" {
+		return fmt.Errorf("归档上传内容需要启用缓存（CacheDir）")
+	}
+	return nil
+}
+
+// archivePath 返回缓存键对应的上传内容归档文件路径，保留源文件的扩展名
+func archivePath(cacheDir, key, name string) string {
+	return filepath.Join(cacheDir, key+".source"+filepath.Ext(name))
+}
+
+// archiveSource 通过签名URL下载Mistral实际处理的内容，保存到缓存目录中缓存键对应的归档文件
+//
+// 内容以流的方式写入磁盘，不读入内存。expectedSHA256 为上传内容的SHA-256，不为空时与下载的内容核对，
+// 不一致时输出警告。归档失败不影响OCR处理，只输出警告。
+func (p *Processor) archiveSource(ctx context.Context, signedURL, name, expectedSHA256 string, opts ProcessOptions, metadata *ProcessMetadata) {
+	if !opts.ArchiveSource || metadata.CacheKey == "" {
+		return
+	}
+	if err := opts.mkdirAll(opts.CacheDir); err != nil {
+		p.logger.Warn("创建缓存目录失败，跳过归档", zap.String("cacheDir", opts.CacheDir), zap.Error(err))
+		return
+	}
+
+	path := archivePath(opts.CacheDir, metadata.CacheKey, name)
+	tmpPath := path + ".tmp"
+	if err := p.downloadToFile(ctx, signedURL, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		p.logger.Warn("下载上传内容失败，跳过归档", zap.String("name", name), zap.Error(err))
+		return
+	}
+
+	sum, size, err := hashSourceFile(tmpPath, opts)
+	if err != nil {
+		os.Remove(tmpPath)
+		p.logger.Warn("计算归档内容哈希失败，跳过归档", zap.String("path", tmpPath), zap.Error(err))
+		return
+	}
+	if err := os.Chmod(tmpPath, opts.fileMode()); err != nil {
+		p.logger.Warn("设置归档文件权限失败", zap.String("path", tmpPath), zap.Error(err))
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		p.logger.Warn("保存归档文件失败", zap.String("path", path), zap.Error(err))
+		return
+	}
+
+	metadata.ArchivedSource = path
+	metadata.ArchivedSHA256 = sum
+	if expectedSHA256 != "" && sum != expectedSHA256 {
+		p.logger.Warn("Mistral收到的内容与上传的内容不一致",
+			zap.String("name", name), zap.String("expected", expectedSHA256), zap.String("archived", sum), zap.Int64("size", size))
+		return
+	}
+	p.logger.Debug("归档了上传内容", zap.String("path", path), zap.Int64("size", size))
+}
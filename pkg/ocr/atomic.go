@@ -0,0 +1,229 @@
+package ocr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// stagingDirPattern 启用 AtomicOutput 时本地临时目录的名称模式
+const stagingDirPattern = "mistral-ocr-*"
+
+// newStagingDir 在系统临时目录中创建用于组装输出的目录，权限与输出目录相同
+func (o ProcessOptions) newStagingDir() (string, error) {
+	dir, err := os.MkdirTemp("", stagingDirPattern)
+	if err != nil {
+		return "", fmt.Errorf("创建临时输出目录错误: %w", err)
+	}
+	if err := os.Chmod(dir, o.dirMode()); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("设置临时输出目录权限错误: %w", err)
+	}
+	return dir, nil
+}
+
+// saveOutput 保存OCR结果到outputDir
+//
+// 启用 AtomicOutput 时先在本地临时目录（流式解析时为解析过程中使用的临时目录）中生成所有文件，
+// 完成后再移动到outputDir，处理中途失败不会留下不完整的输出目录。
+func (p *Processor) saveOutput(resp *OCRResponse, outputDir string, metadata ProcessMetadata, opts ProcessOptions) (*ProcessResult, error) {
+	if !opts.AtomicOutput {
+		if err := opts.mkdirAll(outputDir); err != nil {
+			return nil, fmt.Errorf("创建输出目录错误: %w", err)
+		}
+		return p.saveResults(resp, outputDir, metadata, opts)
+	}
+
+	stagingDir := opts.stagingDir
+	if stagingDir == "" {
+		dir, err := opts.newStagingDir()
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		stagingDir = dir
+	}
+	p.logger.Debug("在临时目录中生成输出", zap.String("stagingDir", stagingDir), zap.String("outputDir", outputDir))
+
+	result, err := p.saveResults(resp, stagingDir, metadata, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.moveOutputDir(stagingDir, outputDir, opts); err != nil {
+		return nil, fmt.Errorf("移动输出目录错误: %w", err)
+	}
+
+	// 结果中的路径指向最终的输出目录
+	if rel, err := filepath.Rel(stagingDir, result.ImagesDir); err == nil {
+		result.ImagesDir = filepath.Join(outputDir, rel)
+	}
+	result.OutputDir = outputDir
+	result.MetadataPath = filepath.Join(outputDir, MetadataFileName)
+	return result, nil
+}
+
+// moveOutputDir 将src目录移动到dst
+//
+// dst不存在时整体重命名，跨设备时先复制到dst所在目录下的临时目录再重命名，保证dst只在完整时出现。
+// dst已存在时（如重新处理或引用已有图片）逐个移动其中的文件，output.md最后移动，
+// 避免 checkOutputDir 把尚未写完的目录当作已完成。
+func (p *Processor) moveOutputDir(src, dst string, opts ProcessOptions) error {
+	if _, err := os.Stat(dst); os.IsNotExist(err) {
+		if err := opts.mkdirAll(filepath.Dir(dst)); err != nil {
+			return err
+		}
+		err := os.Rename(src, dst)
+		if err == nil {
+			return nil
+		}
+		if isCrossDevice(err) {
+			p.logger.Debug("临时目录与输出目录不在同一设备，复制输出", zap.String("outputDir", dst))
+			return copyDirAtomic(src, dst, p.openFiles)
+		}
+		// 其他进程可能同时创建了dst，此时改为合并
+		if _, statErr := os.Stat(dst); statErr != nil {
+			return err
+		}
+	}
+	return mergeDir(src, dst, p.openFiles)
+}
+
+// isCrossDevice 判断重命名是否因源和目标不在同一设备（文件系统）而失败
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// copyDirAtomic 将src复制到dst所在目录下的临时目录，完成后重命名为dst
+func copyDirAtomic(src, dst string, limit fileLimiter) error {
+	tmp, err := os.MkdirTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if err := copyDir(src, tmp, limit); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return nil
+}
+
+// copyDir 将src目录下的内容复制到已存在的dst目录，保留文件和目录的权限
+func copyDir(src, dst string, limit fileLimiter) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chmod(target, info.Mode().Perm())
+		}
+		return copyFileAtomic(path, target, info.Mode().Perm(), limit)
+	})
+}
+
+// copyFileAtomic 将src复制到dst所在目录下的临时文件，完成后重命名为dst
+func copyFileAtomic(src, dst string, mode os.FileMode, limit fileLimiter) error {
+	release := limit.acquire()
+	defer release()
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// mergeDir 将src中的文件逐个移动到已存在的dst目录，同名文件被替换，顶层的output.md最后移动
+func mergeDir(src, dst string, limit fileLimiter) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	var last []os.DirEntry
+	for _, entry := range entries {
+		if entry.Name() == "output.md" {
+			last = append(last, entry)
+			continue
+		}
+		if err := moveEntry(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), entry, limit); err != nil {
+			return err
+		}
+	}
+	for _, entry := range last {
+		if err := moveEntry(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), entry, limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveEntry 将单个文件或目录移动到dst，目标目录已存在时合并，跨设备时复制
+func moveEntry(src, dst string, entry os.DirEntry, limit fileLimiter) error {
+	if entry.IsDir() {
+		if info, err := os.Stat(dst); err == nil && info.IsDir() {
+			return mergeDir(src, dst, limit)
+		}
+		if err := os.Rename(src, dst); err != nil {
+			if !isCrossDevice(err) {
+				return err
+			}
+			return copyDirAtomic(src, dst, limit)
+		}
+		return nil
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		if !isCrossDevice(err) {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return copyFileAtomic(src, dst, info.Mode().Perm(), limit)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// writeHookCore 在写入每条日志前调用hook，用于在处理过程中观察日志字段指向的文件
+type writeHookCore struct {
+	zapcore.Core
+	hook func(zapcore.Entry, []zapcore.Field)
+}
+
+func (c writeHookCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c writeHookCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	c.hook(entry, fields)
+	return c.Core.Write(entry, fields)
+}
+
+// TestAtomicOutputFailedOCR OCR失败时不创建输出目录
+func TestAtomicOutputFailedOCR(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "report.pdf")
+	t.Setenv("TMPDIR", t.TempDir())
+
+	outputRoot := filepath.Join(t.TempDir(), "out")
+	processor := newTestProcessor(&fakeBackend{pages: 1, ocrErr: errors.New("OCR服务不可用")})
+	_, err := processor.ProcessFile(context.Background(), filepath.Join(inputDir, "report.pdf"), ProcessOptions{
+		OutputDir:    outputRoot,
+		AtomicOutput: true,
+	})
+	if err == nil {
+		t.Fatal("OCR失败时 ProcessFile 没有返回错误")
+	}
+	if _, err := os.Stat(filepath.Join(outputRoot, "report")); !os.IsNotExist(err) {
+		t.Errorf("OCR失败后留下了输出目录: %v", err)
+	}
+}
+
+// TestAtomicOutputSingleRename 输出目录不存在时，临时目录整体重命名为输出目录，而不是逐个移动文件
+func TestAtomicOutputSingleRename(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "report.pdf")
+	// 临时目录与输出目录在同一文件系统上，才能直接重命名
+	t.Setenv("TMPDIR", t.TempDir())
+
+	var staging os.FileInfo
+	core, _ := observer.New(zapcore.DebugLevel)
+	logger := zap.New(writeHookCore{Core: core, hook: func(entry zapcore.Entry, fields []zapcore.Field) {
+		if entry.Message != "在临时目录中生成输出" {
+			return
+		}
+		for _, field := range fields {
+			if field.Key == "stagingDir" {
+				info, err := os.Stat(field.String)
+				if err != nil {
+					t.Errorf("临时目录不存在: %v", err)
+					return
+				}
+				staging = info
+			}
+		}
+	}})
+
+	outputRoot := filepath.Join(t.TempDir(), "out")
+	processor := NewProcessor(&fakeBackend{pages: 2}, logger)
+	result, err := processor.ProcessFile(context.Background(), filepath.Join(inputDir, "report.pdf"), ProcessOptions{
+		OutputDir:     outputRoot,
+		IncludeImages: true,
+		AtomicOutput:  true,
+	})
+	if err != nil {
+		t.Fatalf("ProcessFile 返回错误: %v", err)
+	}
+	if staging == nil {
+		t.Fatal("没有在临时目录中生成输出")
+	}
+
+	outputDir := filepath.Join(outputRoot, "report")
+	if result.OutputDir != outputDir {
+		t.Errorf("OutputDir = %s，期望 %s", result.OutputDir, outputDir)
+	}
+	info, err := os.Stat(outputDir)
+	if err != nil {
+		t.Fatalf("输出目录不存在: %v", err)
+	}
+	if !os.SameFile(staging, info) {
+		t.Error("输出目录不是由临时目录重命名得到的")
+	}
+	for _, name := range []string{"output.md", MetadataFileName, filepath.Join("images", "img-0.png"), filepath.Join("images", "img-1.png")} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("输出中缺少 %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ocr
+
+import (
+	"context"
+	"sync"
+)
+
+// AttemptStats 记录处理单个文件时各API请求的尝试次数和最终使用的端点
+//
+// 无论请求最终成功还是失败都会记录，可用于在大批量处理中找出不稳定的端点。
+type AttemptStats struct {
+	AttemptsUpload    int    `json:"attempts_upload"`         // 上传请求的发送次数
+	AttemptsSignedURL int    `json:"attempts_signed_url"`     // 获取签名URL请求的发送次数
+	AttemptsOCR       int    `json:"attempts_ocr"`            // OCR请求的发送次数
+	EndpointSwitches  int    `json:"endpoint_switches"`       // 切换端点的次数
+	EndpointUsed      string `json:"endpoint_used,omitempty"` // 最后一次成功请求使用的端点
+}
+
+// attemptStatsMu 保护对AttemptStats的并发写入
+var attemptStatsMu sync.Mutex
+
+// 需要记录尝试次数的请求类型
+const (
+	attemptOpUpload    = "upload"
+	attemptOpSignedURL = "signed_url"
+	attemptOpOCR       = "ocr"
+)
+
+type attemptStatsKey struct{}
+
+// WithAttemptStats 返回携带尝试次数记录的上下文，客户端会将请求的尝试次数累加到stats中
+func WithAttemptStats(ctx context.Context, stats *AttemptStats) context.Context {
+	return context.WithValue(ctx, attemptStatsKey{}, stats)
+}
+
+// attemptStatsFromContext 返回上下文中的尝试次数记录，没有时返回nil
+func attemptStatsFromContext(ctx context.Context) *AttemptStats {
+	stats, _ := ctx.Value(attemptStatsKey{}).(*AttemptStats)
+	return stats
+}
+
+// Retries 返回所有请求的重试总次数（不含每个请求的首次尝试）
+func (s *AttemptStats) Retries() int {
+	total := 0
+	for _, attempts := range []int{s.AttemptsUpload, s.AttemptsSignedURL, s.AttemptsOCR} {
+		if attempts > 1 {
+			total += attempts - 1
+		}
+	}
+	return total
+}
+
+// recordAttempts 将一次请求的尝试次数累加到上下文中的记录，endpoint为空表示请求最终失败
+func recordAttempts(ctx context.Context, op string, attempts int, endpointsTried int, endpoint string) {
+	stats := attemptStatsFromContext(ctx)
+	if stats == nil {
+		return
+	}
+
+	attemptStatsMu.Lock()
+	defer attemptStatsMu.Unlock()
+
+	switch op {
+	case attemptOpUpload:
+		stats.AttemptsUpload += attempts
+	case attemptOpSignedURL:
+		stats.AttemptsSignedURL += attempts
+	case attemptOpOCR:
+		stats.AttemptsOCR += attempts
+	}
+	if endpointsTried > 1 {
+		stats.EndpointSwitches += endpointsTried - 1
+	}
+	if endpoint != "" {
+		stats.EndpointUsed = endpoint
+	}
+}
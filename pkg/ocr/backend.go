@@ -0,0 +1,29 @@
+package ocr
+
+import (
+	"context"
+	"io"
+)
+
+// OCRBackend 表示OCR服务后端，Processor 通过它完成上传、获取签名URL和OCR调用
+//
+// *Client 是Mistral API的实现，也可以实现该接口接入其他OCR服务，或在测试中替换为不访问网络的假后端。
+type OCRBackend interface {
+	// UploadPDF 上传文件，返回文件ID和上传使用的API密钥
+	UploadPDF(ctx context.Context, filePath string) (string, string, error)
+	// UploadReader 上传reader中的内容，返回文件ID和上传使用的API密钥
+	UploadReader(ctx context.Context, r io.ReadSeeker, filename string) (string, string, error)
+	// GetSignedURL 获取已上传文件的签名URL
+	GetSignedURL(ctx context.Context, fileID string, apiKey string) (string, error)
+	// ProcessOCRWithType 对指定类型（document_url 或 image_url）的URL进行OCR
+	ProcessOCRWithType(ctx context.Context, documentURL string, documentType string, includeImageBase64 bool, apiKey string) (*OCRResponse, error)
+	// DetectContentType 获取URL指向内容的Content-Type
+	DetectContentType(ctx context.Context, targetURL string) (string, error)
+	// DownloadToFile 下载URL指向的内容到本地文件
+	DownloadToFile(ctx context.Context, sourceURL string, dstPath string) error
+	// NextAPIKey 返回下一个要使用的API密钥
+	NextAPIKey() string
+}
+
+// 确保 *Client 实现了 OCRBackend 接口
+var _ OCRBackend = (*Client)(nil)
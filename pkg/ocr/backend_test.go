@@ -0,0 +1,199 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// testPNG 1x1像素的PNG图片
+var testPNG, _ = base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAIAAACQd1PeAAAADElEQVR4nGP4z8AAAAMBAQDJ/pLvAAAAAElFTkSuQmCC")
+
+// fakeBackend 不访问网络的OCR后端，每个文档返回 pages 页，每页包含一张图片
+//
+// 请求的页面超出 pages 时返回错误，与API对不存在的页面的处理一致。
+type fakeBackend struct {
+	pages  int
+	ocrErr error // 不为nil时OCR请求返回该错误
+
+	mu           sync.Mutex
+	uploads      []string // 上传的文件名，按上传顺序
+	ocrCalls     int
+	pageRequests [][]int // ProcessOCRPages 请求的页面
+}
+
+var (
+	_ OCRBackend          = (*fakeBackend)(nil)
+	_ PageRangeOCRBackend = (*fakeBackend)(nil)
+)
+
+func (f *fakeBackend) UploadPDF(ctx context.Context, filePath string) (string, string, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		return "", "", err
+	}
+	return f.recordUpload(filepath.Base(filePath)), "fake-key", nil
+}
+
+func (f *fakeBackend) UploadReader(ctx context.Context, r io.ReadSeeker, filename string) (string, string, error) {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return "", "", err
+	}
+	return f.recordUpload(filename), "fake-key", nil
+}
+
+func (f *fakeBackend) recordUpload(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.uploads = append(f.uploads, name)
+	return fmt.Sprintf("file-%d", len(f.uploads))
+}
+
+func (f *fakeBackend) GetSignedURL(ctx context.Context, fileID string, apiKey string) (string, error) {
+	return "https://files.example.com/" + fileID, nil
+}
+
+func (f *fakeBackend) ProcessOCRWithType(ctx context.Context, documentURL string, documentType string, includeImageBase64 bool, apiKey string) (*OCRResponse, error) {
+	f.mu.Lock()
+	f.ocrCalls++
+	f.mu.Unlock()
+	if f.ocrErr != nil {
+		return nil, f.ocrErr
+	}
+	return f.response(pageRange(0, f.pages), includeImageBase64)
+}
+
+func (f *fakeBackend) ProcessOCRPages(ctx context.Context, documentURL string, documentType string, includeImageBase64 bool, apiKey string, pages []int) (*OCRResponse, error) {
+	f.mu.Lock()
+	f.ocrCalls++
+	f.pageRequests = append(f.pageRequests, append([]int(nil), pages...))
+	f.mu.Unlock()
+	for _, page := range pages {
+		if page >= f.pages {
+			return nil, &APIError{Operation: "OCR处理", StatusCode: 400, Body: fmt.Sprintf("page %d out of range", page)}
+		}
+	}
+	return f.response(pages, includeImageBase64)
+}
+
+// response 构建包含指定页面的响应，原始响应与解析结果一致
+func (f *fakeBackend) response(pages []int, includeImageBase64 bool) (*OCRResponse, error) {
+	resp := &OCRResponse{Model: "fake-ocr", UsageInfo: UsageInfo{PagesProcessed: len(pages)}}
+	for _, index := range pages {
+		imageID := fmt.Sprintf("img-%d.png", index)
+		image := Image{ID: imageID}
+		if includeImageBase64 {
+			image.ImageBase64 = "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG)
+		}
+		resp.Pages = append(resp.Pages, Page{
+			Index:    index,
+			Markdown: fmt.Sprintf("# Page %d\n\nText of page %d.\n\n![%s](%s)", index+1, index+1, imageID, imageID),
+			Images:   []Image{image},
+		})
+	}
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.RawResponse = raw
+	return resp, nil
+}
+
+func (f *fakeBackend) DetectContentType(ctx context.Context, targetURL string) (string, error) {
+	return "application/pdf", nil
+}
+
+func (f *fakeBackend) DownloadToFile(ctx context.Context, sourceURL string, dstPath string) error {
+	return fmt.Errorf("fakeBackend 不支持下载")
+}
+
+func (f *fakeBackend) NextAPIKey() string {
+	return "fake-key"
+}
+
+// newTestProcessor 创建使用假后端、不输出日志的处理器
+func newTestProcessor(backend OCRBackend) *Processor {
+	return NewProcessor(backend, zap.NewNop())
+}
+
+// writeTestPDFs 在dir中创建内容最简单的PDF文件，names可以包含子目录
+func writeTestPDFs(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("%PDF-1.4\n% "+name+"\n%%EOF\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestProcessFileWithFakeBackend 使用不访问网络的后端完整处理一个文件，检查输出文件、图片和元数据
+func TestProcessFileWithFakeBackend(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "report.pdf")
+	sourcePath := filepath.Join(inputDir, "report.pdf")
+
+	backend := &fakeBackend{pages: 2}
+	result, err := newTestProcessor(backend).ProcessFile(context.Background(), sourcePath, ProcessOptions{
+		OutputDir:     t.TempDir(),
+		IncludeImages: true,
+	})
+	if err != nil {
+		t.Fatalf("ProcessFile 返回错误: %v", err)
+	}
+	if len(backend.uploads) != 1 || backend.uploads[0] != "report.pdf" || backend.ocrCalls != 1 {
+		t.Errorf("上传 %v、OCR请求 %d 次，期望上传report.pdf并请求一次", backend.uploads, backend.ocrCalls)
+	}
+	if filepath.Base(result.OutputDir) != "report" || result.Pages != 2 || result.Images != 2 {
+		t.Errorf("输出目录 %s、页数 %d、图片 %d，期望 report、2、2", result.OutputDir, result.Pages, result.Images)
+	}
+
+	markdown, err := os.ReadFile(filepath.Join(result.OutputDir, "output.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Page 1", "Text of page 1.", "![img-0.png](images/img-0.png)", "Text of page 2.", "![img-1.png](images/img-1.png)"} {
+		if !strings.Contains(string(markdown), want) {
+			t.Errorf("output.md 中缺少 %q:\n%s", want, markdown)
+		}
+	}
+
+	for _, name := range []string{"img-0.png", "img-1.png"} {
+		data, err := os.ReadFile(filepath.Join(result.ImagesDir, name))
+		if err != nil {
+			t.Fatalf("读取图片失败: %v", err)
+		}
+		if !bytes.Equal(data, testPNG) {
+			t.Errorf("图片 %s 的内容与响应中的不同", name)
+		}
+	}
+
+	metadata, _, err := LoadMetadata(result.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.SourceType != "file" || metadata.SourcePath != sourcePath {
+		t.Errorf("来源 = %s %s，期望 file %s", metadata.SourceType, metadata.SourcePath, sourcePath)
+	}
+	if metadata.FileID != "file-1" || metadata.DocumentURL != "https://files.example.com/file-1" {
+		t.Errorf("文件ID = %s、文档URL = %s，期望使用后端返回的值", metadata.FileID, metadata.DocumentURL)
+	}
+	if metadata.PagesProcessed != 2 || metadata.ImagesSaved != 2 || !metadata.IncludeImages {
+		t.Errorf("元数据记录了 %d 页、%d 张图片（包含图片: %v），期望 2 页 2 张", metadata.PagesProcessed, metadata.ImagesSaved, metadata.IncludeImages)
+	}
+	if metadata.OCRResponseInfo["model"] != "fake-ocr" {
+		t.Errorf("元数据中的模型 = %v，期望 fake-ocr", metadata.OCRResponseInfo["model"])
+	}
+}
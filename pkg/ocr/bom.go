@@ -0,0 +1,36 @@
+package ocr
+
+import (
+	"bytes"
+	"os"
+)
+
+// utf8BOM UTF-8字节顺序标记
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// withBOM 设置 OutputBOM 时在内容前添加UTF-8 BOM，内容已以BOM开头时不重复添加
+func (o ProcessOptions) withBOM(data []byte) []byte {
+	if !o.OutputBOM || bytes.HasPrefix(data, utf8BOM) {
+		return data
+	}
+	return append(append([]byte{}, utf8BOM...), data...)
+}
+
+// writeBOMIfEmpty 设置 OutputBOM 且追加的目标文件为空时先写入UTF-8 BOM，返回写入前文件是否已有内容
+//
+// 已有内容的文件不再写入BOM，避免多次追加时BOM出现在文件中间。
+func (o ProcessOptions) writeBOMIfEmpty(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() > 0 {
+		return true, nil
+	}
+	if o.OutputBOM {
+		if _, err := file.Write(utf8BOM); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,186 @@
+package ocr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // 注册GIF解码器
+	"image/jpeg"
+	_ "image/png" // 注册PNG解码器
+	"path/filepath"
+	"sort"
+
+	"go.uber.org/zap"
+	_ "golang.org/x/image/webp" // 注册WEBP解码器
+)
+
+// BundleImages 的可选值
+const (
+	BundleImagesNone = "none" // 不合并图片
+	BundleImagesPDF  = "pdf"  // 将保存的图片合并为 images.pdf，每张图片一页
+)
+
+// ImagesPDFFileName 合并图片生成的PDF文件名
+const ImagesPDFFileName = "images.pdf"
+
+// validateBundleImages 校验 BundleImages 的取值
+func (o ProcessOptions) validateBundleImages() error {
+	switch o.BundleImages {
+	case "", BundleImagesNone, BundleImagesPDF:
+		return nil
+	default:
+		return fmt.Errorf("无效的图片合并方式: %s，可选值为 %s 或 %s", o.BundleImages, BundleImagesPDF, BundleImagesNone)
+	}
+}
+
+// orderedImagePaths 按页面索引和页内顺序返回已保存图片的相对路径
+func orderedImagePaths(pages []Page, imageMap map[string]string) []string {
+	ordered := append([]Page(nil), pages...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Index < ordered[j].Index
+	})
+
+	var paths []string
+	for _, page := range ordered {
+		for _, img := range page.Images {
+			if relPath, ok := imageMap[img.ID]; ok {
+				paths = append(paths, relPath)
+			}
+		}
+	}
+	return paths
+}
+
+// bundleImagesPDF 将输出目录中已保存的图片按页面顺序合并为 images.pdf，返回写入的页数
+//
+// JPEG图片直接嵌入，其他格式（PNG、GIF、WEBP）解码后以压缩的RGB数据嵌入，透明部分以白色填充。
+// 无法解码的图片（如AVIF）输出警告后跳过，已保存的图片文件不受影响。
+func (p *Processor) bundleImagesPDF(pages []Page, imageMap map[string]string, outputDir string, opts ProcessOptions) (int, error) {
+	var images []pdfImage
+	for _, relPath := range orderedImagePaths(pages, imageMap) {
+		data, err := p.openFiles.readFile(filepath.Join(outputDir, relPath))
+		if err != nil {
+			return 0, fmt.Errorf("读取图片失败: %w", err)
+		}
+		img, err := newPDFImage(data)
+		if err != nil {
+			p.logger.Warn("无法嵌入PDF的图片，已跳过", zap.String("image", relPath), zap.Error(err))
+			continue
+		}
+		images = append(images, img)
+	}
+	if len(images) == 0 {
+		return 0, nil
+	}
+
+	pdfPath := filepath.Join(outputDir, ImagesPDFFileName)
+	if err := opts.writeFile(pdfPath, buildImagesPDF(images)); err != nil {
+		return 0, fmt.Errorf("写入图片PDF失败: %w", err)
+	}
+	return len(images), nil
+}
+
+// pdfImage 表示嵌入PDF的单张图片
+type pdfImage struct {
+	width, height int
+	colorSpace    string // DeviceRGB 或 DeviceGray
+	filter        string // DCTDecode 或 FlateDecode
+	data          []byte
+}
+
+// newPDFImage 根据图片内容生成可嵌入PDF的图片数据
+func newPDFImage(data []byte) (pdfImage, error) {
+	// 非CMYK的JPEG可以直接嵌入
+	if detectImageFormat(data) == ".jpeg" {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return pdfImage{}, err
+		}
+		switch cfg.ColorModel {
+		case color.YCbCrModel:
+			return pdfImage{width: cfg.Width, height: cfg.Height, colorSpace: "DeviceRGB", filter: "DCTDecode", data: data}, nil
+		case color.GrayModel:
+			return pdfImage{width: cfg.Width, height: cfg.Height, colorSpace: "DeviceGray", filter: "DCTDecode", data: data}, nil
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return pdfImage{}, err
+	}
+
+	// 转换为RGB，透明部分与白色背景混合
+	bounds := img.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			white := 0xffff - a
+			rgb = append(rgb, byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8))
+		}
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(rgb); err != nil {
+		return pdfImage{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return pdfImage{}, err
+	}
+	return pdfImage{width: bounds.Dx(), height: bounds.Dy(), colorSpace: "DeviceRGB", filter: "FlateDecode", data: compressed.Bytes()}, nil
+}
+
+// buildImagesPDF 生成每张图片占一页的PDF，页面大小与图片像素尺寸相同（1像素对应1点）
+func buildImagesPDF(images []pdfImage) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	// 对象编号：1为Catalog，2为Pages，之后每张图片依次占用页面、内容流和图片三个对象
+	beginObject := func() {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", len(offsets))
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	beginObject()
+	buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	beginObject()
+	buf.WriteString("<< /Type /Pages /Kids [")
+	for i := range images {
+		fmt.Fprintf(&buf, " %d 0 R", 3+i*3)
+	}
+	fmt.Fprintf(&buf, " ] /Count %d >>\nendobj\n", len(images))
+
+	for i, img := range images {
+		pageObj := 3 + i*3
+		contentObj := pageObj + 1
+		imageObj := pageObj + 2
+
+		beginObject()
+		fmt.Fprintf(&buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			img.width, img.height, imageObj, contentObj)
+
+		content := fmt.Sprintf("q %d 0 0 %d 0 0 cm /Im0 Do Q", img.width, img.height)
+		beginObject()
+		fmt.Fprintf(&buf, "<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+
+		beginObject()
+		fmt.Fprintf(&buf, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s /Length %d >>\nstream\n",
+			img.width, img.height, img.colorSpace, img.filter, len(img.data))
+		buf.Write(img.data)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+	return buf.Bytes()
+}
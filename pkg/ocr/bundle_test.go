@@ -0,0 +1,116 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+func TestNewPDFImage(t *testing.T) {
+	encodeJPEG := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, nil); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	rgbJPEG := encodeJPEG(image.NewRGBA(image.Rect(0, 0, 3, 2)))
+	grayJPEG := encodeJPEG(image.NewGray(image.Rect(0, 0, 2, 2)))
+
+	tests := []struct {
+		name           string
+		data           []byte
+		wantColorSpace string
+		wantFilter     string
+		wantWidth      int
+		wantHeight     int
+		wantRaw        bool // 原样嵌入
+		wantErr        bool
+	}{
+		{name: "彩色JPEG", data: rgbJPEG, wantColorSpace: "DeviceRGB", wantFilter: "DCTDecode", wantWidth: 3, wantHeight: 2, wantRaw: true},
+		{name: "灰度JPEG", data: grayJPEG, wantColorSpace: "DeviceGray", wantFilter: "DCTDecode", wantWidth: 2, wantHeight: 2, wantRaw: true},
+		{name: "PNG", data: testPNG, wantColorSpace: "DeviceRGB", wantFilter: "FlateDecode", wantWidth: 1, wantHeight: 1},
+		{name: "无法解码", data: []byte("not an image"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := newPDFImage(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("没有返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newPDFImage 返回错误: %v", err)
+			}
+			if img.colorSpace != tt.wantColorSpace || img.filter != tt.wantFilter || img.width != tt.wantWidth || img.height != tt.wantHeight {
+				t.Errorf("得到 %s %s %dx%d，期望 %s %s %dx%d", img.colorSpace, img.filter, img.width, img.height,
+					tt.wantColorSpace, tt.wantFilter, tt.wantWidth, tt.wantHeight)
+			}
+			if got := bytes.Equal(img.data, tt.data); got != tt.wantRaw {
+				t.Errorf("原样嵌入 = %v，期望 %v", got, tt.wantRaw)
+			}
+		})
+	}
+}
+
+func TestOrderedImagePaths(t *testing.T) {
+	pages := []Page{
+		{Index: 2, Images: []Image{{ID: "c"}}},
+		{Index: 0, Images: []Image{{ID: "a1"}, {ID: "a2"}, {ID: "failed"}}},
+		{Index: 1, Images: []Image{{ID: "b"}}},
+	}
+	imageMap := map[string]string{"a1": "images/a1.png", "a2": "images/a2.png", "b": "images/b.png", "c": "images/c.png"}
+
+	want := []string{"images/a1.png", "images/a2.png", "images/b.png", "images/c.png"}
+	if got := orderedImagePaths(pages, imageMap); !reflect.DeepEqual(got, want) {
+		t.Errorf("orderedImagePaths() = %v，期望 %v", got, want)
+	}
+}
+
+// TestBundleImagesPDF 处理时按页面顺序将保存的图片合并为可以被PDF阅读器打开的images.pdf
+func TestBundleImagesPDF(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "report.pdf")
+
+	result, err := newTestProcessor(&fakeBackend{pages: 3}).ProcessFile(context.Background(), filepath.Join(inputDir, "report.pdf"), ProcessOptions{
+		OutputDir:     t.TempDir(),
+		IncludeImages: true,
+		BundleImages:  BundleImagesPDF,
+	})
+	if err != nil {
+		t.Fatalf("ProcessFile 返回错误: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(result.OutputDir, ImagesPDFFileName))
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", ImagesPDFFileName, err)
+	}
+	if err := api.Validate(bytes.NewReader(data), pdfConfiguration("")); err != nil {
+		t.Errorf("生成的PDF无效: %v", err)
+	}
+	if pages, err := pdfPageCount(data, ""); err != nil || pages != 3 {
+		t.Errorf("%s 有 %d 页（%v），期望 3 页", ImagesPDFFileName, pages, err)
+	}
+
+	// 不合并时不生成images.pdf
+	result, err = newTestProcessor(&fakeBackend{pages: 1}).ProcessFile(context.Background(), filepath.Join(inputDir, "report.pdf"), ProcessOptions{
+		OutputDir:     t.TempDir(),
+		IncludeImages: true,
+		BundleImages:  BundleImagesNone,
+	})
+	if err != nil {
+		t.Fatalf("ProcessFile 返回错误: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(result.OutputDir, ImagesPDFFileName)); !os.IsNotExist(err) {
+		t.Errorf("BundleImages 为 none 时生成了 %s: %v", ImagesPDFFileName, err)
+	}
+}
@@ -0,0 +1,109 @@
+package ocr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// computeCacheKey 根据文件内容和影响OCR结果的选项计算缓存键
+func computeCacheKey(filePath string, opts ProcessOptions) (string, error) {
+	release := opts.openFiles.acquire()
+	defer release()
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("无法打开文件: %w", err)
+	}
+	defer file.Close()
+
+	return computeCacheKeyFromReader(file, opts)
+}
+
+// computeCacheKeyFromReader 根据reader中的内容和影响OCR结果的选项计算缓存键
+func computeCacheKeyFromReader(r io.Reader, opts ProcessOptions) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+
+	// 是否包含图片会改变API响应内容，需要纳入缓存键
+	fmt.Fprintf(hash, "|include_images=%t", opts.savesImages())
+	// 只处理前几页时响应只包含部分页面，与完整响应分开缓存
+	if opts.MaxPages > 0 {
+		fmt.Fprintf(hash, "|max_pages=%d", opts.MaxPages)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hashSourceFile 计算源文件内容的SHA-256和大小，用于在元数据中记录来源
+func hashSourceFile(filePath string, opts ProcessOptions) (string, int64, error) {
+	release := opts.openFiles.acquire()
+	defer release()
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, fmt.Errorf("无法打开文件: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// cachePath 返回缓存键对应的缓存文件路径
+func cachePath(cacheDir, key string) string {
+	return filepath.Join(cacheDir, key+".json")
+}
+
+// loadCachedResponse 从缓存目录读取OCR响应，未命中时返回nil
+func (p *Processor) loadCachedResponse(cacheDir, key string) (*OCRResponse, error) {
+	jsonData, err := p.openFiles.readFile(cachePath(cacheDir, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取缓存文件失败: %w", err)
+	}
+
+	// 缓存内容与ConvertJSONToMarkdown使用相同的解析逻辑
+	return p.parseOCRJSON(jsonData, cacheDir)
+}
+
+// saveCachedResponseFile 将已保存在磁盘上的原始OCR响应复制到缓存目录
+func (p *Processor) saveCachedResponseFile(cacheDir, key, responsePath string, opts ProcessOptions) {
+	if err := opts.mkdirAll(cacheDir); err != nil {
+		p.logger.Warn("创建缓存目录失败", zap.String("cacheDir", cacheDir), zap.Error(err))
+		return
+	}
+
+	path := cachePath(cacheDir, key)
+	if err := opts.copyFile(responsePath, path); err != nil {
+		p.logger.Warn("写入缓存文件失败", zap.String("path", path), zap.Error(err))
+		return
+	}
+	p.logger.Debug("保存了OCR响应缓存", zap.String("path", path))
+}
+
+// saveCachedResponse 将原始OCR响应写入缓存目录
+func (p *Processor) saveCachedResponse(cacheDir, key string, rawResponse []byte, opts ProcessOptions) {
+	if err := opts.mkdirAll(cacheDir); err != nil {
+		p.logger.Warn("创建缓存目录失败", zap.String("cacheDir", cacheDir), zap.Error(err))
+		return
+	}
+
+	path := cachePath(cacheDir, key)
+	if err := opts.writeFile(path, rawResponse); err != nil {
+		p.logger.Warn("写入缓存文件失败", zap.String("path", path), zap.Error(err))
+		return
+	}
+	p.logger.Debug("保存了OCR响应缓存", zap.String("path", path))
+}
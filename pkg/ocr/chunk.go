@@ -0,0 +1,177 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// PageRangeOCRBackend 表示支持只处理文档部分页面的OCR后端
+//
+// 设置 ChunkPages 时，Processor 在后端实现了该接口的情况下分批请求页面，
+// 否则退回到一次处理整个文档。
+type PageRangeOCRBackend interface {
+	ProcessOCRPages(ctx context.Context, documentURL string, documentType string, includeImageBase64 bool, apiKey string, pages []int) (*OCRResponse, error)
+}
+
+// 确保 *Client 实现了 PageRangeOCRBackend 接口
+var _ PageRangeOCRBackend = (*Client)(nil)
+
+// ProcessOCRPages 使用OCR处理文档中的指定页面，pages为从0开始的页面索引，为空时处理整个文档
+//
+// 重试和切换端点的规则与 ProcessOCRWithType 相同。
+func (c *Client) ProcessOCRPages(ctx context.Context, documentURL string, documentType string, includeImageBase64 bool, apiKey string, pages []int) (*OCRResponse, error) {
+	return c.processOCR(ctx, documentURL, documentType, includeImageBase64, apiKey, pages, nil, nil)
+}
+
+// validateChunkPages 校验 ChunkPages 和 MaxPages 的取值，按页面范围请求时不支持流式解析
+func (o ProcessOptions) validateChunkPages() error {
+	if o.ChunkPages < 0 {
+		return fmt.Errorf("无效的分批页数 %d，不能为负数", o.ChunkPages)
+	}
+	if o.MaxPages < 0 {
+		return fmt.Errorf("无效的最大页数 %d，不能为负数", o.MaxPages)
+	}
+	if o.ChunkPages > 0 && o.StreamLargeResponses {
+		return fmt.Errorf("分批处理页面不能与流式解析同时使用")
+	}
+	if o.MaxPages > 0 && o.StreamLargeResponses {
+		return fmt.Errorf("限制最大页数不能与流式解析同时使用")
+	}
+	return nil
+}
+
+// countPDFPages 在设置了 ChunkPages 或 MaxPages 时读取PDF的页数，不是PDF或无法读取时返回0
+//
+// 页数取自页面树根节点的 /Count，加密的PDF使用为该文件设置的密码打开。页数未知时调用方不分批也不限制页数。
+func (p *Processor) countPDFPages(data []byte, name string, opts ProcessOptions) int {
+	if !opts.needsPageCount() || !isPDFData(data) {
+		return 0
+	}
+	pages, err := pdfPageCount(data, opts.pdfPasswordFor(name))
+	if err != nil {
+		p.logger.Warn("无法读取PDF页数", zap.String("name", name), zap.Error(err))
+		return 0
+	}
+	p.logger.Debug("读取PDF页数", zap.String("name", name), zap.Int("pages", pages))
+	return pages
+}
+
+// countPDFFilePages 在设置了 ChunkPages 或 MaxPages 时读取本地PDF文件的页数，读取失败时返回0
+func (p *Processor) countPDFFilePages(filePath string, opts ProcessOptions) int {
+	if !opts.needsPageCount() {
+		return 0
+	}
+	data, err := p.openFiles.readFile(filePath)
+	if err != nil {
+		p.logger.Warn("读取文件失败，无法读取页数", zap.String("filePath", filePath), zap.Error(err))
+		return 0
+	}
+	return p.countPDFPages(data, filePath, opts)
+}
+
+// needsPageCount 判断是否需要读取PDF的页数
+func (o ProcessOptions) needsPageCount() bool {
+	return o.ChunkPages > 0 || o.MaxPages > 0
+}
+
+// pageChunks 将 [0, totalPages) 按每批size页划分为页面索引列表
+func pageChunks(totalPages int, size int) [][]int {
+	var chunks [][]int
+	for start := 0; start < totalPages; start += size {
+		chunks = append(chunks, pageRange(start, min(start+size, totalPages)))
+	}
+	return chunks
+}
+
+// pageRange 返回 [start, end) 的页面索引列表
+func pageRange(start, end int) []int {
+	pages := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		pages = append(pages, i)
+	}
+	return pages
+}
+
+// chunkedOCR 按 ChunkPages 分批请求页面，并按顺序合并为一个响应
+func (p *Processor) chunkedOCR(ctx context.Context, backend PageRangeOCRBackend, documentURL string, documentType string, apiKey string, totalPages int, opts ProcessOptions) (*OCRResponse, error) {
+	chunks := pageChunks(totalPages, opts.ChunkPages)
+	responses := make([]*OCRResponse, 0, len(chunks))
+	for i, chunk := range chunks {
+		p.logger.Info("分批OCR处理",
+			zap.Int("chunk", i+1),
+			zap.Int("chunks", len(chunks)),
+			zap.Int("firstPage", chunk[0]+1),
+			zap.Int("lastPage", chunk[len(chunk)-1]+1))
+		resp, err := backend.ProcessOCRPages(ctx, documentURL, documentType, opts.savesImages(), apiKey, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("处理第 %d-%d 页失败: %w", chunk[0]+1, chunk[len(chunk)-1]+1, err)
+		}
+		responses = append(responses, resp)
+	}
+	return mergeChunkResponses(chunks, responses)
+}
+
+// mergeChunkResponses 按顺序合并分批处理的响应
+//
+// 每批的页面按请求的页面索引重新编号；与之前批次重复的图片ID加上页面索引前缀，
+// 并同步修改该页markdown中的图片引用，避免合并后的图片相互覆盖。
+func mergeChunkResponses(chunks [][]int, responses []*OCRResponse) (*OCRResponse, error) {
+	merged := &OCRResponse{}
+	usedIDs := make(map[string]bool)
+	for i, resp := range responses {
+		if merged.Model == "" {
+			merged.Model = resp.Model
+		}
+		if merged.UsageInfo.DocSizeBytes == nil {
+			merged.UsageInfo.DocSizeBytes = resp.UsageInfo.DocSizeBytes
+		}
+		merged.UsageInfo.PagesProcessed += resp.UsageInfo.PagesProcessed
+		if merged.DocumentAnnotation == nil {
+			merged.DocumentAnnotation = resp.DocumentAnnotation
+		}
+
+		for j, page := range resp.Pages {
+			if j < len(chunks[i]) {
+				page.Index = chunks[i][j]
+			}
+			page.Images = append([]Image(nil), page.Images...)
+			for k, img := range page.Images {
+				if !usedIDs[img.ID] {
+					usedIDs[img.ID] = true
+					continue
+				}
+				newID := uniqueChunkImageID(img.ID, page.Index, usedIDs)
+				page.Markdown = renameImageReference(page.Markdown, img.ID, newID)
+				page.Images[k].ID = newID
+				usedIDs[newID] = true
+			}
+			merged.Pages = append(merged.Pages, page)
+		}
+	}
+
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("合并分批响应失败: %w", err)
+	}
+	merged.RawResponse = raw
+	return merged, nil
+}
+
+// uniqueChunkImageID 为重复的图片ID加上页面索引前缀（如第51页为 p50-img-0.jpeg），仍重复时再加序号
+func uniqueChunkImageID(id string, pageIndex int, usedIDs map[string]bool) string {
+	newID := fmt.Sprintf("p%d-%s", pageIndex, id)
+	for n := 2; usedIDs[newID]; n++ {
+		newID = fmt.Sprintf("p%d-%d-%s", pageIndex, n, id)
+	}
+	return newID
+}
+
+// renameImageReference 将markdown中图片的替代文本和链接目标从oldID改为newID
+func renameImageReference(markdown, oldID, newID string) string {
+	markdown = strings.ReplaceAll(markdown, "!["+oldID+"]", "!["+newID+"]")
+	return strings.ReplaceAll(markdown, "]("+oldID+")", "]("+newID+")")
+}
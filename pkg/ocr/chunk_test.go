@@ -0,0 +1,153 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPDFPageCount(t *testing.T) {
+	encrypted := encryptTestPDF(t, buildTestPDF(3), testEncryption(true, 256))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		password string
+		want     int
+		wantErr  error
+	}{
+		{name: "未加密", data: buildTestPDF(3), want: 3},
+		// 内容中提到页面类型声明不影响页数
+		{name: "内容中出现页面类型", data: buildTestPDFWithTexts([]string{"/Type /Page /Type /Page"}), want: 1},
+		{name: "加密并提供用户密码", data: encrypted, password: "user", want: 3},
+		{name: "加密并提供所有者密码", data: encrypted, password: "owner", want: 3},
+		{name: "加密但密码错误", data: encrypted, password: "wrong", wantErr: ErrPDFPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pdfPageCount(tt.data, tt.password)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("pdfPageCount 返回 %v，期望 %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pdfPageCount 返回错误: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("pdfPageCount = %d，期望 %d", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := pdfPageCount([]byte("%PDF-1.4\n%%EOF\n"), ""); err == nil {
+		t.Error("没有页面树的PDF没有返回错误")
+	}
+}
+
+// TestChunkPagesUsesPageTreeCount 按 /Count 分批请求页面，加密的源文件使用PDF密码读取页数，页数未知时不分批
+func TestChunkPagesUsesPageTreeCount(t *testing.T) {
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "plain.pdf")
+	if err := os.WriteFile(plainPath, buildTestPDF(5), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	encryptedPath := filepath.Join(dir, "encrypted.pdf")
+	encrypted := encryptTestPDF(t, buildTestPDF(5), testEncryption(true, 128))
+	if err := os.WriteFile(encryptedPath, encrypted, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestPDFs(t, dir, "unknown.pdf")
+
+	tests := []struct {
+		name         string
+		path         string
+		password     string
+		wantRequests [][]int
+		wantCalls    int
+	}{
+		{name: "未加密", path: plainPath, wantRequests: [][]int{{0, 1}, {2, 3}, {4}}, wantCalls: 3},
+		{name: "加密", path: encryptedPath, password: "user", wantRequests: [][]int{{0, 1}, {2, 3}, {4}}, wantCalls: 3},
+		{name: "页数未知", path: filepath.Join(dir, "unknown.pdf"), wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{pages: 5}
+			result, err := newTestProcessor(backend).ProcessFile(context.Background(), tt.path, ProcessOptions{
+				OutputDir:   filepath.Join(t.TempDir(), "out"),
+				ChunkPages:  2,
+				PDFPassword: tt.password,
+				NoImages:    true,
+			})
+			if err != nil {
+				t.Fatalf("ProcessFile 返回错误: %v", err)
+			}
+			if !reflect.DeepEqual(backend.pageRequests, tt.wantRequests) {
+				t.Errorf("请求的页面 = %v，期望 %v", backend.pageRequests, tt.wantRequests)
+			}
+			if backend.ocrCalls != tt.wantCalls {
+				t.Errorf("OCR请求 %d 次，期望 %d 次", backend.ocrCalls, tt.wantCalls)
+			}
+			if result.Pages != 5 {
+				t.Errorf("处理了 %d 页，期望 5 页", result.Pages)
+			}
+		})
+	}
+}
+
+// TestMaxPages 页数已知时只请求前 MaxPages 页；页数未知时不请求可能不存在的页面，处理整个文档
+func TestMaxPages(t *testing.T) {
+	dir := t.TempDir()
+	knownPath := filepath.Join(dir, "known.pdf")
+	if err := os.WriteFile(knownPath, buildTestPDF(4), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestPDFs(t, dir, "unknown.pdf")
+
+	tests := []struct {
+		name         string
+		path         string
+		pages        int
+		maxPages     int
+		wantRequests [][]int
+		wantPages    int
+		wantMaxPages int
+	}{
+		{name: "页数超过限制", path: knownPath, pages: 4, maxPages: 2, wantRequests: [][]int{{0, 1}}, wantPages: 2, wantMaxPages: 2},
+		{name: "页数未超过限制", path: knownPath, pages: 4, maxPages: 10, wantPages: 4, wantMaxPages: 10},
+		{name: "页数未知", path: filepath.Join(dir, "unknown.pdf"), pages: 2, maxPages: 5, wantPages: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{pages: tt.pages}
+			result, err := newTestProcessor(backend).ProcessFile(context.Background(), tt.path, ProcessOptions{
+				OutputDir: filepath.Join(t.TempDir(), "out"),
+				MaxPages:  tt.maxPages,
+				NoImages:  true,
+			})
+			if err != nil {
+				t.Fatalf("ProcessFile 返回错误: %v", err)
+			}
+			if !reflect.DeepEqual(backend.pageRequests, tt.wantRequests) {
+				t.Errorf("请求的页面 = %v，期望 %v", backend.pageRequests, tt.wantRequests)
+			}
+			if result.Pages != tt.wantPages {
+				t.Errorf("处理了 %d 页，期望 %d 页", result.Pages, tt.wantPages)
+			}
+			metadata, _, err := LoadMetadata(result.OutputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata.MaxPages != tt.wantMaxPages {
+				t.Errorf("元数据记录的最大页数 = %d，期望 %d", metadata.MaxPages, tt.wantMaxPages)
+			}
+		})
+	}
+}
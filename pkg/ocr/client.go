@@ -2,6 +2,7 @@ package ocr
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,34 +13,85 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 // 全局随机数生成器
 var rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
 
+// 默认签名URL有效期（小时）
+const defaultSignedURLExpiryHours = 24
+
+// 默认重试退避时间：第n次重试等待 base*2^(n-1)，最长不超过 max
+const (
+	defaultBackoffBase = time.Second
+	defaultBackoffMax  = 30 * time.Second
+)
+
+// DefaultMaxUploadSizeMB 默认的上传文件大小上限（MB），与Mistral官方API的限制一致
+const DefaultMaxUploadSizeMB = 50
+
+// EndpointPaths 表示各API接口相对于基础URL的路径
+type EndpointPaths struct {
+	Files     string // 上传文件，默认 "files"
+	SignedURL string // 获取签名URL，{id} 会被替换为文件ID，默认 "files/{id}/url"
+	OCR       string // OCR处理，默认 "ocr"
+	Models    string // 模型列表，默认 "models"
+}
+
+// DefaultEndpointPaths 返回Mistral API的默认接口路径
+func DefaultEndpointPaths() EndpointPaths {
+	return EndpointPaths{
+		Files:     "files",
+		SignedURL: "files/{id}/url",
+		OCR:       "ocr",
+		Models:    "models",
+	}
+}
+
+// OCR请求的文档类型
+const (
+	DocumentTypeDocument = "document_url" // PDF等文档
+	DocumentTypeImage    = "image_url"    // 图片
+)
+
 // Client 表示Mistral OCR API客户端
 type Client struct {
 	apiKeys                []string
 	baseURLs               []string
 	httpTimeout            time.Duration
+	ocrTimeout             time.Duration
 	maxRetries             int
+	backoffBase            time.Duration
+	backoffMax             time.Duration
 	currentKeyIndex        int
 	currentURLIndex        int
 	retryDifferentEndpoint bool
+	signedURLExpiryHours   int
+	maxUploadSizeMB        float64
+	probeConcurrency       int
+	endpointHealth         map[string]EndpointHealth
+	paths                  EndpointPaths
+	adaptiveTimeout        *adaptiveTimeout  // 按文件大小计算的超时时间，为nil时使用固定超时时间
+	transport              *http.Transport   // 客户端专用的Transport，所有请求共用其连接池，CA证书和TLS校验的设置也作用于它
+	queryParams            map[string]string // 附加到每个API请求URL的查询参数
+	uploadURL              string            // 上传文件请求的完整URL，为空时使用基础URL和接口路径
+	signedURLEndpoint      string            // 获取签名URL请求的完整URL，{id} 会被替换为文件ID
+	ocrURL                 string            // OCR请求的完整URL
+	metrics                MetricsCollector  // 接收API请求指标的收集器，为nil时不收集
+	logger                 *zap.Logger       // 记录请求过程的日志记录器，为nil时输出到标准输出
 	mu                     sync.Mutex
 }
 
 // NewClient 创建一个新的Mistral OCR客户端
 func NewClient(apiKeys []string, baseURLs []string) *Client {
 	// 确保每个URL都以"/"结尾
-	for i, baseURL := range baseURLs {
-		if baseURL != "" && baseURL[len(baseURL)-1] != '/' {
-			baseURLs[i] = baseURL + "/"
-		}
-	}
+	baseURLs = normalizeBaseURLs(baseURLs)
 
 	// 随机选择初始的 API 密钥和 URL 索引
 	var keyIndex, urlIndex int
@@ -55,10 +107,73 @@ func NewClient(apiKeys []string, baseURLs []string) *Client {
 		baseURLs:               baseURLs,
 		httpTimeout:            5 * time.Minute, // 默认5分钟超时
 		maxRetries:             3,               // 默认最多重试3次
+		backoffBase:            defaultBackoffBase,
+		backoffMax:             defaultBackoffMax,
 		currentKeyIndex:        keyIndex,
 		currentURLIndex:        urlIndex,
 		retryDifferentEndpoint: true, // 默认启用不同端点重试
+		signedURLExpiryHours:   defaultSignedURLExpiryHours,
+		maxUploadSizeMB:        DefaultMaxUploadSizeMB,
+		paths:                  DefaultEndpointPaths(),
+		transport:              newTransport(),
+	}
+}
+
+// normalizeBaseURLs 返回确保每个URL都以"/"结尾的副本
+func normalizeBaseURLs(baseURLs []string) []string {
+	normalized := make([]string, len(baseURLs))
+	for i, baseURL := range baseURLs {
+		if baseURL != "" && !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		normalized[i] = baseURL
 	}
+	return normalized
+}
+
+// UpdateKeys 替换使用的API密钥，可在客户端使用过程中调用（如配置热加载时），
+// 之后的请求使用新的密钥，从随机位置开始轮询
+func (c *Client) UpdateKeys(apiKeys []string) error {
+	if len(apiKeys) == 0 {
+		return fmt.Errorf("至少需要一个API密钥")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKeys = append([]string(nil), apiKeys...)
+	c.currentKeyIndex = rnd.Intn(len(c.apiKeys))
+	return nil
+}
+
+// UpdateEndpoints 替换使用的API基础URL，可在客户端使用过程中调用（如配置热加载时），
+// 之后的请求使用新的端点，从随机位置开始轮询，已移除端点的探测结果会被清除
+func (c *Client) UpdateEndpoints(baseURLs []string) error {
+	if len(baseURLs) == 0 {
+		return fmt.Errorf("至少需要一个API基础URL")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.baseURLs = normalizeBaseURLs(baseURLs)
+	c.currentURLIndex = rnd.Intn(len(c.baseURLs))
+
+	current := make(map[string]bool, len(c.baseURLs))
+	for _, baseURL := range c.baseURLs {
+		current[baseURL] = true
+	}
+	for baseURL := range c.endpointHealth {
+		if !current[baseURL] {
+			delete(c.endpointHealth, baseURL)
+		}
+	}
+	return nil
+}
+
+// endpointCount 返回配置的端点数量
+func (c *Client) endpointCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.baseURLs)
 }
 
 // SetRetryDifferentEndpoint 设置是否在 API 调用失败时尝试使用不同的端点
@@ -66,8 +181,11 @@ func (c *Client) SetRetryDifferentEndpoint(retry bool) {
 	c.retryDifferentEndpoint = retry
 }
 
-// getNextAPIKey 获取下一个要使用的API密钥
-func (c *Client) getNextAPIKey() string {
+// NextAPIKey 按轮询顺序获取下一个要使用的API密钥，可以在多个goroutine中并发调用
+//
+// 起始位置在创建客户端时随机选择，之后每次调用推进一个密钥。Processor 每个文件只调用一次，
+// 认证失败时换用密钥不推进轮询位置。
+func (c *Client) NextAPIKey() string {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -106,18 +224,146 @@ func (c *Client) getCurrentBaseURL() string {
 	return c.baseURLs[c.currentURLIndex]
 }
 
-// SetTimeout 设置HTTP客户端超时时间
+// SetTimeout 设置HTTP客户端超时时间，用于上传、获取签名URL等请求
 func (c *Client) SetTimeout(timeout time.Duration) {
 	c.httpTimeout = timeout
 }
 
+// SetOCRTimeout 设置OCR请求的超时时间，大文档的OCR处理可能远慢于上传和获取签名URL，
+// 非正数表示使用按文件大小计算的超时时间（见 SetAdaptiveTimeout），未启用时与 SetTimeout 设置的超时时间相同
+func (c *Client) SetOCRTimeout(timeout time.Duration) {
+	c.ocrTimeout = timeout
+}
+
 // SetMaxRetries 设置最大重试次数
 func (c *Client) SetMaxRetries(retries int) {
 	c.maxRetries = retries
 }
 
+// SetBackoff 设置重试的退避时间，第n次重试等待 base*2^(n-1)，最长不超过max
+//
+// 非正数的base使用默认值1秒，非正数的max表示不限制。
+func (c *Client) SetBackoff(base, max time.Duration) {
+	if base <= 0 {
+		base = defaultBackoffBase
+	}
+	c.backoffBase = base
+	c.backoffMax = max
+}
+
+// backoffDuration 返回第attempt次重试前的等待时间
+func (c *Client) backoffDuration(attempt int) time.Duration {
+	// 先用浮点数比较，避免重试次数很大时转换为Duration溢出
+	backoff := math.Pow(2, float64(attempt-1)) * float64(c.backoffBase)
+	if c.backoffMax > 0 && backoff > float64(c.backoffMax) {
+		return c.backoffMax
+	}
+	if backoff > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(backoff)
+}
+
+// SetEndpointPaths 设置各API接口的路径，未设置的字段保持默认值
+func (c *Client) SetEndpointPaths(paths EndpointPaths) {
+	if paths.Files != "" {
+		c.paths.Files = strings.TrimPrefix(paths.Files, "/")
+	}
+	if paths.SignedURL != "" {
+		c.paths.SignedURL = strings.TrimPrefix(paths.SignedURL, "/")
+	}
+	if paths.OCR != "" {
+		c.paths.OCR = strings.TrimPrefix(paths.OCR, "/")
+	}
+	if paths.Models != "" {
+		c.paths.Models = strings.TrimPrefix(paths.Models, "/")
+	}
+}
+
+// SetQueryParams 设置附加到每个API请求URL的查询参数，用于要求API版本、区域等参数的网关，传入空map时清除
+//
+// 参数与URL中已有的参数合并：同名时覆盖接口路径中的参数，但不覆盖请求本身的参数（如获取签名URL时的expiry）。
+func (c *Client) SetQueryParams(params map[string]string) {
+	c.queryParams = make(map[string]string, len(params))
+	for k, v := range params {
+		c.queryParams[k] = v
+	}
+}
+
+// apiURL 拼接基础URL和接口路径，并合并 SetQueryParams 设置的查询参数和请求本身的参数（后者优先）
+//
+// 参数经过URL编码；无法解析时原样返回拼接结果，由创建请求时报告错误。
+func (c *Client) apiURL(baseURL, path string, params url.Values) string {
+	requestURL := baseURL + path
+	if len(c.queryParams) == 0 && len(params) == 0 {
+		return requestURL
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return requestURL
+	}
+	query := u.Query()
+	for k, v := range c.queryParams {
+		query.Set(k, v)
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// SetMaxUploadSizeMB 设置上传文件的大小上限（MB），用于允许更大文件的自托管网关，非正数时使用默认值50MB
+func (c *Client) SetMaxUploadSizeMB(sizeMB float64) {
+	if sizeMB <= 0 {
+		sizeMB = DefaultMaxUploadSizeMB
+	}
+	c.maxUploadSizeMB = sizeMB
+}
+
+// checkUploadSize 检查上传内容的大小是否超过配置的上限
+func (c *Client) checkUploadSize(size int64) error {
+	sizeMB := float64(size) / 1024 / 1024
+	if sizeMB > c.maxUploadSizeMB {
+		return fmt.Errorf("文件大小超过限制: %.2f MB > %.2f MB", sizeMB, c.maxUploadSizeMB)
+	}
+	return nil
+}
+
+// SetSignedURLExpiry 设置签名URL的有效期（小时），非正数时返回错误，不修改原有设置
+func (c *Client) SetSignedURLExpiry(hours int) error {
+	if hours <= 0 {
+		return fmt.Errorf("签名URL有效期必须为正数: %d", hours)
+	}
+	c.signedURLExpiryHours = hours
+	return nil
+}
+
+// displayURL 返回用于日志输出的URL，内联的data URL只保留类型部分
+func displayURL(u string) string {
+	if strings.HasPrefix(u, "data:") {
+		if i := strings.Index(u, ","); i >= 0 {
+			return u[:i] + ",..."
+		}
+	}
+	return u
+}
+
+// sleepContext 等待指定时间，上下文取消时提前返回错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // UploadPDF 上传PDF文件到Mistral API
-func (c *Client) UploadPDF(filePath string) (string, string, error) {
+func (c *Client) UploadPDF(ctx context.Context, filePath string) (string, string, error) {
 	// 获取文件信息
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
@@ -126,11 +372,11 @@ func (c *Client) UploadPDF(filePath string) (string, string, error) {
 
 	// 记录文件大小
 	fileSizeMB := float64(fileInfo.Size()) / 1024 / 1024
-	fmt.Printf("开始上传文件: %s, 大小: %.2f MB\n", filePath, fileSizeMB)
+	c.printf("开始上传文件: %s, 大小: %.2f MB\n", filePath, fileSizeMB)
 
-	// 检查文件大小是否超过限制（50MB）
-	if fileSizeMB > 50 {
-		return "", "", fmt.Errorf("文件大小超过限制: %.2f MB > 50 MB", fileSizeMB)
+	// 检查文件大小是否超过限制
+	if err := c.checkUploadSize(fileInfo.Size()); err != nil {
+		return "", "", err
 	}
 
 	file, err := os.Open(filePath)
@@ -139,146 +385,194 @@ func (c *Client) UploadPDF(filePath string) (string, string, error) {
 	}
 	defer file.Close()
 
+	return c.UploadReader(ctx, file, filepath.Base(filePath))
+}
+
+// UploadReader 上传reader中的文档内容，filename为上传时使用的文件名，
+// 适用于内容已在内存中、无需先写入临时文件的情况。重试时会将reader重新定位到开头。
+//
+// 使用上下文中固定的API密钥（见 WithAPIKey），没有固定时从轮询中取一个密钥，所有重试都使用该密钥，
+// 只在认证失败时换用其他密钥。返回的密钥是上传成功时使用的密钥。
+func (c *Client) UploadReader(ctx context.Context, file io.ReadSeeker, filename string) (string, string, error) {
+	// 检查内容大小是否超过限制
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", "", fmt.Errorf("获取内容大小失败: %w", err)
+	}
+	if err := c.checkUploadSize(size); err != nil {
+		return "", "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", "", fmt.Errorf("定位内容开头失败: %w", err)
+	}
+
+	// 按内容大小确定请求超时时间
+	timeout := c.uploadTimeout(size)
+
 	var resp *http.Response
 	var lastErr error
 	var bodyBytes []byte
+	apiKey := c.fileAPIKey(ctx)
 	var usedAPIKey string
 
 	// 记录已尝试过的端点
 	triedEndpoints := make(map[string]bool)
+	previousEndpoint := ""
+
+	// 记录实际发送请求的次数和成功的端点，无论最终成功与否都会记录
+	attempts := 0
+	succeededEndpoint := ""
+	defer func() {
+		recordAttempts(ctx, attemptOpUpload, attempts, len(triedEndpoints), succeededEndpoint)
+	}()
 
 	// 外层循环：尝试不同的端点
-	for endpointAttempt := 0; endpointAttempt < len(c.baseURLs); endpointAttempt++ {
-		// 获取当前端点
+	for endpointAttempt := 0; endpointAttempt < c.endpointCountFor(c.uploadURL); endpointAttempt++ {
+		// 获取当前端点，设置了完整URL时直接使用该URL
 		baseURL := c.getCurrentBaseURL()
+		if c.uploadURL != "" {
+			baseURL = c.uploadURL
+		}
 		if triedEndpoints[baseURL] {
 			// 如果已经尝试过这个端点，获取下一个
 			baseURL = c.getNextBaseURL()
 			if triedEndpoints[baseURL] {
 				// 如果所有端点都已尝试过，退出
-				if len(triedEndpoints) >= len(c.baseURLs) {
+				if len(triedEndpoints) >= c.endpointCount() {
 					break
 				}
 				continue
 			}
 		}
 		triedEndpoints[baseURL] = true
+		c.observeFailover(attemptOpUpload, previousEndpoint, baseURL)
+		previousEndpoint = baseURL
 
-		fmt.Printf("尝试使用端点: %s\n", baseURL)
+		c.printf("尝试使用端点: %s\n", baseURL)
+
+		// 记录在当前端点上认证失败的API密钥，换用其他密钥前不放弃该端点
+		triedKeys := make(map[string]bool)
+		rotateKey := false
+		usedAPIKey = apiKey
 
 		// 内层循环：在当前端点上进行重试
 		for attempt := 0; attempt <= c.maxRetries; attempt++ {
-			if attempt > 0 {
+			if attempt > 0 && !rotateKey {
 				// 指数退避策略，每次重试等待时间增加
-				backoffTime := time.Duration(math.Pow(2, float64(attempt-1))) * time.Second
-				fmt.Printf("第 %d 次重试，等待 %v 后重试...\n", attempt, backoffTime)
-				time.Sleep(backoffTime)
-
-				// 重新打开文件，因为前一次尝试可能已经读取了部分内容
-				file.Seek(0, 0)
-			}
-
-			body := &bytes.Buffer{}
-			writer := multipart.NewWriter(body)
-
-			// 添加表单字段 'purpose'
-			err = writer.WriteField("purpose", "ocr")
-			if err != nil {
-				lastErr = fmt.Errorf("写入表单字段错误: %w", err)
-				fmt.Printf("写入表单字段错误: %v\n", err)
-				continue
+				c.observeRetry(attemptOpUpload, baseURL)
+				backoffTime := c.backoffDuration(attempt)
+				c.printf("第 %d 次重试，等待 %v 后重试...\n", attempt, backoffTime)
+				if err := sleepContext(ctx, backoffTime); err != nil {
+					return "", "", err
+				}
 			}
+			rotateKey = false
 
-			// 添加文件
-			part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+			// 每次尝试都从头构建完整的请求体，前一次尝试（包括构建表单时出错）可能只读取了部分内容
+			c.printf("开始复制文件内容...\n")
+			body, contentType, err := buildUploadBody(file, filename)
 			if err != nil {
-				lastErr = fmt.Errorf("创建表单文件错误: %w", err)
-				fmt.Printf("创建表单文件错误: %v\n", err)
-				continue
-			}
-
-			fmt.Printf("开始复制文件内容...\n")
-			if _, err = io.Copy(part, file); err != nil {
-				lastErr = fmt.Errorf("复制文件内容错误: %w", err)
-				fmt.Printf("复制文件内容错误: %v\n", err)
-				continue
-			}
-
-			if err = writer.Close(); err != nil {
-				lastErr = fmt.Errorf("关闭表单写入器错误: %w", err)
-				fmt.Printf("关闭表单写入器错误: %v\n", err)
+				lastErr = err
+				c.printf("%v\n", err)
 				continue
 			}
 
-			// 获取当前使用的 API 密钥（打码处理）
-			usedAPIKey = c.getNextAPIKey()
+			// 当前使用的 API 密钥（打码处理）
 			maskedKey := "****"
 			if len(usedAPIKey) > 8 {
 				maskedKey = usedAPIKey[:4] + strings.Repeat("*", len(usedAPIKey)-8) + usedAPIKey[len(usedAPIKey)-4:]
 			}
 
-			fmt.Printf("创建请求: POST %sfiles, API密钥: %s\n", baseURL, maskedKey)
-			req, err := http.NewRequest(http.MethodPost, baseURL+"files", body)
+			requestURL := c.apiURL(baseURL, c.paths.Files, nil)
+			if c.uploadURL != "" {
+				requestURL = c.apiURL(c.uploadURL, "", nil)
+			}
+			c.printf("创建请求: POST %s, API密钥: %s\n", requestURL, maskedKey)
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, body)
 			if err != nil {
 				lastErr = fmt.Errorf("创建请求错误: %w", err)
-				fmt.Printf("创建请求错误: %v\n", err)
+				c.printf("创建请求错误: %v\n", err)
 				continue
 			}
 
-			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.Header.Set("Content-Type", contentType)
 			req.Header.Set("Authorization", "Bearer "+usedAPIKey)
 
 			// 创建带超时的HTTP客户端
-			client := &http.Client{
-				Timeout: c.httpTimeout,
-			}
+			client := c.httpClient(timeout)
 
-			fmt.Printf("发送请求中...\n")
+			c.printf("发送请求中...\n")
+			attempts++
+			start := time.Now()
 			resp, err = client.Do(req)
 			if err != nil {
+				c.observeRequest(attemptOpUpload, baseURL, 0, start)
+				// 上下文已取消时立即返回，不再重试
+				if ctx.Err() != nil {
+					return "", "", ctx.Err()
+				}
 				lastErr = fmt.Errorf("发送请求错误: %w", err)
-				fmt.Printf("发送请求错误: %v\n", err)
+				c.printf("发送请求错误: %v\n", err)
 				continue
 			}
 
 			// 读取响应体
-			fmt.Printf("收到响应，状态码: %d\n", resp.StatusCode)
+			c.printf("收到响应，状态码: %d\n", resp.StatusCode)
 			bodyBytes, err = io.ReadAll(resp.Body)
 			resp.Body.Close()
+			c.observeRequest(attemptOpUpload, baseURL, resp.StatusCode, start)
 
 			if err != nil {
 				lastErr = fmt.Errorf("读取响应体错误: %w", err)
-				fmt.Printf("读取响应体错误: %v\n", err)
+				c.printf("读取响应体错误: %v\n", err)
 				continue
 			}
 
 			// 检查状态码
 			if resp.StatusCode == http.StatusOK {
+				succeededEndpoint = baseURL
+				pinUploadAPIKey(ctx, usedAPIKey)
 				// 成功，跳出重试循环
 				var uploadResp UploadResponse
 				err = json.Unmarshal(bodyBytes, &uploadResp)
 				if err != nil {
-					fmt.Printf("解析响应错误: %v\n", err)
+					c.printf("解析响应错误: %v\n", err)
 					return "", "", fmt.Errorf("解析响应错误: %w", err)
 				}
-				fmt.Printf("上传成功，文件ID: %s\n", uploadResp.ID)
+				c.printf("上传成功，文件ID: %s\n", uploadResp.ID)
 				return uploadResp.ID, usedAPIKey, nil
 			} else if resp.StatusCode == http.StatusGatewayTimeout || resp.StatusCode == http.StatusServiceUnavailable {
 				// 服务器超时或不可用，继续重试
-				lastErr = fmt.Errorf("上传失败，状态码 %d: %s", resp.StatusCode, string(bodyBytes))
-				fmt.Printf("服务器错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				lastErr = &APIError{Operation: "上传", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+				c.printf("服务器错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
 				continue
 			} else if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-				// 认证错误，尝试下一个API密钥
-				lastErr = fmt.Errorf("上传失败，状态码 %d: %s", resp.StatusCode, string(bodyBytes))
-				fmt.Printf("认证错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				// 认证错误，先在当前端点换用其他API密钥，所有密钥都失败后再尝试下一个端点
+				lastErr = &APIError{Operation: "上传", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+				c.printf("认证错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				triedKeys[usedAPIKey] = true
+				if nextKey, ok := c.nextKeyAfter(usedAPIKey, triedKeys); ok {
+					c.printf("在当前端点换用下一个API密钥重试\n")
+					usedAPIKey = nextKey
+					rotateKey = true
+					attempt-- // 更换密钥不计入重试次数
+					continue
+				}
 				break // 跳出内层循环，尝试下一个端点
+			} else if resp.StatusCode == http.StatusRequestEntityTooLarge {
+				// 内容超过端点的大小限制，重试或更换端点也会被拒绝，直接返回
+				lastErr = &PayloadTooLargeError{
+					APIError: &APIError{Operation: "上传", StatusCode: resp.StatusCode, Body: string(bodyBytes)},
+					Size:     size,
+				}
+				c.printf("请求内容过大，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				return "", "", lastErr
 			} else {
 				// 其他错误，如果启用了不同端点重试，则尝试下一个端点
-				lastErr = fmt.Errorf("上传失败，状态码 %d: %s", resp.StatusCode, string(bodyBytes))
-				fmt.Printf("请求失败，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				lastErr = &APIError{Operation: "上传", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+				c.printf("请求失败，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
 				if c.retryDifferentEndpoint {
-					fmt.Printf("将尝试使用不同端点重试\n")
+					c.printf("将尝试使用不同端点重试\n")
 					break // 跳出内层循环，尝试下一个端点
 				} else {
 					return "", "", lastErr // 不尝试其他端点，直接返回错误
@@ -293,13 +587,49 @@ func (c *Client) UploadPDF(filePath string) (string, string, error) {
 	}
 
 	// 如果所有尝试都失败
-	fmt.Printf("所有尝试均失败，最后错误: %v\n", lastErr)
+	c.printf("所有尝试均失败，最后错误: %v\n", lastErr)
 	return "", "", lastErr
 }
 
-// GetSignedURL 获取上传文件的签名URL
-func (c *Client) GetSignedURL(fileID string, apiKey string) (string, error) {
-	fmt.Printf("获取文件签名URL，文件ID: %s\n", fileID)
+// buildUploadBody 构建上传文件的multipart请求体，返回请求体和对应的Content-Type
+//
+// 构建前总是将reader定位到开头，保证无论前一次尝试在哪一步失败，请求体中都是完整的文件内容。
+func buildUploadBody(file io.ReadSeeker, filename string) (*bytes.Buffer, string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, "", fmt.Errorf("定位内容开头失败: %w", err)
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	// 添加表单字段 'purpose'
+	if err := writer.WriteField("purpose", "ocr"); err != nil {
+		return nil, "", fmt.Errorf("写入表单字段错误: %w", err)
+	}
+
+	// 添加文件
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, "", fmt.Errorf("创建表单文件错误: %w", err)
+	}
+
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, "", fmt.Errorf("复制文件内容错误: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("关闭表单写入器错误: %w", err)
+	}
+
+	return body, writer.FormDataContentType(), nil
+}
+
+// GetSignedURL 获取上传文件的签名URL，上下文中固定了API密钥时使用固定的密钥（见 WithAPIKey）
+//
+// 文件是用同一个上下文上传的时，认证失败直接返回错误，不换用其他密钥。
+func (c *Client) GetSignedURL(ctx context.Context, fileID string, apiKey string) (string, error) {
+	c.printf("获取文件签名URL，文件ID: %s\n", fileID)
+	apiKey = pinnedAPIKey(ctx, apiKey)
 
 	var resp *http.Response
 	var lastErr error
@@ -307,34 +637,55 @@ func (c *Client) GetSignedURL(fileID string, apiKey string) (string, error) {
 
 	// 记录已尝试过的端点
 	triedEndpoints := make(map[string]bool)
+	previousEndpoint := ""
+
+	// 记录实际发送请求的次数和成功的端点，无论最终成功与否都会记录
+	attempts := 0
+	succeededEndpoint := ""
+	defer func() {
+		recordAttempts(ctx, attemptOpSignedURL, attempts, len(triedEndpoints), succeededEndpoint)
+	}()
 
 	// 外层循环：尝试不同的端点
-	for endpointAttempt := 0; endpointAttempt < len(c.baseURLs); endpointAttempt++ {
-		// 获取当前端点
+	for endpointAttempt := 0; endpointAttempt < c.endpointCountFor(c.signedURLEndpoint); endpointAttempt++ {
+		// 获取当前端点，设置了完整URL时直接使用该URL
 		baseURL := c.getCurrentBaseURL()
+		if c.signedURLEndpoint != "" {
+			baseURL = c.signedURLEndpoint
+		}
 		if triedEndpoints[baseURL] {
 			// 如果已经尝试过这个端点，获取下一个
 			baseURL = c.getNextBaseURL()
 			if triedEndpoints[baseURL] {
 				// 如果所有端点都已尝试过，退出
-				if len(triedEndpoints) >= len(c.baseURLs) {
+				if len(triedEndpoints) >= c.endpointCount() {
 					break
 				}
 				continue
 			}
 		}
 		triedEndpoints[baseURL] = true
+		c.observeFailover(attemptOpSignedURL, previousEndpoint, baseURL)
+		previousEndpoint = baseURL
 
-		fmt.Printf("尝试使用端点: %s\n", baseURL)
+		c.printf("尝试使用端点: %s\n", baseURL)
+
+		// 记录在当前端点上认证失败的API密钥，换用其他密钥前不放弃该端点
+		triedKeys := make(map[string]bool)
+		rotateKey := false
 
 		// 内层循环：在当前端点上进行重试
 		for attempt := 0; attempt <= c.maxRetries; attempt++ {
-			if attempt > 0 {
+			if attempt > 0 && !rotateKey {
 				// 指数退避策略，每次重试等待时间增加
-				backoffTime := time.Duration(math.Pow(2, float64(attempt-1))) * time.Second
-				fmt.Printf("第 %d 次重试，等待 %v 后重试...\n", attempt, backoffTime)
-				time.Sleep(backoffTime)
+				c.observeRetry(attemptOpSignedURL, baseURL)
+				backoffTime := c.backoffDuration(attempt)
+				c.printf("第 %d 次重试，等待 %v 后重试...\n", attempt, backoffTime)
+				if err := sleepContext(ctx, backoffTime); err != nil {
+					return "", err
+				}
 			}
+			rotateKey = false
 
 			// 使用传入的 API 密钥（打码处理）
 			maskedKey := "****"
@@ -342,13 +693,18 @@ func (c *Client) GetSignedURL(fileID string, apiKey string) (string, error) {
 				maskedKey = apiKey[:4] + strings.Repeat("*", len(apiKey)-8) + apiKey[len(apiKey)-4:]
 			}
 
-			requestURL := baseURL + "files/" + fileID + "/url?expiry=24"
-			fmt.Printf("创建请求: GET %s, API密钥: %s\n", requestURL, maskedKey)
+			expiry := url.Values{"expiry": {strconv.Itoa(c.signedURLExpiryHours)}}
+			signedURLPath := strings.ReplaceAll(c.paths.SignedURL, "{id}", url.PathEscape(fileID))
+			requestURL := c.apiURL(baseURL, signedURLPath, expiry)
+			if c.signedURLEndpoint != "" {
+				requestURL = c.apiURL(strings.ReplaceAll(c.signedURLEndpoint, "{id}", url.PathEscape(fileID)), "", expiry)
+			}
+			c.printf("创建请求: GET %s, API密钥: %s\n", requestURL, maskedKey)
 
-			req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 			if err != nil {
 				lastErr = fmt.Errorf("创建请求错误: %w", err)
-				fmt.Printf("创建请求错误: %v\n", err)
+				c.printf("创建请求错误: %v\n", err)
 				continue
 			}
 
@@ -356,56 +712,84 @@ func (c *Client) GetSignedURL(fileID string, apiKey string) (string, error) {
 			req.Header.Set("Accept", "application/json")
 
 			// 创建带超时的HTTP客户端
-			client := &http.Client{
-				Timeout: c.httpTimeout,
-			}
+			client := c.httpClient(c.httpTimeout)
 
-			fmt.Printf("发送请求中...\n")
+			c.printf("发送请求中...\n")
+			attempts++
+			start := time.Now()
 			resp, err = client.Do(req)
 			if err != nil {
+				c.observeRequest(attemptOpSignedURL, baseURL, 0, start)
+				// 上下文已取消时立即返回，不再重试
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
 				lastErr = fmt.Errorf("发送请求错误: %w", err)
-				fmt.Printf("发送请求错误: %v\n", err)
+				c.printf("发送请求错误: %v\n", err)
 				continue
 			}
 
 			// 读取响应体
-			fmt.Printf("收到响应，状态码: %d\n", resp.StatusCode)
+			c.printf("收到响应，状态码: %d\n", resp.StatusCode)
 			bodyBytes, err = io.ReadAll(resp.Body)
 			resp.Body.Close()
+			c.observeRequest(attemptOpSignedURL, baseURL, resp.StatusCode, start)
 
 			if err != nil {
 				lastErr = fmt.Errorf("读取响应体错误: %w", err)
-				fmt.Printf("读取响应体错误: %v\n", err)
+				c.printf("读取响应体错误: %v\n", err)
 				continue
 			}
 
 			// 检查状态码
 			if resp.StatusCode == http.StatusOK {
+				succeededEndpoint = baseURL
+				repinAPIKey(ctx, apiKey)
 				// 成功，解析响应
 				var signedURLResp SignedURLResponse
 				err := json.Unmarshal(bodyBytes, &signedURLResp)
 				if err != nil {
-					fmt.Printf("解析响应错误: %v\n", err)
+					c.printf("解析响应错误: %v\n", err)
 					return "", fmt.Errorf("解析响应错误: %w", err)
 				}
-				fmt.Printf("获取签名URL成功: %s\n", signedURLResp.URL)
+				c.printf("获取签名URL成功: %s\n", signedURLResp.URL)
 				return signedURLResp.URL, nil
 			} else if resp.StatusCode == http.StatusGatewayTimeout || resp.StatusCode == http.StatusServiceUnavailable {
 				// 服务器超时或不可用，继续重试
-				lastErr = fmt.Errorf("获取签名URL失败，状态码 %d: %s", resp.StatusCode, string(bodyBytes))
-				fmt.Printf("服务器错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				lastErr = &APIError{Operation: "获取签名URL", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+				c.printf("服务器错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
 				continue
 			} else if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-				// 认证错误，尝试下一个API密钥
-				lastErr = fmt.Errorf("获取签名URL失败，状态码 %d: %s", resp.StatusCode, string(bodyBytes))
-				fmt.Printf("认证错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				// 认证错误，先在当前端点换用其他API密钥，所有密钥都失败后再尝试下一个端点
+				lastErr = &APIError{Operation: "获取签名URL", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+				c.printf("认证错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				if uploadKeyPinned(ctx) {
+					// 文件只能用上传时的密钥访问，换用其他密钥或端点也无法成功
+					return "", fmt.Errorf("上传文件使用的API密钥认证失败: %w", lastErr)
+				}
+				triedKeys[apiKey] = true
+				if nextKey, ok := c.nextKeyAfter(apiKey, triedKeys); ok {
+					c.printf("在当前端点换用下一个API密钥重试\n")
+					apiKey = nextKey
+					rotateKey = true
+					attempt-- // 更换密钥不计入重试次数
+					continue
+				}
 				break // 跳出内层循环，尝试下一个端点
+			} else if resp.StatusCode == http.StatusRequestEntityTooLarge {
+				// 内容超过端点的大小限制，重试或更换端点也会被拒绝，直接返回
+				lastErr = &PayloadTooLargeError{
+					APIError: &APIError{Operation: "获取签名URL", StatusCode: resp.StatusCode, Body: string(bodyBytes)},
+					Size:     0,
+				}
+				c.printf("请求内容过大，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				return "", lastErr
 			} else {
 				// 其他错误，如果启用了不同端点重试，则尝试下一个端点
-				lastErr = fmt.Errorf("获取签名URL失败，状态码 %d: %s", resp.StatusCode, string(bodyBytes))
-				fmt.Printf("请求失败，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				lastErr = &APIError{Operation: "获取签名URL", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+				c.printf("请求失败，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
 				if c.retryDifferentEndpoint {
-					fmt.Printf("将尝试使用不同端点重试\n")
+					c.printf("将尝试使用不同端点重试\n")
 					break // 跳出内层循环，尝试下一个端点
 				} else {
 					return "", lastErr // 不尝试其他端点，直接返回错误
@@ -420,35 +804,118 @@ func (c *Client) GetSignedURL(fileID string, apiKey string) (string, error) {
 	}
 
 	// 如果所有尝试都失败
-	fmt.Printf("所有尝试均失败，最后错误: %v\n", lastErr)
+	c.printf("所有尝试均失败，最后错误: %v\n", lastErr)
 	return "", lastErr
 }
 
+// DetectContentType 通过HEAD请求获取URL指向内容的Content-Type
+func (c *Client) DetectContentType(ctx context.Context, targetURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, targetURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建请求错误: %w", err)
+	}
+
+	client := c.httpClient(c.httpTimeout)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("发送请求错误: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("HEAD请求失败，状态码 %d", resp.StatusCode)
+	}
+
+	return resp.Header.Get("Content-Type"), nil
+}
+
+// DownloadToFile 下载URL指向的内容并写入本地文件
+func (c *Client) DownloadToFile(ctx context.Context, sourceURL string, dstPath string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求错误: %w", err)
+	}
+
+	client := c.httpClient(c.httpTimeout)
+
+	c.printf("下载文件: %s\n", sourceURL)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求错误: %w", err)
+	}
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载失败，状态码 %d", resp.StatusCode)
+	}
+
+	file, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %w", err)
+	}
+	defer file.Close()
+
+	// 流式写入磁盘，避免将整个文件读入内存
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+
+	return nil
+}
+
 // ProcessOCR 使用OCR处理文档
-func (c *Client) ProcessOCR(documentURL string, includeImageBase64 bool, apiKey string) (*OCRResponse, error) {
-	fmt.Printf("开始OCR处理文档，URL: %s\n", documentURL)
+func (c *Client) ProcessOCR(ctx context.Context, documentURL string, includeImageBase64 bool, apiKey string) (*OCRResponse, error) {
+	return c.ProcessOCRWithType(ctx, documentURL, DocumentTypeDocument, includeImageBase64, apiKey)
+}
+
+// ProcessOCRWithType 使用OCR处理指定类型（document_url 或 image_url）的文档
+func (c *Client) ProcessOCRWithType(ctx context.Context, documentURL string, documentType string, includeImageBase64 bool, apiKey string) (*OCRResponse, error) {
+	return c.processOCR(ctx, documentURL, documentType, includeImageBase64, apiKey, nil, nil, nil)
+}
+
+// processOCR 发送OCR请求，onPage不为nil时流式解析成功的响应，见 ProcessOCRStream
+//
+// pages不为空时只处理其中的页面（从0开始），见 ProcessOCRPages。上下文中固定了API密钥时使用固定的密钥，见 WithAPIKey。
+func (c *Client) processOCR(ctx context.Context, documentURL string, documentType string, includeImageBase64 bool, apiKey string, pages []int, raw io.Writer, onPage func(Page) error) (*OCRResponse, error) {
+	c.printf("开始OCR处理文档，URL: %s, 类型: %s\n", displayURL(documentURL), documentType)
+	apiKey = pinnedAPIKey(ctx, apiKey)
 
 	// 检查是否为有效URL
 	_, err := url.ParseRequestURI(documentURL)
 	if err != nil {
-		fmt.Printf("无效的URL: %v\n", err)
+		c.printf("无效的URL: %v\n", err)
 		return nil, fmt.Errorf("无效的URL: %w", err)
 	}
 
-	requestBody, err := json.Marshal(map[string]interface{}{
+	body := map[string]interface{}{
 		"model": "mistral-ocr-latest",
 		"document": map[string]string{
-			"type":         "document_url",
-			"document_url": documentURL,
+			"type":       documentType,
+			documentType: documentURL,
 		},
 		"include_image_base64": includeImageBase64,
-	})
+	}
+	if len(pages) > 0 {
+		body["pages"] = pages
+	}
+	requestBody, err := json.Marshal(body)
 	if err != nil {
-		fmt.Printf("创建请求体错误: %v\n", err)
+		c.printf("创建请求体错误: %v\n", err)
 		return nil, fmt.Errorf("创建请求体错误: %w", err)
 	}
 
-	fmt.Printf("请求体: %s\n", string(requestBody))
+	// 内联文档的大小即请求体的大小，文档URL由API获取，大小未知
+	var documentSize int64
+	if strings.HasPrefix(documentURL, "data:") {
+		documentSize = int64(len(requestBody))
+		c.printf("请求体: 内联文档，共 %d 字节\n", len(requestBody))
+	} else {
+		c.printf("请求体: %s\n", string(requestBody))
+	}
+
+	// 确定OCR请求的超时时间
+	timeout := c.ocrRequestTimeout(ctx)
 
 	var resp *http.Response
 	var lastErr error
@@ -456,34 +923,55 @@ func (c *Client) ProcessOCR(documentURL string, includeImageBase64 bool, apiKey
 
 	// 记录已尝试过的端点
 	triedEndpoints := make(map[string]bool)
+	previousEndpoint := ""
+
+	// 记录实际发送请求的次数和成功的端点，无论最终成功与否都会记录
+	attempts := 0
+	succeededEndpoint := ""
+	defer func() {
+		recordAttempts(ctx, attemptOpOCR, attempts, len(triedEndpoints), succeededEndpoint)
+	}()
 
 	// 外层循环：尝试不同的端点
-	for endpointAttempt := 0; endpointAttempt < len(c.baseURLs); endpointAttempt++ {
-		// 获取当前端点
+	for endpointAttempt := 0; endpointAttempt < c.endpointCountFor(c.ocrURL); endpointAttempt++ {
+		// 获取当前端点，设置了完整URL时直接使用该URL
 		baseURL := c.getCurrentBaseURL()
+		if c.ocrURL != "" {
+			baseURL = c.ocrURL
+		}
 		if triedEndpoints[baseURL] {
 			// 如果已经尝试过这个端点，获取下一个
 			baseURL = c.getNextBaseURL()
 			if triedEndpoints[baseURL] {
 				// 如果所有端点都已尝试过，退出
-				if len(triedEndpoints) >= len(c.baseURLs) {
+				if len(triedEndpoints) >= c.endpointCount() {
 					break
 				}
 				continue
 			}
 		}
 		triedEndpoints[baseURL] = true
+		c.observeFailover(attemptOpOCR, previousEndpoint, baseURL)
+		previousEndpoint = baseURL
 
-		fmt.Printf("尝试使用端点: %s\n", baseURL)
+		c.printf("尝试使用端点: %s\n", baseURL)
+
+		// 记录在当前端点上认证失败的API密钥，换用其他密钥前不放弃该端点
+		triedKeys := make(map[string]bool)
+		rotateKey := false
 
 		// 内层循环：在当前端点上进行重试
 		for attempt := 0; attempt <= c.maxRetries; attempt++ {
-			if attempt > 0 {
+			if attempt > 0 && !rotateKey {
 				// 指数退避策略，每次重试等待时间增加
-				backoffTime := time.Duration(math.Pow(2, float64(attempt-1))) * time.Second
-				fmt.Printf("第 %d 次重试，等待 %v 后重试...\n", attempt, backoffTime)
-				time.Sleep(backoffTime)
+				c.observeRetry(attemptOpOCR, baseURL)
+				backoffTime := c.backoffDuration(attempt)
+				c.printf("第 %d 次重试，等待 %v 后重试...\n", attempt, backoffTime)
+				if err := sleepContext(ctx, backoffTime); err != nil {
+					return nil, err
+				}
 			}
+			rotateKey = false
 
 			// 使用传入的 API 密钥（打码处理）
 			maskedKey := "****"
@@ -491,11 +979,15 @@ func (c *Client) ProcessOCR(documentURL string, includeImageBase64 bool, apiKey
 				maskedKey = apiKey[:4] + strings.Repeat("*", len(apiKey)-8) + apiKey[len(apiKey)-4:]
 			}
 
-			fmt.Printf("创建请求: POST %socr, API密钥: %s\n", baseURL, maskedKey)
-			req, err := http.NewRequest(http.MethodPost, baseURL+"ocr", bytes.NewBuffer(requestBody))
+			requestURL := c.apiURL(baseURL, c.paths.OCR, nil)
+			if c.ocrURL != "" {
+				requestURL = c.apiURL(c.ocrURL, "", nil)
+			}
+			c.printf("创建请求: POST %s, API密钥: %s\n", requestURL, maskedKey)
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewBuffer(requestBody))
 			if err != nil {
 				lastErr = fmt.Errorf("创建请求错误: %w", err)
-				fmt.Printf("创建请求错误: %v\n", err)
+				c.printf("创建请求错误: %v\n", err)
 				continue
 			}
 
@@ -503,26 +995,48 @@ func (c *Client) ProcessOCR(documentURL string, includeImageBase64 bool, apiKey
 			req.Header.Set("Authorization", "Bearer "+apiKey)
 
 			// 创建带超时的HTTP客户端
-			client := &http.Client{
-				Timeout: c.httpTimeout,
-			}
+			client := c.httpClient(timeout)
 
-			fmt.Printf("发送请求中...\n")
+			c.printf("发送请求中...\n")
+			attempts++
+			start := time.Now()
 			resp, err = client.Do(req)
 			if err != nil {
+				c.observeRequest(attemptOpOCR, baseURL, 0, start)
+				// 上下文已取消时立即返回，不再重试
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
 				lastErr = fmt.Errorf("发送请求错误: %w", err)
-				fmt.Printf("发送请求错误: %v\n", err)
+				c.printf("发送请求错误: %v\n", err)
 				continue
 			}
 
+			c.printf("收到响应，状态码: %d\n", resp.StatusCode)
+
+			// 流式解析成功的响应，不将整个响应体读入内存。解析过程中已经处理了部分页面，出错时不再重试
+			if resp.StatusCode == http.StatusOK && onPage != nil {
+				succeededEndpoint = baseURL
+				repinAPIKey(ctx, apiKey)
+				ocrResp, err := decodeOCRResponseStream(resp.Body, raw, onPage)
+				drainAndClose(resp.Body)
+				c.observeRequest(attemptOpOCR, baseURL, resp.StatusCode, start)
+				if err != nil {
+					c.printf("解析响应错误: %v\n", err)
+					return nil, fmt.Errorf("解析响应错误: %w", err)
+				}
+				c.printf("OCR处理成功，共 %d 页\n", len(ocrResp.Pages))
+				return ocrResp, nil
+			}
+
 			// 读取响应体
-			fmt.Printf("收到响应，状态码: %d\n", resp.StatusCode)
 			bodyBytes, err = io.ReadAll(resp.Body)
 			resp.Body.Close()
+			c.observeRequest(attemptOpOCR, baseURL, resp.StatusCode, start)
 
 			if err != nil {
 				lastErr = fmt.Errorf("读取响应体错误: %w", err)
-				fmt.Printf("读取响应体错误: %v\n", err)
+				c.printf("读取响应体错误: %v\n", err)
 				continue
 			}
 
@@ -532,31 +1046,60 @@ func (c *Client) ProcessOCR(documentURL string, includeImageBase64 bool, apiKey
 				var ocrResp OCRResponse
 				err = json.Unmarshal(bodyBytes, &ocrResp)
 				if err != nil {
-					fmt.Printf("解析响应错误: %v\n", err)
+					c.printf("解析响应错误（响应体 %d 字节）: %v\n", len(bodyBytes), err)
+					// 连接不稳定时可能收到状态码为200但不完整的响应体，重新请求通常可以成功
+					if isTruncatedJSON(err, bodyBytes) {
+						lastErr = fmt.Errorf("解析响应错误，响应体不完整（%d 字节）: %w", len(bodyBytes), err)
+						c.printf("响应体不完整，重试请求\n")
+						continue
+					}
+					succeededEndpoint = baseURL
 					return nil, fmt.Errorf("解析响应错误: %w", err)
 				}
+				succeededEndpoint = baseURL
+				repinAPIKey(ctx, apiKey)
 
 				// 设置原始响应
 				ocrResp.RawResponse = bodyBytes
 
-				fmt.Printf("OCR处理成功，共 %d 页\n", len(ocrResp.Pages))
+				c.printf("OCR处理成功，共 %d 页\n", len(ocrResp.Pages))
 				return &ocrResp, nil
 			} else if resp.StatusCode == http.StatusGatewayTimeout || resp.StatusCode == http.StatusServiceUnavailable {
 				// 服务器超时或不可用，继续重试
-				lastErr = fmt.Errorf("OCR处理失败，状态码 %d: %s", resp.StatusCode, string(bodyBytes))
-				fmt.Printf("服务器错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				lastErr = &APIError{Operation: "OCR处理", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+				c.printf("服务器错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
 				continue
 			} else if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-				// 认证错误，尝试下一个API密钥
-				lastErr = fmt.Errorf("OCR处理失败，状态码 %d: %s", resp.StatusCode, string(bodyBytes))
-				fmt.Printf("认证错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				// 认证错误，先在当前端点换用其他API密钥，所有密钥都失败后再尝试下一个端点
+				lastErr = &APIError{Operation: "OCR处理", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+				c.printf("认证错误，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				if uploadKeyPinned(ctx) {
+					// 签名URL由上传时的密钥获取，文件的请求必须使用同一个密钥
+					return nil, fmt.Errorf("上传文件使用的API密钥认证失败: %w", lastErr)
+				}
+				triedKeys[apiKey] = true
+				if nextKey, ok := c.nextKeyAfter(apiKey, triedKeys); ok {
+					c.printf("在当前端点换用下一个API密钥重试\n")
+					apiKey = nextKey
+					rotateKey = true
+					attempt-- // 更换密钥不计入重试次数
+					continue
+				}
 				break // 跳出内层循环，尝试下一个端点
+			} else if resp.StatusCode == http.StatusRequestEntityTooLarge {
+				// 内容超过端点的大小限制，重试或更换端点也会被拒绝，直接返回
+				lastErr = &PayloadTooLargeError{
+					APIError: &APIError{Operation: "OCR处理", StatusCode: resp.StatusCode, Body: string(bodyBytes)},
+					Size:     documentSize,
+				}
+				c.printf("请求内容过大，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				return nil, lastErr
 			} else {
 				// 其他错误，如果启用了不同端点重试，则尝试下一个端点
-				lastErr = fmt.Errorf("OCR处理失败，状态码 %d: %s", resp.StatusCode, string(bodyBytes))
-				fmt.Printf("请求失败，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
+				lastErr = &APIError{Operation: "OCR处理", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+				c.printf("请求失败，状态码: %d, 响应: %s\n", resp.StatusCode, string(bodyBytes))
 				if c.retryDifferentEndpoint {
-					fmt.Printf("将尝试使用不同端点重试\n")
+					c.printf("将尝试使用不同端点重试\n")
 					break // 跳出内层循环，尝试下一个端点
 				} else {
 					return nil, lastErr // 不尝试其他端点，直接返回错误
@@ -571,6 +1114,6 @@ func (c *Client) ProcessOCR(documentURL string, includeImageBase64 bool, apiKey
 	}
 
 	// 如果所有尝试都失败
-	fmt.Printf("所有尝试均失败，最后错误: %v\n", lastErr)
+	c.printf("所有尝试均失败，最后错误: %v\n", lastErr)
 	return nil, lastErr
 }
@@ -0,0 +1,127 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestClient 创建指向测试服务器的客户端，重试等待时间缩短到毫秒级，不输出日志
+func newTestClient(t *testing.T, serverURL string, apiKeys ...string) *Client {
+	t.Helper()
+	if len(apiKeys) == 0 {
+		apiKeys = []string{"test-key-0000"}
+	}
+	client := NewClient(apiKeys, []string{serverURL + "/"})
+	client.SetBackoff(time.Millisecond, time.Millisecond)
+	client.SetLogger(zap.NewNop())
+	return client
+}
+
+// failingReader 在第一次读取到failAt字节时返回错误，模拟复制文件内容的途中出错，之后的读取正常
+//
+// 不嵌入 *bytes.Reader，避免 io.Copy 通过 WriteTo 绕过 Read。
+type failingReader struct {
+	reader *bytes.Reader
+	failAt int64
+	failed bool
+}
+
+func (r *failingReader) Seek(offset int64, whence int) (int64, error) {
+	return r.reader.Seek(offset, whence)
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if !r.failed {
+		pos, _ := r.reader.Seek(0, io.SeekCurrent)
+		if pos >= r.failAt {
+			r.failed = true
+			return 0, errors.New("模拟的读取错误")
+		}
+		if remaining := r.failAt - pos; int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+	return r.reader.Read(p)
+}
+
+func TestUploadReaderRetriesWithFullContentAfterMidCopyFailure(t *testing.T) {
+	content := bytes.Repeat([]byte("%PDF-1.4 test content "), 4096)
+
+	var mu sync.Mutex
+	var uploads [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		mu.Lock()
+		uploads = append(uploads, data)
+		mu.Unlock()
+		w.Write([]byte(`{"id":"file-1"}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	reader := &failingReader{reader: bytes.NewReader(content), failAt: int64(len(content) / 2)}
+
+	fileID, _, err := client.UploadReader(context.Background(), reader, "test.pdf")
+	if err != nil {
+		t.Fatalf("UploadReader 返回错误: %v", err)
+	}
+	if fileID != "file-1" {
+		t.Fatalf("文件ID = %q，期望 file-1", fileID)
+	}
+	if !reader.failed {
+		t.Fatal("没有模拟出复制途中的读取错误")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(uploads) != 1 {
+		t.Fatalf("服务器收到 %d 次上传，期望只收到重试后的1次", len(uploads))
+	}
+	if !bytes.Equal(uploads[0], content) {
+		t.Fatalf("服务器收到 %d 字节，期望完整的 %d 字节", len(uploads[0]), len(content))
+	}
+}
+
+// TestSetSignedURLExpiry 非正数的有效期返回错误并保留原有设置，有效的设置随获取签名URL的请求发送
+func TestSetSignedURLExpiry(t *testing.T) {
+	expiries := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expiries <- r.URL.Query().Get("expiry")
+		w.Write([]byte(`{"url":"https://files.example.com/signed"}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	for _, hours := range []int{0, -5} {
+		if err := client.SetSignedURLExpiry(hours); err == nil {
+			t.Errorf("SetSignedURLExpiry(%d) 没有返回错误", hours)
+		}
+	}
+	if client.signedURLExpiryHours != defaultSignedURLExpiryHours {
+		t.Errorf("无效的设置修改了有效期: %d", client.signedURLExpiryHours)
+	}
+
+	if err := client.SetSignedURLExpiry(2); err != nil {
+		t.Fatalf("SetSignedURLExpiry(2) 返回错误: %v", err)
+	}
+	if _, err := client.GetSignedURL(context.Background(), "file-1", "test-key-0000"); err != nil {
+		t.Fatalf("GetSignedURL 返回错误: %v", err)
+	}
+	if got := <-expiries; got != "2" {
+		t.Errorf("请求中的expiry = %q，期望 2", got)
+	}
+}
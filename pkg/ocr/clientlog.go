@@ -0,0 +1,24 @@
+package ocr
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// SetLogger 设置客户端记录请求过程（使用的端点、重试、状态码等）的日志记录器，这些信息以info级别记录
+//
+// 未设置时与旧版本行为一致，直接输出到标准输出。应在发送请求前设置。
+func (c *Client) SetLogger(logger *zap.Logger) {
+	c.logger = logger
+}
+
+// printf 输出请求过程信息，设置了日志记录器时以info级别记录，否则输出到标准输出
+func (c *Client) printf(format string, args ...any) {
+	if c.logger == nil {
+		fmt.Printf(format, args...)
+		return
+	}
+	c.logger.Info(strings.TrimRight(fmt.Sprintf(format, args...), "\n"))
+}
@@ -0,0 +1,29 @@
+package ocr
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestClientPrintfLevel 设置日志记录器后，请求过程信息以info级别记录，默认级别下仍然可见
+func TestClientPrintfLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	client := NewClient([]string{"key"}, []string{"https://api.example.com/v1"})
+	client.SetLogger(zap.New(core))
+
+	client.printf("尝试使用端点: %s\n", "https://api.example.com/v1")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("记录了 %d 条日志，期望 1 条", len(entries))
+	}
+	if entries[0].Level != zapcore.InfoLevel {
+		t.Errorf("日志级别 = %s，期望 info", entries[0].Level)
+	}
+	if entries[0].Message != "尝试使用端点: https://api.example.com/v1" {
+		t.Errorf("日志内容 = %q", entries[0].Message)
+	}
+}
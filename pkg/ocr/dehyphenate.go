@@ -0,0 +1,65 @@
+package ocr
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// hyphenBreakPattern 匹配行尾连字符断开的单词，如 "exam-\nple"，前半部分可以是 "state-of-the-" 这样的复合词
+var hyphenBreakPattern = regexp.MustCompile(`(\p{L}+(?:-\p{L}+)*)-[ \t]*\r?\n[ \t]*(\p{L}+)`)
+
+// hyphenatedWordPattern 匹配同一行中带连字符的完整单词，如 "well-known" 或 "state-of-the-art"
+var hyphenatedWordPattern = regexp.MustCompile(`\p{L}+(?:-\p{L}+)+`)
+
+// dehyphenate 合并因排版在行尾断开的单词
+//
+// 前后两部分都是小写字母时去掉连字符合并为一个词（"exam-\nple" -> "example"）；
+// 以下情况认为是真正的复合词，只去掉换行并保留连字符：
+//   - 下一行以大写字母开头（"pre-\nChristian" -> "pre-Christian"）
+//   - 前半部分本身包含连字符（"state-of-the-\nart" -> "state-of-the-art"）
+//   - 带连字符的写法在文本的其他位置作为完整的单词出现过（"well-known"），
+//     "unwell-knownish" 这样只是包含该写法的单词不算
+//
+// 连字符前不是小写字母时（如列表符号、数字、缩写或中文）保持原样。
+func dehyphenate(text string) string {
+	if !strings.Contains(text, "-") {
+		return text
+	}
+	compounds := hyphenatedWords(text)
+
+	return hyphenBreakPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := hyphenBreakPattern.FindStringSubmatch(match)
+		head, tail := parts[1], parts[2]
+
+		last, _ := utf8.DecodeLastRuneInString(head)
+		first, _ := utf8.DecodeRuneInString(tail)
+		if !unicode.IsLower(last) {
+			return match
+		}
+
+		compound := head + "-" + tail
+		switch {
+		case unicode.IsUpper(first), strings.Contains(head, "-"), compounds[strings.ToLower(compound)]:
+			return compound
+		case unicode.IsLower(first):
+			return head + tail
+		default:
+			return match
+		}
+	})
+}
+
+// hyphenatedWords 返回文本中同一行内带连字符的单词中每两个相邻部分组成的写法（小写），
+// 如 "state-of-the-art" 得到 "state-of"、"of-the" 和 "the-art"
+func hyphenatedWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range hyphenatedWordPattern.FindAllString(text, -1) {
+		parts := strings.Split(strings.ToLower(word), "-")
+		for i := 1; i < len(parts); i++ {
+			words[parts[i-1]+"-"+parts[i]] = true
+		}
+	}
+	return words
+}
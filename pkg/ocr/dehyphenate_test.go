@@ -0,0 +1,37 @@
+package ocr
+
+import "testing"
+
+func TestDehyphenate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "没有连字符", in: "plain text\nnext line", want: "plain text\nnext line"},
+		{name: "小写单词合并", in: "an exam-\nple here", want: "an example here"},
+		{name: "连字符后有空白", in: "an exam- \n  ple here", want: "an example here"},
+		{name: "CRLF换行", in: "an exam-\r\nple here", want: "an example here"},
+		{name: "下一行以大写字母开头", in: "the pre-\nChristian era", want: "the pre-Christian era"},
+		{name: "前半部分是复合词", in: "a state-of-the-\nart method", want: "a state-of-the-art method"},
+		{name: "复合词在其他位置出现", in: "a well-known fact\nis well-\nknown", want: "a well-known fact\nis well-known"},
+		{name: "复合词大小写不同", in: "Well-Known facts\nare well-\nknown", want: "Well-Known facts\nare well-known"},
+		{name: "只是包含复合词的单词不算", in: "unwell-knownish\nis well-\nknown", want: "unwell-knownish\nis wellknown"},
+		{name: "复合词被更长的单词包含", in: "nonwell-known\nwell-\nknown", want: "nonwell-known\nwellknown"},
+		{name: "同样断开的写法不算出现过", in: "exam-\nple and exam-\nple", want: "example and example"},
+		{name: "列表符号", in: "items:\n-\nfirst", want: "items:\n-\nfirst"},
+		{name: "连字符前是大写字母", in: "see ABC-\ndef", want: "see ABC-\ndef"},
+		{name: "连字符前是数字", in: "pages 10-\n20", want: "pages 10-\n20"},
+		{name: "中文", in: "中文-\n文本", want: "中文-\n文本"},
+		{name: "下一行以数字开头", in: "version-\n2 released", want: "version-\n2 released"},
+		{name: "同一行的连字符不变", in: "a co-op and a re-run", want: "a co-op and a re-run"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dehyphenate(tt.in); got != tt.want {
+				t.Errorf("dehyphenate(%q) = %q，期望 %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package ocr
+
+import "strings"
+
+// SetUploadURL 设置上传文件请求的完整URL，用于将上传路由到其他主机的代理
+//
+// 设置后上传请求直接使用该URL（仍附加 SetQueryParams 设置的参数），不再拼接基础URL和接口路径，
+// 也不在多个端点之间切换；传入空字符串恢复默认行为。
+func (c *Client) SetUploadURL(requestURL string) {
+	c.uploadURL = strings.TrimSpace(requestURL)
+}
+
+// SetSignedURLEndpoint 设置获取签名URL请求的完整URL，{id} 会被替换为文件ID，规则同 SetUploadURL
+func (c *Client) SetSignedURLEndpoint(requestURL string) {
+	c.signedURLEndpoint = strings.TrimSpace(requestURL)
+}
+
+// SetOCRURL 设置OCR请求的完整URL，规则同 SetUploadURL
+func (c *Client) SetOCRURL(requestURL string) {
+	c.ocrURL = strings.TrimSpace(requestURL)
+}
+
+// endpointCountFor 返回一次请求最多尝试的端点数量，设置了完整URL时只使用该URL
+func (c *Client) endpointCountFor(override string) int {
+	if override != "" {
+		return 1
+	}
+	return c.endpointCount()
+}
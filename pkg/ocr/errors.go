@@ -0,0 +1,161 @@
+package ocr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// BatchError 表示批量处理失败，同时携带已成功处理的结果和每个失败文件的错误
+//
+// BatchError 实现了 Unwrap() []error，可以通过 errors.Is 和 errors.As 检查其中任意一个文件的错误。
+type BatchError struct {
+	Results []*ProcessResult // 出错前已成功处理的结果
+	Errors  []error          // 每个失败文件（或路径）的错误
+}
+
+// Error 实现 error 接口
+func (e *BatchError) Error() string {
+	if len(e.Errors) == 1 {
+		if len(e.Results) == 0 {
+			return fmt.Sprintf("处理文件失败: %v", e.Errors[0])
+		}
+		return fmt.Sprintf("处理文件失败（已成功 %d 个）: %v", len(e.Results), e.Errors[0])
+	}
+	if len(e.Results) == 0 {
+		return fmt.Sprintf("所有文件处理失败，发生了 %d 个错误", len(e.Errors))
+	}
+	return fmt.Sprintf("批量处理失败，成功 %d 个，失败 %d 个", len(e.Results), len(e.Errors))
+}
+
+// Unwrap 返回每个失败文件的错误，供 errors.Is 和 errors.As 逐个检查
+func (e *BatchError) Unwrap() []error {
+	return e.Errors
+}
+
+// noFilesError 返回批量处理没有找到可处理的文件时的 *BatchError，errs为收集文件时发生的错误
+func noFilesError(message string, errs []error) *BatchError {
+	err := errors.New(message)
+	if len(errs) > 0 {
+		err = fmt.Errorf("%s，发生了 %d 个错误: %w", message, len(errs), errors.Join(errs...))
+	}
+	return &BatchError{Errors: []error{err}}
+}
+
+// APIError 表示API返回了非成功的状态码
+type APIError struct {
+	Operation  string // 操作名称，例如 "上传"、"获取签名URL"、"OCR处理"
+	StatusCode int    // HTTP状态码
+	Body       string // 响应体
+}
+
+// Error 实现 error 接口
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s失败，状态码 %d: %s", e.Operation, e.StatusCode, e.Body)
+}
+
+// PayloadTooLargeError 表示端点因请求内容过大拒绝了请求（API返回413）
+//
+// 文件超过端点的大小限制时重试或更换端点都无法处理，因此不再重试。
+// PayloadTooLargeError 包装了对应的 *APIError，可以通过 errors.As 取得状态码和响应体。
+type PayloadTooLargeError struct {
+	*APIError
+	Size int64 // 请求中文档内容的大小（字节），未知时为0
+}
+
+// Error 实现 error 接口
+func (e *PayloadTooLargeError) Error() string {
+	size := ""
+	switch {
+	case e.Size >= 1024*1024:
+		size = fmt.Sprintf("（%.2f MB）", float64(e.Size)/1024/1024)
+	case e.Size > 0:
+		size = fmt.Sprintf("（%d 字节）", e.Size)
+	}
+	return fmt.Sprintf("%s失败，文件%s超过了端点允许的请求大小（状态码 %d），请将文件拆分为较小的文件或压缩后重试，"+
+		"并将 max_upload_size_mb 设置为端点的实际限制以便在上传前发现: %s", e.Operation, size, e.StatusCode, e.Body)
+}
+
+// Unwrap 返回对应的 *APIError
+func (e *PayloadTooLargeError) Unwrap() error {
+	return e.APIError
+}
+
+// IsPayloadTooLarge 判断错误是否由请求内容超过端点的大小限制（API返回413）导致
+func IsPayloadTooLarge(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestEntityTooLarge
+}
+
+// IsAuthError 判断错误是否由认证失败（API返回401或403）导致
+//
+// 对于 *BatchError，所有失败的文件都是认证失败时才返回true。
+func IsAuthError(err error) bool {
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		if len(batchErr.Errors) == 0 {
+			return false
+		}
+		for _, e := range batchErr.Errors {
+			if !IsAuthError(e) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+}
+
+// isDocumentURLExpired 判断OCR错误是否由签名URL过期或失效导致
+func isDocumentURLExpired(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusGone, http.StatusUnprocessableEntity:
+	default:
+		return false
+	}
+
+	body := strings.ToLower(apiErr.Body)
+	return strings.Contains(body, "expired") ||
+		strings.Contains(body, "signature") ||
+		(strings.Contains(body, "url") && strings.Contains(body, "invalid"))
+}
+
+// isDocumentFetchError 判断OCR错误是否表示API无法访问提供的文档URL
+//
+// 认证失败（401/403）和限流（429）不属于此类错误。
+func isDocumentFetchError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	}
+	return apiErr.StatusCode >= 400 && apiErr.StatusCode < 500
+}
+
+// isTruncatedJSON 判断JSON解析错误是否由响应体不完整导致，即语法错误出现在内容末尾（意外结束）
+//
+// 内容完整但格式错误或字段类型不符的响应不属于此类，重试也无法解析。
+func isTruncatedJSON(err error, body []byte) bool {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return false
+	}
+	return syntaxErr.Offset >= int64(len(bytes.TrimRight(body, " \t\r\n")))
+}
@@ -0,0 +1,53 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+// TestBatchNoFilesReturnsBatchError 没有找到可处理的文件时批量处理返回 *BatchError，其中包装了收集文件时的错误
+func TestBatchNoFilesReturnsBatchError(t *testing.T) {
+	emptyDir := t.TempDir()
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		name        string
+		paths       []string
+		wantMissing bool
+	}{
+		{name: "没有路径", paths: nil},
+		{name: "目录中没有文件", paths: []string{emptyDir}},
+		{name: "路径不存在", paths: []string{missing}, wantMissing: true},
+	}
+
+	processor := newTestProcessor(&fakeBackend{pages: 1})
+	convert := func(paths []string, opts ProcessOptions) ([]*ProcessResult, error) {
+		return processor.ConvertMultipleJSON(context.Background(), paths, opts)
+	}
+	process := func(paths []string, opts ProcessOptions) ([]*ProcessResult, error) {
+		return processor.ProcessMultipleFiles(context.Background(), paths, opts)
+	}
+	for _, batch := range []struct {
+		name string
+		run  func([]string, ProcessOptions) ([]*ProcessResult, error)
+	}{{"ProcessMultipleFiles", process}, {"ConvertMultipleJSON", convert}} {
+		for _, tt := range tests {
+			t.Run(batch.name+"/"+tt.name, func(t *testing.T) {
+				results, err := batch.run(tt.paths, ProcessOptions{OutputDir: t.TempDir(), ContinueOnError: true})
+				var batchErr *BatchError
+				if !errors.As(err, &batchErr) {
+					t.Fatalf("返回 %v（%T），期望 *BatchError", err, err)
+				}
+				if len(results) != 0 || len(batchErr.Results) != 0 {
+					t.Errorf("返回了 %d 个结果，期望没有结果", len(results))
+				}
+				if got := errors.Is(err, fs.ErrNotExist); got != tt.wantMissing {
+					t.Errorf("errors.Is(err, fs.ErrNotExist) = %v，期望 %v: %v", got, tt.wantMissing, err)
+				}
+			})
+		}
+	}
+}
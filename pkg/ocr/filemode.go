@@ -0,0 +1,72 @@
+package ocr
+
+import (
+	"fmt"
+	"os"
+)
+
+// 默认的输出文件和目录权限
+const (
+	DefaultFileMode os.FileMode = 0644
+	DefaultDirMode  os.FileMode = 0755
+)
+
+// fileMode 返回输出文件的权限，未设置时使用 DefaultFileMode
+func (o ProcessOptions) fileMode() os.FileMode {
+	if o.FileMode == 0 {
+		return DefaultFileMode
+	}
+	return o.FileMode
+}
+
+// dirMode 返回输出目录的权限，未设置时使用 DefaultDirMode
+func (o ProcessOptions) dirMode() os.FileMode {
+	if o.DirMode == 0 {
+		return DefaultDirMode
+	}
+	return o.DirMode
+}
+
+// validateModes 校验权限设置：只能包含权限位，文件至少所有者可读写，目录至少所有者可读写和进入
+func (o ProcessOptions) validateModes() error {
+	if o.FileMode != 0 && (o.FileMode&^os.ModePerm != 0 || o.FileMode&0600 != 0600) {
+		return fmt.Errorf("无效的文件权限 %#o，必须在0600到0777之间且所有者可读写", uint32(o.FileMode))
+	}
+	if o.DirMode != 0 && (o.DirMode&^os.ModePerm != 0 || o.DirMode&0700 != 0700) {
+		return fmt.Errorf("无效的目录权限 %#o，必须在0700到0777之间且所有者可读写和进入", uint32(o.DirMode))
+	}
+	return nil
+}
+
+// writeFile 按设置的权限写入文件，显式设置了 FileMode 时同时修正已存在文件的权限
+func (o ProcessOptions) writeFile(path string, data []byte) error {
+	release := o.openFiles.acquire()
+	defer release()
+	if err := os.WriteFile(path, data, o.fileMode()); err != nil {
+		return err
+	}
+	if o.FileMode != 0 {
+		return os.Chmod(path, o.FileMode)
+	}
+	return nil
+}
+
+// createFile 按设置的权限创建或清空文件，显式设置了 FileMode 时同时修正已存在文件的权限
+func (o ProcessOptions) createFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, o.fileMode())
+	if err != nil {
+		return nil, err
+	}
+	if o.FileMode != 0 {
+		if err := file.Chmod(o.FileMode); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// mkdirAll 按设置的权限创建目录
+func (o ProcessOptions) mkdirAll(path string) error {
+	return os.MkdirAll(path, o.dirMode())
+}
@@ -0,0 +1,140 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 端点探测的超时时间
+const probeTimeout = 10 * time.Second
+
+// EndpointHealth 记录单个端点的探测结果
+type EndpointHealth struct {
+	BaseURL   string        // 端点基础URL
+	Healthy   bool          // 是否可用
+	Latency   time.Duration // 探测耗时
+	Error     string        // 探测失败的原因
+	CheckedAt time.Time     // 探测时间
+}
+
+// SetProbeConcurrency 设置端点探测的最大并发数，非正数表示同时探测所有端点
+func (c *Client) SetProbeConcurrency(n int) {
+	c.probeConcurrency = n
+}
+
+// EndpointStatus 返回最近一次探测得到的各端点状态
+func (c *Client) EndpointStatus() []EndpointHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := make([]EndpointHealth, 0, len(c.endpointHealth))
+	for _, baseURL := range c.baseURLs {
+		if health, ok := c.endpointHealth[baseURL]; ok {
+			status = append(status, health)
+		}
+	}
+	return status
+}
+
+// WarmupEndpoints 并发探测所有配置的端点，并调整轮询顺序，优先使用响应最快的可用端点
+//
+// 探测使用一次轻量的GET请求，只要端点返回了非5xx的响应（包括认证失败）即认为可用。
+// 返回的结果按新的轮询顺序排列。
+func (c *Client) WarmupEndpoints(ctx context.Context) []EndpointHealth {
+	c.mu.Lock()
+	baseURLs := append([]string(nil), c.baseURLs...)
+	c.mu.Unlock()
+
+	results := make([]EndpointHealth, len(baseURLs))
+
+	concurrency := c.probeConcurrency
+	if concurrency <= 0 || concurrency > len(baseURLs) {
+		concurrency = len(baseURLs)
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, baseURL := range baseURLs {
+		wg.Add(1)
+		go func(i int, baseURL string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = c.probeEndpoint(ctx, baseURL)
+		}(i, baseURL)
+	}
+	wg.Wait()
+
+	// 可用端点按耗时排序排在前面，不可用端点保持原有顺序排在后面
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Healthy != results[j].Healthy {
+			return results[i].Healthy
+		}
+		if results[i].Healthy {
+			return results[i].Latency < results[j].Latency
+		}
+		return false
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.endpointHealth == nil {
+		c.endpointHealth = make(map[string]EndpointHealth)
+	}
+	ordered := make([]string, len(results))
+	for i, health := range results {
+		ordered[i] = health.BaseURL
+		c.endpointHealth[health.BaseURL] = health
+	}
+	c.baseURLs = ordered
+	c.currentURLIndex = 0
+
+	return results
+}
+
+// probeEndpoint 探测单个端点是否可用
+func (c *Client) probeEndpoint(ctx context.Context, baseURL string) EndpointHealth {
+	health := EndpointHealth{
+		BaseURL:   baseURL,
+		CheckedAt: time.Now(),
+	}
+
+	timeout := c.httpTimeout
+	if timeout <= 0 || timeout > probeTimeout {
+		timeout = probeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL(baseURL, c.paths.Models, nil), nil)
+	if err != nil {
+		health.Error = fmt.Sprintf("创建请求错误: %v", err)
+		return health
+	}
+	if apiKey := c.NextAPIKey(); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient(0).Do(req)
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Error = fmt.Sprintf("发送请求错误: %v", err)
+		return health
+	}
+	// 读完响应体，探测时建立的连接可以在之后的请求中复用
+	drainAndClose(resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		health.Error = fmt.Sprintf("服务器错误，状态码 %d", resp.StatusCode)
+		return health
+	}
+
+	health.Healthy = true
+	return health
+}
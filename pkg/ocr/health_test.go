@@ -0,0 +1,63 @@
+package ocr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWarmupEndpointsProbeConcurrency 选项中的探测并发数限制同时探测的端点数
+func TestWarmupEndpointsProbeConcurrency(t *testing.T) {
+	var current, peak atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var baseURLs []string
+	for i := 0; i < 6; i++ {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		baseURLs = append(baseURLs, server.URL+"/")
+	}
+
+	tests := []struct {
+		concurrency int
+		min, max    int32
+	}{
+		{concurrency: 2, min: 1, max: 2},
+		// 0表示同时探测所有端点
+		{concurrency: 0, min: 3, max: int32(len(baseURLs))},
+	}
+	for _, tt := range tests {
+		peak.Store(0)
+		client, err := NewClientWithOptions(ClientOptions{
+			APIKeys:          []string{"key"},
+			BaseURLs:         baseURLs,
+			Timeout:          time.Minute,
+			ProbeConcurrency: tt.concurrency,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, health := range client.WarmupEndpoints(context.Background()) {
+			if !health.Healthy {
+				t.Fatalf("端点 %s 不可用: %s", health.BaseURL, health.Error)
+			}
+		}
+		if got := peak.Load(); got < tt.min || got > tt.max {
+			t.Errorf("探测并发数为 %d 时同时探测了 %d 个端点，期望 %d 到 %d 个", tt.concurrency, got, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ocr
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// OnImageError 的可选值
+const (
+	OnImageErrorSkip        = "skip"        // 跳过无法解码的图片，markdown中的链接保持原样（默认）
+	OnImageErrorPlaceholder = "placeholder" // 保存一张占位图片，无法解码的图片链接指向该图片
+	OnImageErrorRemoveLink  = "removelink"  // 从markdown中移除无法解码的图片的链接
+)
+
+// ImagePlaceholderFileName 无法解码的图片链接指向的占位图片文件名，保存在images目录中
+const ImagePlaceholderFileName = "image-unavailable.svg"
+
+// imagePlaceholderSVG 占位图片的内容：灰色边框和"图片无法显示"文字
+const imagePlaceholderSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="320" height="120" viewBox="0 0 320 120">
+  <rect x="1" y="1" width="318" height="118" fill="#f4f4f4" stroke="#999" stroke-dasharray="6 4"/>
+  <text x="160" y="66" font-family="sans-serif" font-size="16" fill="#666" text-anchor="middle">图片无法显示</text>
+</svg>
+`
+
+// validateOnImageError 校验 OnImageError 的取值
+func (o ProcessOptions) validateOnImageError() error {
+	switch o.OnImageError {
+	case "", OnImageErrorSkip, OnImageErrorPlaceholder, OnImageErrorRemoveLink:
+		return nil
+	default:
+		return fmt.Errorf("无效的图片解码失败处理方式: %s，可选值为 %s、%s 或 %s",
+			o.OnImageError, OnImageErrorSkip, OnImageErrorPlaceholder, OnImageErrorRemoveLink)
+	}
+}
+
+// savePlaceholderImage 在imagesDir中保存占位图片，返回其相对于输出目录、以 / 分隔的路径，可直接用作markdown链接
+//
+// 文件名与已保存的图片重名时添加序号。
+func savePlaceholderImage(imagesDir string, imageMap map[string]string, opts ProcessOptions) (string, error) {
+	used := make(map[string]bool, len(imageMap))
+	for _, relPath := range imageMap {
+		used[filepath.Base(relPath)] = true
+	}
+	name := uniqueFilename(ImagePlaceholderFileName, used)
+	if err := opts.writeFile(filepath.Join(imagesDir, name), []byte(imagePlaceholderSVG)); err != nil {
+		return "", err
+	}
+	return path.Join("images", name), nil
+}
+
+// removeImageLinksTo 移除markdown中指向ids中图片ID的图片链接，只包含这些链接的行整行移除
+func removeImageLinksTo(markdown string, ids map[string]bool) string {
+	if len(ids) == 0 {
+		return markdown
+	}
+	lines := strings.Split(markdown, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		stripped := markdownImagePattern.ReplaceAllStringFunc(line, func(link string) string {
+			if ids[markdownImagePattern.FindStringSubmatch(link)[2]] {
+				return ""
+			}
+			return link
+		})
+		if stripped != line && strings.TrimSpace(stripped) == "" {
+			continue
+		}
+		kept = append(kept, stripped)
+	}
+	return strings.Join(kept, "\n")
+}
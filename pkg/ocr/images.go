@@ -0,0 +1,286 @@
+package ocr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 默认图片扩展名
+const defaultImageExt = ".jpeg"
+
+// ImageNaming 的可选值
+const (
+	ImageNamingID         = "id"         // 按响应中的图片ID命名，如 img-0.jpeg
+	ImageNamingPositional = "positional" // 按页面索引和页内序号命名，如 page0003-img001.jpeg，文件名顺序与文档顺序一致
+)
+
+// validateImageNaming 校验 ImageNaming 的取值
+func (o ProcessOptions) validateImageNaming() error {
+	switch o.ImageNaming {
+	case "", ImageNamingID, ImageNamingPositional:
+		return nil
+	default:
+		return fmt.Errorf("无效的图片命名方式: %s，可选值为 %s 或 %s", o.ImageNaming, ImageNamingID, ImageNamingPositional)
+	}
+}
+
+// imageFileNameFor 按 ImageNaming 确定页面中第pos张图片的文件名，data为nil时根据ID确定扩展名
+//
+// 按位置命名时页面索引补零到4位、页内序号补零到3位，保证文件名排序与文档顺序一致。
+func (o ProcessOptions) imageFileNameFor(page Page, pos int, data []byte) string {
+	name := imageFilename(page.Images[pos].ID, data)
+	if o.ImageNaming != ImageNamingPositional {
+		return name
+	}
+	return fmt.Sprintf("page%04d-img%03d%s", page.Index, pos, filepath.Ext(name))
+}
+
+// uniqueImageFileName 按 imageFileNameFor 确定图片文件名，文件名已被used占用时添加序号后缀
+//
+// 保存图片和只引用已有图片时使用相同的规则，ID不同但规范化后同名的图片在两种情况下得到相同的文件名。
+func (o ProcessOptions) uniqueImageFileName(page Page, pos int, data []byte, used map[string]bool) string {
+	return uniqueFilename(o.imageFileNameFor(page, pos, data), used)
+}
+
+// markdownImagePattern 匹配markdown图片链接 ![alt](target)
+var markdownImagePattern = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+
+// imageLink 返回markdown中引用已保存图片的链接，设置了 ImageLinkBase 时将其加在相对路径之前
+func (o ProcessOptions) imageLink(relPath string) string {
+	if o.ImageLinkBase == "" {
+		return relPath
+	}
+	return strings.TrimSuffix(o.ImageLinkBase, "/") + "/" + filepath.ToSlash(relPath)
+}
+
+// imageLinks 将图片ID到相对路径的映射转换为图片ID到markdown链接的映射，未设置 ImageLinkBase 时原样返回
+func (o ProcessOptions) imageLinks(imageMap map[string]string) map[string]string {
+	if o.ImageLinkBase == "" {
+		return imageMap
+	}
+	links := make(map[string]string, len(imageMap))
+	for id, relPath := range imageMap {
+		links[id] = o.imageLink(relPath)
+	}
+	return links
+}
+
+// removeImageLinks 移除markdown中的所有图片链接，只包含图片链接的行整行移除，不留下多余的空行
+func removeImageLinks(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		stripped := markdownImagePattern.ReplaceAllString(line, "")
+		if stripped != line && strings.TrimSpace(stripped) == "" {
+			continue
+		}
+		kept = append(kept, stripped)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// rewriteImageLinks 将markdown中指向图片ID的链接替换为本地路径
+//
+// 按链接目标匹配图片ID，不要求替代文本与ID相同，未知的链接保持不变。
+func rewriteImageLinks(markdown string, imageMap map[string]string) string {
+	if len(imageMap) == 0 {
+		return markdown
+	}
+	return markdownImagePattern.ReplaceAllStringFunc(markdown, func(link string) string {
+		match := markdownImagePattern.FindStringSubmatch(link)
+		localPath, ok := imageMap[match[2]]
+		if !ok {
+			return link
+		}
+		return "![" + match[1] + "](" + localPath + ")"
+	})
+}
+
+// sanitizeImageFilename 将图片ID转换为安全的文件名
+//
+// 移除目录分隔符和 ".."，替换Windows不允许的字符和控制字符，
+// 保证生成的文件只能写入images目录内。
+func sanitizeImageFilename(id string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			return '_'
+		case strings.ContainsRune(`/\<>:"|?*`, r):
+			return '_'
+		default:
+			return r
+		}
+	}, id)
+
+	// 不允许出现上级目录引用
+	name = strings.ReplaceAll(name, "..", "_")
+
+	// Windows不允许文件名以空格或点结尾
+	name = strings.TrimRight(name, " .")
+	name = strings.TrimLeft(name, " ")
+
+	if name == "" || name == "." {
+		name = "image"
+	}
+
+	return name
+}
+
+// detectImageFormat 根据文件头识别图片格式，返回对应的扩展名，无法识别时返回空字符串
+func detectImageFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8, 0xff}):
+		return ".jpeg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return ".png"
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return ".gif"
+	case len(data) >= 12 && bytes.Equal(data[0:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return ".webp"
+	case len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")) &&
+		(bytes.Equal(data[8:12], []byte("avif")) || bytes.Equal(data[8:12], []byte("avis"))):
+		return ".avif"
+	case bytes.HasPrefix(data, []byte("BM")):
+		return ".bmp"
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		return ".tiff"
+	default:
+		return ""
+	}
+}
+
+// ImageContentType 根据图片内容返回MIME类型，无法识别时返回 application/octet-stream
+func ImageContentType(data []byte) string {
+	switch detectImageFormat(data) {
+	case ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".avif":
+		return "image/avif"
+	case ".bmp":
+		return "image/bmp"
+	case ".tiff":
+		return "image/tiff"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// hasImageData 判断图片是否包含base64数据，未请求图片时API返回空字符串或 "..."
+func hasImageData(img Image) bool {
+	return img.ImageBase64 != "" && img.ImageBase64 != "..."
+}
+
+// decodeImageData 解码图片的base64数据，支持 data:image/jpeg;base64,... 格式的data URL
+func decodeImageData(imgData string) ([]byte, error) {
+	// 检查是否是Data URL格式
+	if strings.HasPrefix(imgData, "data:") {
+		// 提取base64部分
+		parts := strings.Split(imgData, ",")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("解析图片数据URL格式失败")
+		}
+		imgData = parts[1]
+		// 处理URL编码的换行符
+		imgData = strings.ReplaceAll(imgData, "\n", "")
+		imgData = strings.ReplaceAll(imgData, "\r", "")
+		// 移除所有空白字符
+		imgData = strings.ReplaceAll(imgData, " ", "")
+	}
+
+	// 解码base64数据
+	data, err := base64.StdEncoding.DecodeString(imgData)
+	if err != nil {
+		return nil, fmt.Errorf("解码base64数据失败: %w", err)
+	}
+	return data, nil
+}
+
+// imageFilename 根据图片ID和内容生成安全的文件名，ID没有扩展名时根据内容补全
+func imageFilename(id string, data []byte) string {
+	name := sanitizeImageFilename(id)
+	if filepath.Ext(name) != "" {
+		return name
+	}
+
+	ext := detectImageFormat(data)
+	if ext == "" {
+		ext = defaultImageExt // 无法识别时使用默认扩展名
+	}
+	return name + ext
+}
+
+// uniqueFilename 在文件名已被占用时添加序号后缀
+func uniqueFilename(name string, used map[string]bool) string {
+	if !used[name] {
+		used[name] = true
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := base + "-" + strconv.Itoa(i) + ext
+		if !used[candidate] {
+			used[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// imageDedup 记录已保存图片的内容哈希，启用 DedupImages 时内容相同的图片只保存一次
+type imageDedup struct {
+	paths      map[string]string // 图片内容的SHA-256到已保存文件的相对路径
+	duplicates int               // 指向已有文件而未保存的图片数量
+	bytesSaved int64             // 未保存的重复图片的总字节数
+}
+
+// newImageDedup 在启用 DedupImages 时创建去重记录，否则返回nil
+func newImageDedup(opts ProcessOptions) *imageDedup {
+	if !opts.DedupImages {
+		return nil
+	}
+	return &imageDedup{paths: make(map[string]string)}
+}
+
+// lookup 返回与data内容相同的已保存图片的相对路径，d为nil或没有相同图片时返回false
+func (d *imageDedup) lookup(data []byte) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+	relPath, ok := d.paths[imageHash(data)]
+	return relPath, ok
+}
+
+// record 记录已保存的图片
+func (d *imageDedup) record(data []byte, relPath string) {
+	if d != nil {
+		d.paths[imageHash(data)] = relPath
+	}
+}
+
+// duplicateCount 返回重复的图片数量，d为nil时返回0
+func (d *imageDedup) duplicateCount() int {
+	if d == nil {
+		return 0
+	}
+	return d.duplicates
+}
+
+// imageHash 计算图片内容的SHA-256
+func imageHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,276 @@
+package ocr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testWEBP 1x1像素的无损WEBP图片
+var testWEBP, _ = base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+func TestDecodeWEBP(t *testing.T) {
+	img, format, err := image.Decode(bytes.NewReader(testWEBP))
+	if err != nil {
+		t.Fatalf("解码WEBP图片失败: %v", err)
+	}
+	if format != "webp" {
+		t.Errorf("格式 = %s，期望 webp", format)
+	}
+	if b := img.Bounds(); b.Dx() != 1 || b.Dy() != 1 {
+		t.Errorf("图片大小 = %v，期望 1x1", b)
+	}
+}
+
+// TestSaveUndecodableImages WEBP图片可以解码并合并到images.pdf，无法解码的图片仍按原始数据保存
+func TestSaveUndecodableImages(t *testing.T) {
+	unknown := []byte("not an image format Go can decode")
+	resp := OCRResponse{Model: "fake-ocr", Pages: []Page{{
+		Index:    0,
+		Markdown: "![img-0.webp](img-0.webp)\n\n![blob](blob)",
+		Images: []Image{
+			{ID: "img-0.webp", ImageBase64: "data:image/webp;base64," + base64.StdEncoding.EncodeToString(testWEBP)},
+			{ID: "blob", ImageBase64: base64.StdEncoding.EncodeToString(unknown)},
+		},
+	}}}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(t.TempDir(), "response.json")
+	if err := os.WriteFile(jsonPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := newTestProcessor(&fakeBackend{}).ConvertJSONToMarkdown(jsonPath, ProcessOptions{
+		OutputDir:          t.TempDir(),
+		IncludeImages:      true,
+		StripImageMetadata: true,
+		BundleImages:       BundleImagesPDF,
+	})
+	if err != nil {
+		t.Fatalf("ConvertJSONToMarkdown 返回错误: %v", err)
+	}
+	if result.Images != 2 {
+		t.Errorf("保存了 %d 张图片，期望 2 张", result.Images)
+	}
+
+	for name, want := range map[string][]byte{"img-0.webp": testWEBP, "blob.jpeg": unknown} {
+		got, err := os.ReadFile(filepath.Join(result.OutputDir, "images", name))
+		if err != nil {
+			t.Errorf("读取图片 %s 失败: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("图片 %s 的内容与原始数据不同", name)
+		}
+	}
+
+	pdfData, err := os.ReadFile(filepath.Join(result.OutputDir, ImagesPDFFileName))
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", ImagesPDFFileName, err)
+	}
+	if pages, err := pdfPageCount(pdfData, ""); err != nil || pages != 1 {
+		t.Errorf("%s 有 %d 页（%v），期望只包含WEBP图片的 1 页", ImagesPDFFileName, pages, err)
+	}
+}
+
+func TestSanitizeImageFilename(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{id: "img-0.jpeg", want: "img-0.jpeg"},
+		{id: "../x", want: "__x"},
+		{id: "a/../../b", want: "a_____b"},
+		{id: `..\x`, want: "__x"},
+		{id: `C:\x`, want: "C__x"},
+		{id: "/etc/passwd", want: "_etc_passwd"},
+		{id: "con:", want: "con_"},
+		{id: "a\x00b\tc\x7f", want: "a_b_c_"},
+		{id: `a<b>c"d|e?f*g`, want: "a_b_c_d_e_f_g"},
+		{id: " . ", want: "image"},
+		{id: "..", want: "_"},
+		{id: "...", want: "_"},
+		{id: "name. ", want: "name"},
+		{id: "", want: "image"},
+	}
+
+	imagesDir := filepath.Join(t.TempDir(), "images")
+	for _, tt := range tests {
+		got := sanitizeImageFilename(tt.id)
+		if got != tt.want {
+			t.Errorf("sanitizeImageFilename(%q) = %q，期望 %q", tt.id, got, tt.want)
+		}
+		if filepath.Dir(filepath.Join(imagesDir, got)) != imagesDir {
+			t.Errorf("sanitizeImageFilename(%q) = %q 会写到images目录之外", tt.id, got)
+		}
+	}
+}
+
+// TestSaveResultsUnsafeImageIDs 图片ID包含路径或特殊字符时，所有图片都写入images目录，markdown中的链接指向实际保存的文件
+func TestSaveResultsUnsafeImageIDs(t *testing.T) {
+	ids := []string{"../escape.png", "a/../../b.png", `..\escape.png`, `C:\abs.png`, "con:", "ctl\x01.png", " . ", ""}
+	page := Page{Index: 0}
+	var markdown []string
+	for _, id := range ids {
+		page.Images = append(page.Images, Image{ID: id, ImageBase64: base64.StdEncoding.EncodeToString(testPNG)})
+		markdown = append(markdown, "!["+id+"]("+id+")")
+	}
+	page.Markdown = strings.Join(markdown, "\n\n")
+
+	root := t.TempDir()
+	outputDir := filepath.Join(root, "out", "doc")
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	processor := newTestProcessor(&fakeBackend{})
+	result, err := processor.saveResults(&OCRResponse{Pages: []Page{page}}, outputDir, ProcessMetadata{}, ProcessOptions{IncludeImages: true})
+	if err != nil {
+		t.Fatalf("saveResults 返回错误: %v", err)
+	}
+	if result.Images != len(ids) {
+		t.Errorf("保存了 %d 张图片，期望 %d 张", result.Images, len(ids))
+	}
+
+	imagesDir := filepath.Join(outputDir, "images")
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if filepath.Dir(path) != imagesDir && filepath.Dir(path) != outputDir {
+			t.Errorf("写入了输出目录之外的文件: %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(imagesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(ids) {
+		t.Errorf("images目录中有 %d 个文件，期望 %d 个", len(entries), len(ids))
+	}
+
+	output, err := os.ReadFile(filepath.Join(outputDir, "output.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, _, err := LoadMetadata(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		relPath, ok := metadata.ImageFiles[id]
+		if !ok {
+			t.Errorf("image_files 中没有图片 %q", id)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(relPath))); err != nil {
+			t.Errorf("图片 %q 映射到的文件 %s 不存在: %v", id, relPath, err)
+		}
+		// 链接目标不能包含空白，这类ID的链接在响应中本来就无法识别
+		if id == "" || strings.ContainsAny(id, " \t\n") {
+			continue
+		}
+		if link := "![" + id + "](" + relPath + ")"; !strings.Contains(string(output), link) {
+			t.Errorf("output.md 中缺少链接 %q:\n%s", link, output)
+		}
+	}
+}
+
+func TestRewriteImageLinks(t *testing.T) {
+	imageMap := map[string]string{"img-0.jpeg": "images/img-0.jpeg", "img-1.jpeg": "images/page-1.jpeg"}
+	tests := []struct {
+		markdown string
+		want     string
+	}{
+		{markdown: "![img-0.jpeg](img-0.jpeg)", want: "![img-0.jpeg](images/img-0.jpeg)"},
+		{markdown: "see ![Figure 2](img-1.jpeg) below", want: "see ![Figure 2](images/page-1.jpeg) below"},
+		{markdown: "![](img-0.jpeg)", want: "![](images/img-0.jpeg)"},
+		{markdown: "![img-0.jpeg](other.png)", want: "![img-0.jpeg](other.png)"},
+		{markdown: "[img-0.jpeg](img-0.jpeg)", want: "[img-0.jpeg](img-0.jpeg)"},
+	}
+	for _, tt := range tests {
+		if got := rewriteImageLinks(tt.markdown, imageMap); got != tt.want {
+			t.Errorf("rewriteImageLinks(%q) = %q，期望 %q", tt.markdown, got, tt.want)
+		}
+	}
+}
+
+// TestConvertJSONImageLinks 转换归档的响应JSON后，output.md中的图片链接指向images目录中保存的文件
+func TestConvertJSONImageLinks(t *testing.T) {
+	for _, name := range []string{"ocr_response.json", "metadata_raw_response.json"} {
+		t.Run(name, func(t *testing.T) {
+			result, err := newTestProcessor(&fakeBackend{}).ConvertJSONToMarkdown(filepath.Join("testdata", name), ProcessOptions{
+				OutputDir:     t.TempDir(),
+				IncludeImages: true,
+			})
+			if err != nil {
+				t.Fatalf("ConvertJSONToMarkdown 返回错误: %v", err)
+			}
+			output, err := os.ReadFile(filepath.Join(result.OutputDir, "output.md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []string{"img-0.jpeg", "img-1.jpeg"} {
+				if link := "![" + id + "](images/" + id + ")"; !strings.Contains(string(output), link) {
+					t.Errorf("output.md 中缺少 %q:\n%s", link, output)
+				}
+				if strings.Contains(string(output), "]("+id+")") {
+					t.Errorf("output.md 中仍有未改写的链接 %s", id)
+				}
+				if _, err := os.Stat(filepath.Join(result.OutputDir, "images", id)); err != nil {
+					t.Errorf("链接指向的图片不存在: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestLinkOnlyImageNamesMatchSavedFiles 只引用图片时，规范化后同名的图片链接与保存图片时写入的文件一致
+func TestLinkOnlyImageNamesMatchSavedFiles(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString(testPNG)
+	resp := &OCRResponse{Pages: []Page{
+		{Index: 0, Markdown: "![a/b.png](a/b.png)\n\n![a_b.png](a_b.png)", Images: []Image{{ID: "a/b.png", ImageBase64: data}, {ID: "a_b.png", ImageBase64: data}}},
+		{Index: 1, Markdown: "![a:b.png](a:b.png)", Images: []Image{{ID: "a:b.png", ImageBase64: data}}},
+	}}
+	processor := newTestProcessor(&fakeBackend{})
+
+	savedDir := t.TempDir()
+	if _, err := processor.saveResults(resp, savedDir, ProcessMetadata{}, ProcessOptions{SaveImages: true, LinkImages: true, KeepImagesInText: true}); err != nil {
+		t.Fatalf("保存图片时 saveResults 返回错误: %v", err)
+	}
+	linkedDir := t.TempDir()
+	if _, err := processor.saveResults(resp, linkedDir, ProcessMetadata{}, ProcessOptions{LinkImages: true, KeepImagesInText: true}); err != nil {
+		t.Fatalf("只引用图片时 saveResults 返回错误: %v", err)
+	}
+
+	saved, err := os.ReadFile(filepath.Join(savedDir, "output.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, err := os.ReadFile(filepath.Join(linkedDir, "output.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(linked) != string(saved) {
+		t.Errorf("只引用图片时的输出与保存图片时不同:\n%s\n---\n%s", linked, saved)
+	}
+	for _, name := range []string{"a_b.png", "a_b-1.png", "a_b-2.png"} {
+		if !strings.Contains(string(linked), "(images/"+name+")") {
+			t.Errorf("输出中没有指向 %s 的链接:\n%s", name, linked)
+		}
+		if _, err := os.Stat(filepath.Join(savedDir, "images", name)); err != nil {
+			t.Errorf("保存图片时没有写入 %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ocr
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// IndexDBDriver 索引数据库使用的 database/sql 驱动名称
+//
+// 本包不直接依赖SQLite驱动，使用 IndexDB 的程序需要导入注册该名称的纯Go驱动，
+// 例如 _ "modernc.org/sqlite" 或 _ "github.com/glebarez/go-sqlite"，无需cgo；命令行程序已导入 modernc.org/sqlite。
+const IndexDBDriver = "sqlite"
+
+// indexDBSchema 索引数据库的表结构，每个源文件一行，以源文件路径为主键
+const indexDBSchema = `CREATE TABLE IF NOT EXISTS documents (
+	source       TEXT PRIMARY KEY,
+	output_dir   TEXT NOT NULL,
+	pages        INTEGER NOT NULL,
+	images       INTEGER NOT NULL,
+	model        TEXT NOT NULL DEFAULT '',
+	processed_at TEXT NOT NULL,
+	sha256       TEXT NOT NULL DEFAULT ''
+)`
+
+// indexDBUpsert 插入一行记录，源文件已存在时更新该行，重复运行不会产生重复记录
+const indexDBUpsert = `INSERT INTO documents (source, output_dir, pages, images, model, processed_at, sha256)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(source) DO UPDATE SET
+	output_dir = excluded.output_dir,
+	pages = excluded.pages,
+	images = excluded.images,
+	model = excluded.model,
+	processed_at = excluded.processed_at,
+	sha256 = excluded.sha256`
+
+// indexDB 记录批量处理结果的SQLite索引数据库
+type indexDB struct {
+	db        *sql.DB
+	openFiles fileLimiter // 读取元数据时遵守的文件操作上限
+}
+
+// openIndexDB 打开或创建path处的索引数据库并确保表存在，读取元数据时遵守limit
+func openIndexDB(ctx context.Context, path string, limit fileLimiter) (*indexDB, error) {
+	if !slices.Contains(sql.Drivers(), IndexDBDriver) {
+		return nil, fmt.Errorf("未注册SQLite驱动 %q，请在程序中导入纯Go的SQLite驱动（如 modernc.org/sqlite）", IndexDBDriver)
+	}
+	db, err := sql.Open(IndexDBDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("打开索引数据库失败: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, indexDBSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建索引数据库表失败: %w", err)
+	}
+	return &indexDB{db: db, openFiles: limit}, nil
+}
+
+// record 写入或更新源文件对应的记录，模型和SHA-256从输出目录的metadata.json中读取
+func (d *indexDB) record(ctx context.Context, source string, result *ProcessResult) error {
+	var model, sum string
+	processedAt := result.ProcessedAt
+	if metadata, _, err := loadMetadata(result.OutputDir, d.openFiles); err == nil {
+		sum = metadata.SourceSHA256
+		if m, ok := metadata.OCRResponseInfo["model"].(string); ok {
+			model = m
+		}
+		if processedAt == "" {
+			processedAt = metadata.ProcessedAt
+		}
+	}
+	if processedAt == "" {
+		processedAt = time.Now().Format(time.RFC3339)
+	}
+
+	_, err := d.db.ExecContext(ctx, indexDBUpsert,
+		manifestKey(source), result.OutputDir, result.Pages, result.Images, model, processedAt, sum)
+	if err != nil {
+		return fmt.Errorf("写入索引数据库失败: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭索引数据库
+func (d *indexDB) Close() error {
+	return d.db.Close()
+}
@@ -0,0 +1,59 @@
+package ocr
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// TestProcessMultipleFilesIndexDB 每个处理成功的文件在索引数据库中有一行记录，重复运行时更新原有记录
+func TestProcessMultipleFilesIndexDB(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "a.pdf", "sub/b.pdf")
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+
+	for run := 1; run <= 2; run++ {
+		opts := ProcessOptions{
+			OutputDir: filepath.Join(t.TempDir(), "out"),
+			IndexDB:   dbPath,
+			NoImages:  true,
+		}
+		if _, err := newTestProcessor(&fakeBackend{pages: 3}).ProcessMultipleFiles(context.Background(), []string{inputDir}, opts); err != nil {
+			t.Fatalf("第%d次运行: ProcessMultipleFiles 返回错误: %v", run, err)
+		}
+
+		db, err := sql.Open(IndexDBDriver, dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := db.Query("SELECT source, output_dir, pages, images, model, sha256 FROM documents ORDER BY source")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sources []string
+		for rows.Next() {
+			var source, outputDir, model, sum string
+			var pages, images int
+			if err := rows.Scan(&source, &outputDir, &pages, &images, &model, &sum); err != nil {
+				t.Fatal(err)
+			}
+			sources = append(sources, source)
+			if filepath.Dir(outputDir) != opts.OutputDir {
+				t.Errorf("第%d次运行: %s 的输出目录 = %s，期望位于 %s", run, source, outputDir, opts.OutputDir)
+			}
+			if pages != 3 || model != "fake-ocr" || sum == "" {
+				t.Errorf("第%d次运行: %s 的记录 pages=%d model=%q sha256=%q", run, source, pages, model, sum)
+			}
+		}
+		rows.Close()
+		db.Close()
+
+		want := []string{filepath.Join(inputDir, "a.pdf"), filepath.Join(inputDir, "sub", "b.pdf")}
+		if len(sources) != len(want) || sources[0] != want[0] || sources[1] != want[1] {
+			t.Errorf("第%d次运行: 索引中的源文件 = %v，期望 %v", run, sources, want)
+		}
+	}
+}
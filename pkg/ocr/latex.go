@@ -0,0 +1,330 @@
+package ocr
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// OutputFormats 的可选值
+const (
+	OutputFormatLaTeX = "latex" // 将合并的markdown转换为独立的LaTeX文档 output.tex
+)
+
+// LaTeXFileName LaTeX输出的文件名
+const LaTeXFileName = "output.tex"
+
+// latexPreamble LaTeX文档的导言区，只引入数学公式、图片和链接需要的宏包
+const latexPreamble = `\documentclass{article}
+\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+\usepackage{amsmath,amssymb}
+\usepackage{graphicx}
+\usepackage{hyperref}
+
+\begin{document}
+
+`
+
+var (
+	// latexHeadingPattern 匹配markdown标题
+	latexHeadingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	// latexListPattern 匹配无序和有序列表项，嵌套列表按同一层处理
+	latexListPattern = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+(.*)$`)
+	// latexRulePattern 匹配水平分隔线
+	latexRulePattern = regexp.MustCompile(`^\s*(?:-{3,}|\*{3,}|_{3,})\s*$`)
+	// latexTableSeparatorPattern 匹配表格的表头分隔行，如 |---|:---:|
+	latexTableSeparatorPattern = regexp.MustCompile(`^\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?$`)
+	// latexImagePattern 匹配开头的markdown图片
+	latexImagePattern = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)\s]+)\)`)
+	// latexLinkPattern 匹配开头的markdown链接
+	latexLinkPattern = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// latexSectionCommands 各级标题对应的LaTeX命令，OCR文本中通常已包含原文的编号，因此使用不编号的形式
+var latexSectionCommands = []string{`\section*`, `\subsection*`, `\subsubsection*`, `\paragraph*`, `\subparagraph*`, `\subparagraph*`}
+
+// validateOutputFormats 校验 OutputFormats 中的取值
+func (o ProcessOptions) validateOutputFormats() error {
+	for _, format := range o.OutputFormats {
+		if format != OutputFormatLaTeX {
+			return fmt.Errorf("无效的输出格式: %s，可选值为 %s", format, OutputFormatLaTeX)
+		}
+	}
+	return nil
+}
+
+// wantsOutputFormat 判断是否需要额外生成指定格式的输出
+func (o ProcessOptions) wantsOutputFormat(format string) bool {
+	for _, f := range o.OutputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// markdownToLaTeX 将合并的markdown转换为独立的LaTeX文档
+//
+// 数学公式（$...$、$$...$$、\(...\)、\[...\]）原样保留，标题、列表、表格、粗体、斜体、
+// 代码和链接转换为对应的LaTeX命令，其余文本中的特殊字符会被转义。
+// images为markdown中图片链接到已保存的图片（相对于输出目录）的映射，这些图片通过 \includegraphics 插入，
+// 其他图片只保留替代文本。
+func markdownToLaTeX(markdown string, images map[string]string) string {
+	var b strings.Builder
+	b.WriteString(latexPreamble)
+
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	list := "" // 当前打开的列表环境
+	closeList := func() {
+		if list != "" {
+			fmt.Fprintf(&b, "\\end{%s}\n", list)
+			list = ""
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			closeList()
+			b.WriteString("\n")
+
+		case strings.HasPrefix(trimmed, "$$") || strings.HasPrefix(trimmed, `\[`):
+			// 行间公式，可能跨越多行，原样输出到结束标记为止
+			closeList()
+			open, end := "$$", "$$"
+			if strings.HasPrefix(trimmed, `\[`) {
+				open, end = `\[`, `\]`
+			}
+			b.WriteString(line + "\n")
+			if !strings.Contains(trimmed[len(open):], end) {
+				for i+1 < len(lines) {
+					i++
+					b.WriteString(lines[i] + "\n")
+					if strings.Contains(lines[i], end) {
+						break
+					}
+				}
+			}
+
+		case strings.HasPrefix(trimmed, "|"):
+			closeList()
+			start := i
+			for i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "|") {
+				i++
+			}
+			writeLaTeXTable(&b, lines[start:i+1], images)
+
+		case latexHeadingPattern.MatchString(trimmed):
+			closeList()
+			m := latexHeadingPattern.FindStringSubmatch(trimmed)
+			fmt.Fprintf(&b, "%s{%s}\n", latexSectionCommands[len(m[1])-1], latexInline(m[2], images))
+
+		case latexRulePattern.MatchString(trimmed):
+			closeList()
+			b.WriteString("\\bigskip\\hrule\\bigskip\n")
+
+		case latexListPattern.MatchString(line):
+			m := latexListPattern.FindStringSubmatch(line)
+			env := "itemize"
+			if m[1][0] >= '0' && m[1][0] <= '9' {
+				env = "enumerate"
+			}
+			if list != env {
+				closeList()
+				fmt.Fprintf(&b, "\\begin{%s}\n", env)
+				list = env
+			}
+			fmt.Fprintf(&b, "\\item %s\n", latexInline(m[2], images))
+
+		default:
+			closeList()
+			b.WriteString(latexInline(trimmed, images) + "\n")
+		}
+	}
+	closeList()
+
+	b.WriteString("\n\\end{document}\n")
+	return b.String()
+}
+
+// writeLaTeXTable 将markdown表格转换为tabular环境，跳过表头分隔行
+func writeLaTeXTable(b *strings.Builder, lines []string, images map[string]string) {
+	var rows [][]string
+	columns := 0
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if latexTableSeparatorPattern.MatchString(trimmed) {
+			continue
+		}
+		trimmed = strings.TrimSuffix(strings.TrimPrefix(trimmed, "|"), "|")
+		cells := splitTableRow(trimmed)
+		for i, cell := range cells {
+			cells[i] = latexInline(strings.TrimSpace(cell), images)
+		}
+		rows = append(rows, cells)
+		columns = max(columns, len(cells))
+	}
+	if columns == 0 {
+		return
+	}
+
+	b.WriteString("\\begin{center}\n")
+	fmt.Fprintf(b, "\\begin{tabular}{|%s}\n\\hline\n", strings.Repeat("l|", columns))
+	for _, cells := range rows {
+		for len(cells) < columns {
+			cells = append(cells, "")
+		}
+		b.WriteString(strings.Join(cells, " & ") + " \\\\\n\\hline\n")
+	}
+	b.WriteString("\\end{tabular}\n\\end{center}\n")
+}
+
+// splitTableRow 按 | 拆分表格行，忽略数学公式中和转义的 |
+func splitTableRow(row string) []string {
+	var cells []string
+	start := 0
+	for i := 0; i < len(row); i++ {
+		if n := latexMathLength(row[i:]); n > 0 {
+			i += n - 1
+			continue
+		}
+		switch {
+		case row[i] == '\\':
+			i++
+		case row[i] == '|':
+			cells = append(cells, row[start:i])
+			start = i + 1
+		}
+	}
+	return append(cells, row[start:])
+}
+
+// latexInline 转换一行中的行内markdown，数学公式原样保留，其他文本转义LaTeX特殊字符
+func latexInline(s string, images map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		rest := s[i:]
+
+		if n := latexMathLength(rest); n > 0 {
+			b.WriteString(rest[:n])
+			i += n
+			continue
+		}
+
+		// markdown的反斜杠转义，如 \* \_ \#
+		if rest[0] == '\\' && len(rest) > 1 && strings.IndexByte("\\`*_{}[]()#+-.!|$%&~^<>", rest[1]) >= 0 {
+			b.WriteString(latexEscape(rest[1:2]))
+			i += 2
+			continue
+		}
+
+		if m := latexImagePattern.FindStringSubmatch(rest); m != nil {
+			if local, ok := images[m[2]]; ok {
+				fmt.Fprintf(&b, "\\includegraphics[width=\\linewidth]{%s}", filepath.ToSlash(local))
+			} else if m[1] != "" {
+				b.WriteString("[" + latexEscape(m[1]) + "]")
+			}
+			i += len(m[0])
+			continue
+		}
+
+		if m := latexLinkPattern.FindStringSubmatch(rest); m != nil {
+			url := strings.NewReplacer(`\`, `\\`, "%", `\%`, "#", `\#`, "{", `\{`, "}", `\}`).Replace(m[2])
+			fmt.Fprintf(&b, "\\href{%s}{%s}", url, latexInline(m[1], images))
+			i += len(m[0])
+			continue
+		}
+
+		if inner, n := latexDelimited(rest, "**"); n > 0 {
+			fmt.Fprintf(&b, "\\textbf{%s}", latexInline(inner, images))
+			i += n
+			continue
+		}
+		if inner, n := latexDelimited(rest, "*"); n > 0 {
+			fmt.Fprintf(&b, "\\emph{%s}", latexInline(inner, images))
+			i += n
+			continue
+		}
+		if inner, n := latexDelimited(rest, "`"); n > 0 {
+			fmt.Fprintf(&b, "\\texttt{%s}", latexEscape(inner))
+			i += n
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(rest)
+		b.WriteString(latexEscape(rest[:size]))
+		i += size
+	}
+	return b.String()
+}
+
+// latexMathLength 返回s开头的数学公式的长度，不是公式时返回0
+//
+// 行内公式 $...$ 要求开始的 $ 后和结束的 $ 前不是空白，避免把金额误认为公式。
+func latexMathLength(s string) int {
+	for _, delims := range [][2]string{{"$$", "$$"}, {`\(`, `\)`}, {`\[`, `\]`}} {
+		if strings.HasPrefix(s, delims[0]) {
+			if end := strings.Index(s[len(delims[0]):], delims[1]); end >= 0 {
+				return len(delims[0]) + end + len(delims[1])
+			}
+			return 0
+		}
+	}
+	if len(s) < 3 || s[0] != '$' || s[1] == ' ' {
+		return 0
+	}
+	for j := 1; j < len(s); j++ {
+		if s[j] == '\\' {
+			j++
+			continue
+		}
+		if s[j] == '$' {
+			if s[j-1] == ' ' {
+				return 0
+			}
+			return j + 1
+		}
+	}
+	return 0
+}
+
+// latexDelimited 返回s开头被delim包围的内容及总长度，内容为空或以空白开头结尾时返回0
+func latexDelimited(s, delim string) (string, int) {
+	if !strings.HasPrefix(s, delim) {
+		return "", 0
+	}
+	end := strings.Index(s[len(delim):], delim)
+	if end <= 0 {
+		return "", 0
+	}
+	inner := s[len(delim) : len(delim)+end]
+	if strings.TrimSpace(inner) != inner {
+		return "", 0
+	}
+	return inner, len(delim) + end + len(delim)
+}
+
+// latexSpecialChars 转义LaTeX特殊字符
+var latexSpecialChars = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"$", `\$`,
+	"&", `\&`,
+	"#", `\#`,
+	"%", `\%`,
+	"_", `\_`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+)
+
+// latexEscape 转义文本中的LaTeX特殊字符
+func latexEscape(s string) string {
+	return latexSpecialChars.Replace(s)
+}
@@ -0,0 +1,105 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ModelInfo 表示模型列表中的单个模型
+type ModelInfo struct {
+	ID           string         `json:"id"`
+	Object       string         `json:"object"`
+	Created      int64          `json:"created"`
+	OwnedBy      string         `json:"owned_by"`
+	Aliases      []string       `json:"aliases,omitempty"`
+	Capabilities map[string]any `json:"capabilities,omitempty"`
+}
+
+// SupportsOCR 判断模型是否支持OCR，优先使用capabilities中的ocr字段，没有时根据模型ID判断
+func (m ModelInfo) SupportsOCR() bool {
+	if ocr, ok := m.Capabilities["ocr"].(bool); ok {
+		return ocr
+	}
+	return strings.Contains(strings.ToLower(m.ID), "ocr")
+}
+
+// modelListResponse 表示模型列表接口的响应
+type modelListResponse struct {
+	Object string      `json:"object"`
+	Data   []ModelInfo `json:"data"`
+}
+
+// ListModels 获取可用的模型列表，按模型ID排序
+//
+// 依次尝试配置的端点，认证失败时先在当前端点换用其他API密钥。
+func (c *Client) ListModels(ctx context.Context, apiKey string) ([]ModelInfo, error) {
+	c.mu.Lock()
+	baseURLs := append([]string(nil), c.baseURLs...)
+	c.mu.Unlock()
+	if len(baseURLs) == 0 {
+		baseURLs = []string{"https://api.mistral.ai/v1/"}
+	}
+
+	client := c.httpClient(c.httpTimeout)
+
+	var lastErr error
+	for _, baseURL := range baseURLs {
+		triedKeys := make(map[string]bool)
+		key := apiKey
+
+		for {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL(baseURL, c.paths.Models, nil), nil)
+			if err != nil {
+				return nil, fmt.Errorf("创建请求错误: %w", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+key)
+			req.Header.Set("Accept", "application/json")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				lastErr = fmt.Errorf("发送请求错误: %w", err)
+				break // 尝试下一个端点
+			}
+			bodyBytes, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				lastErr = fmt.Errorf("读取响应体错误: %w", err)
+				break
+			}
+
+			if resp.StatusCode == http.StatusOK {
+				var list modelListResponse
+				if err := json.Unmarshal(bodyBytes, &list); err != nil {
+					return nil, fmt.Errorf("解析响应错误: %w", err)
+				}
+				sort.Slice(list.Data, func(i, j int) bool {
+					return list.Data[i].ID < list.Data[j].ID
+				})
+				return list.Data, nil
+			}
+
+			lastErr = &APIError{Operation: "获取模型列表", StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+			if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+				break
+			}
+
+			// 认证错误，换用当前端点上尚未尝试的API密钥
+			triedKeys[key] = true
+			nextKey, ok := c.nextKeyAfter(key, triedKeys)
+			if !ok {
+				break
+			}
+			key = nextKey
+		}
+	}
+
+	return nil, lastErr
+}
@@ -0,0 +1,161 @@
+package ocr
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ManifestFileName 批量处理清单的文件名，保存在输出目录下
+const ManifestFileName = "manifest.json"
+
+// 清单中文件的处理状态
+const (
+	ManifestStatusPending   = "pending"   // 尚未处理
+	ManifestStatusProcessed = "processed" // 处理成功
+	ManifestStatusSkipped   = "skipped"   // 输出已存在，跳过处理
+	ManifestStatusFailed    = "failed"    // 处理失败
+)
+
+// BatchManifest 记录一次批量处理中每个文件的处理状态
+type BatchManifest struct {
+	StartedAt   string          `json:"started_at"`  // 开始时间
+	FinishedAt  string          `json:"finished_at"` // 结束时间
+	Interrupted bool            `json:"interrupted"` // 是否被中断
+	Files       []ManifestEntry `json:"files"`       // 文件列表
+}
+
+// ManifestEntry 表示清单中的单个文件
+type ManifestEntry struct {
+	Path      string        `json:"path"`                 // 源文件路径
+	RelPath   string        `json:"rel_path,omitempty"`   // 相对于批次根目录（所在的输入目录，直接指定的文件为其所在目录）的路径，使用 / 分隔
+	Status    string        `json:"status"`               // 处理状态
+	Resumed   bool          `json:"resumed,omitempty"`    // 从之前的清单恢复，本次没有处理
+	OutputDir string        `json:"output_dir,omitempty"` // 输出目录
+	Pages     int           `json:"pages,omitempty"`      // 处理的页数
+	Images    int           `json:"images,omitempty"`     // 保存的图片数量
+	Error     string        `json:"error,omitempty"`      // 错误信息
+	Timings   *PhaseTimings `json:"timings,omitempty"`    // 各阶段的耗时（处理成功时）
+}
+
+// newBatchManifest 为待处理文件创建清单，所有文件初始状态为pending，roots为每个文件所属的批次根目录
+func newBatchManifest(files []string, roots map[string]string) *BatchManifest {
+	manifest := &BatchManifest{
+		StartedAt: time.Now().Format(time.RFC3339),
+		Files:     make([]ManifestEntry, len(files)),
+	}
+	for i, file := range files {
+		manifest.Files[i] = ManifestEntry{Path: file, RelPath: batchRelPath(roots[file], file), Status: ManifestStatusPending}
+	}
+	return manifest
+}
+
+// writeManifest 将清单写入输出目录
+func (p *Processor) writeManifest(opts ProcessOptions, manifest *BatchManifest) {
+	outputDir := opts.OutputDir
+	manifest.FinishedAt = time.Now().Format(time.RFC3339)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		p.logger.Warn("生成清单失败", zap.Error(err))
+		return
+	}
+
+	if err := opts.mkdirAll(outputDir); err != nil {
+		p.logger.Warn("创建输出目录失败", zap.String("outputDir", outputDir), zap.Error(err))
+		return
+	}
+
+	manifestPath := filepath.Join(outputDir, ManifestFileName)
+	if err := opts.writeFile(manifestPath, data); err != nil {
+		p.logger.Warn("写入清单文件失败", zap.String("path", manifestPath), zap.Error(err))
+		return
+	}
+	p.logger.Info("保存了批量处理清单", zap.String("path", manifestPath))
+}
+
+// LoadManifest 读取批量处理清单
+func LoadManifest(path string) (*BatchManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取清单文件失败: %w", err)
+	}
+
+	var manifest BatchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析清单文件失败: %w", err)
+	}
+	return &manifest, nil
+}
+
+// completedSet 清单中已完成（处理成功或跳过）的文件，分别按绝对路径和相对于批次根目录的路径索引
+type completedSet struct {
+	byPath map[string]ManifestEntry
+	byRel  map[string]ManifestEntry
+}
+
+// completedFiles 返回清单中已完成的文件
+//
+// 多个已完成的文件相对路径相同（来自不同的输入目录）时，无法按相对路径区分，这些相对路径不参与匹配。
+func (m *BatchManifest) completedFiles() *completedSet {
+	set := &completedSet{byPath: make(map[string]ManifestEntry), byRel: make(map[string]ManifestEntry)}
+	ambiguous := make(map[string]bool)
+	for _, entry := range m.Files {
+		if entry.Status != ManifestStatusProcessed && entry.Status != ManifestStatusSkipped {
+			continue
+		}
+		set.byPath[manifestKey(entry.Path)] = entry
+		if entry.RelPath == "" {
+			continue
+		}
+		if _, ok := set.byRel[entry.RelPath]; ok {
+			ambiguous[entry.RelPath] = true
+		}
+		set.byRel[entry.RelPath] = entry
+	}
+	for rel := range ambiguous {
+		delete(set.byRel, rel)
+	}
+	return set
+}
+
+// len 返回已完成的文件数
+func (s *completedSet) len() int {
+	return len(s.byPath)
+}
+
+// lookup 查找文件是否已完成，先按绝对路径匹配，再按相对于批次根目录的路径匹配，
+// 输入目录移动到其他位置或其他机器上时仍能找到
+func (s *completedSet) lookup(path, relPath string) (ManifestEntry, bool) {
+	if entry, ok := s.byPath[manifestKey(path)]; ok {
+		return entry, true
+	}
+	if relPath == "" {
+		return ManifestEntry{}, false
+	}
+	entry, ok := s.byRel[relPath]
+	return entry, ok
+}
+
+// batchRelPath 返回文件相对于批次根目录的路径，使用 / 分隔，无法计算时返回文件名
+func batchRelPath(root, path string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(path)
+}
+
+// manifestKey 返回用于比较清单路径的键，能取得绝对路径时使用绝对路径
+func manifestKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
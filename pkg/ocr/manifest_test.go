@@ -0,0 +1,122 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestResumeManifestMovedInput 输入目录移动到其他位置后，按相对路径匹配清单中已完成的文件，恢复的文件不计为本次成功
+func TestResumeManifestMovedInput(t *testing.T) {
+	firstInput := filepath.Join(t.TempDir(), "scans")
+	writeTestPDFs(t, firstInput, "a.pdf", "sub/b.pdf")
+
+	var firstSummary *BatchSummary
+	_, err := newTestProcessor(&fakeBackend{pages: 2}).ProcessMultipleFiles(context.Background(), []string{firstInput}, ProcessOptions{
+		OutputDir:   filepath.Join(t.TempDir(), "out"),
+		NoImages:    true,
+		OnBatchDone: func(s *BatchSummary) { firstSummary = s },
+	})
+	if err != nil {
+		t.Fatalf("第一次处理返回错误: %v", err)
+	}
+	manifestPath := filepath.Join(t.TempDir(), ManifestFileName)
+	manifest := &BatchManifest{Files: firstSummary.Files}
+	for _, entry := range manifest.Files {
+		if entry.RelPath == "" {
+			t.Errorf("清单中 %s 没有记录相对路径", entry.Path)
+		}
+	}
+	writeTestManifest(t, manifestPath, manifest)
+
+	// 模拟在另一台机器上：输入目录位于不同的位置，并新增了一个文件
+	secondInput := filepath.Join(t.TempDir(), "elsewhere", "scans")
+	writeTestPDFs(t, secondInput, "a.pdf", "sub/b.pdf", "c.pdf")
+
+	backend := &fakeBackend{pages: 3}
+	var summary *BatchSummary
+	results, err := newTestProcessor(backend).ProcessMultipleFiles(context.Background(), []string{secondInput}, ProcessOptions{
+		OutputDir:      filepath.Join(t.TempDir(), "out"),
+		NoImages:       true,
+		ResumeManifest: manifestPath,
+		OnBatchDone:    func(s *BatchSummary) { summary = s },
+	})
+	if err != nil {
+		t.Fatalf("恢复处理返回错误: %v", err)
+	}
+	if !reflect.DeepEqual(backend.uploads, []string{"c.pdf"}) {
+		t.Errorf("上传了 %v，期望只上传新增的 c.pdf", backend.uploads)
+	}
+	if len(results) != 1 {
+		t.Errorf("返回了 %d 个结果，期望 1 个", len(results))
+	}
+	if summary.TotalFiles != 3 || summary.Succeeded != 1 || summary.Resumed != 2 || summary.Skipped != 0 {
+		t.Errorf("摘要: 共 %d 个，成功 %d，恢复 %d，跳过 %d，期望共 3 个，成功 1，恢复 2，跳过 0",
+			summary.TotalFiles, summary.Succeeded, summary.Resumed, summary.Skipped)
+	}
+	if summary.TotalPages != 3 {
+		t.Errorf("总页数 = %d，期望只计入本次处理的 3 页", summary.TotalPages)
+	}
+
+	// 再次从新的清单恢复时，之前恢复的文件仍视为已完成
+	writeTestManifest(t, manifestPath, &BatchManifest{Files: summary.Files})
+	backend = &fakeBackend{pages: 1}
+	if _, err := newTestProcessor(backend).ProcessMultipleFiles(context.Background(), []string{secondInput}, ProcessOptions{
+		OutputDir:      filepath.Join(t.TempDir(), "out"),
+		NoImages:       true,
+		ResumeManifest: manifestPath,
+		OnBatchDone:    func(s *BatchSummary) { summary = s },
+	}); err != nil {
+		t.Fatalf("第二次恢复处理返回错误: %v", err)
+	}
+	if len(backend.uploads) != 0 {
+		t.Errorf("第二次恢复时上传了 %v", backend.uploads)
+	}
+}
+
+func TestCompletedFilesLookup(t *testing.T) {
+	manifest := &BatchManifest{Files: []ManifestEntry{
+		{Path: "/data/a/x.pdf", RelPath: "x.pdf", Status: ManifestStatusProcessed},
+		{Path: "/data/b/x.pdf", RelPath: "x.pdf", Status: ManifestStatusProcessed},
+		{Path: "/data/a/y.pdf", RelPath: "sub/y.pdf", Status: ManifestStatusSkipped},
+		{Path: "/data/a/z.pdf", RelPath: "z.pdf", Status: ManifestStatusFailed},
+		{Path: "/data/a/old.pdf", Status: ManifestStatusProcessed},
+	}}
+	completed := manifest.completedFiles()
+
+	tests := []struct {
+		name    string
+		path    string
+		relPath string
+		want    bool
+	}{
+		{name: "绝对路径相同", path: "/data/b/x.pdf", relPath: "x.pdf", want: true},
+		{name: "相对路径不唯一时不按相对路径匹配", path: "/mnt/x.pdf", relPath: "x.pdf", want: false},
+		{name: "按相对路径匹配跳过的文件", path: "/mnt/sub/y.pdf", relPath: "sub/y.pdf", want: true},
+		{name: "失败的文件不算完成", path: "/mnt/z.pdf", relPath: "z.pdf", want: false},
+		{name: "没有相对路径的旧清单按绝对路径匹配", path: "/data/a/old.pdf", relPath: "old.pdf", want: true},
+		{name: "没有相对路径的旧清单不按相对路径匹配", path: "/mnt/old.pdf", relPath: "old.pdf", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := completed.lookup(tt.path, tt.relPath); got != tt.want {
+				t.Errorf("lookup(%q, %q) = %v，期望 %v", tt.path, tt.relPath, got, tt.want)
+			}
+		})
+	}
+}
+
+// writeTestManifest 将清单写入path
+func writeTestManifest(t *testing.T, path string, manifest *BatchManifest) {
+	t.Helper()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,90 @@
+package ocr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MarkdownTemplateData 表示markdown模板中可用的字段
+//
+// 例如以下模板为Hugo生成带front matter的output.md：
+//
+//	---
+//	title: {{printf "%q" .Name}}
+//	date: {{.Date}}
+//	pages: {{.PageCount}}
+//	---
+//
+//	{{range .Pages}}<!-- 第 {{.Number}} 页 -->
+//	{{.Markdown}}
+//
+//	{{end}}
+type MarkdownTemplateData struct {
+	Name      string                 // 文档名称：源文件名（不含扩展名），处理URL时为输出目录名
+	Source    string                 // 源文件路径或URL
+	Date      string                 // 处理日期，格式为 2006-01-02
+	PageCount int                    // 合并输出中的页数，不含因文本过短移除的页面
+	Pages     []MarkdownTemplatePage // 合并输出中的各页
+	Content   string                 // 未设置模板时output.md的内容，各页之间以空行分隔
+}
+
+// MarkdownTemplatePage 表示markdown模板中的单个页面
+type MarkdownTemplatePage struct {
+	Index    int    // OCR响应中的页面索引（从0开始）
+	Number   int    // 页码（Index+1）
+	Markdown string // 按图片选项改写链接并完成规范化和自定义处理后的markdown
+}
+
+// validateMarkdownTemplate 在开始处理前读取并解析 MarkdownTemplate，避免处理完成后才发现模板错误
+func (o ProcessOptions) validateMarkdownTemplate() error {
+	if o.MarkdownTemplate == "" {
+		return nil
+	}
+	_, err := o.loadMarkdownTemplate()
+	return err
+}
+
+// loadMarkdownTemplate 读取并解析 MarkdownTemplate 指定的模板文件
+func (o ProcessOptions) loadMarkdownTemplate() (*template.Template, error) {
+	data, err := os.ReadFile(o.MarkdownTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("读取markdown模板失败: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(o.MarkdownTemplate)).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("解析markdown模板失败: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderMarkdownTemplate 按 MarkdownTemplate 生成output.md的内容，未设置模板时直接返回content
+func (o ProcessOptions) renderMarkdownTemplate(content string, pages []MarkdownTemplatePage, metadata ProcessMetadata) (string, error) {
+	if o.MarkdownTemplate == "" {
+		return content, nil
+	}
+	tmpl, err := o.loadMarkdownTemplate()
+	if err != nil {
+		return "", err
+	}
+
+	name := sourceBaseName(metadata.SourcePath)
+	if metadata.SourceType == "url" || name == "" {
+		name = filepath.Base(metadata.OutputDir)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, MarkdownTemplateData{
+		Name:      name,
+		Source:    metadata.SourcePath,
+		Date:      time.Now().Format("2006-01-02"),
+		PageCount: len(pages),
+		Pages:     pages,
+		Content:   content,
+	}); err != nil {
+		return "", fmt.Errorf("生成markdown输出失败: %w", err)
+	}
+	return b.String(), nil
+}
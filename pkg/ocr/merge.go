@@ -0,0 +1,210 @@
+package ocr
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// MergeResult 表示合并多个输出目录的结果
+type MergeResult struct {
+	OutputPath string   // 合并后的markdown文件路径
+	Documents  []string // 按合并顺序排列的文档输出目录
+	Images     int      // 复制到合并目录的图片数量
+}
+
+// mergeDocument 表示参与合并的单个文档输出目录
+type mergeDocument struct {
+	dir   string
+	title string
+}
+
+// MergeOutputs 将多个输出目录中的 output.md 和图片合并到 outDir，不调用API
+//
+// dirs 中的每一项可以是单个文档的输出目录（包含 output.md），也可以是批量处理的输出根目录，
+// 此时按名称顺序合并其中包含 output.md 的子目录。每个文档的图片复制到 images/<文档目录名>/，
+// 不同来源的同名图片不会互相覆盖，markdown中的图片链接改写为新的路径。
+// 合并后的 output.md 开头是根据所有文档标题生成的目录，每个文档以来源命名的一级标题开始，
+// 文档中原有的标题降低一级，使目录按文档嵌套。
+func (p *Processor) MergeOutputs(dirs []string, outDir string, opts ProcessOptions) (*MergeResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts.openFiles = p.openFiles
+	if outDir == "" {
+		return nil, fmt.Errorf("未指定合并输出目录")
+	}
+
+	var documents []mergeDocument
+	for _, dir := range dirs {
+		found, err := p.findMergeDocuments(dir, outDir)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, found...)
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("没有找到包含 output.md 的输出目录")
+	}
+
+	if err := opts.mkdirAll(outDir); err != nil {
+		return nil, fmt.Errorf("创建合并输出目录错误: %w", err)
+	}
+
+	result := &MergeResult{OutputPath: filepath.Join(outDir, "output.md")}
+	usedNamespaces := make(map[string]bool)
+	var merged strings.Builder
+	for _, doc := range documents {
+		data, err := p.openFiles.readFile(filepath.Join(doc.dir, "output.md"))
+		if err != nil {
+			return nil, fmt.Errorf("读取markdown文件失败: %w", err)
+		}
+		markdown := string(bytes.TrimPrefix(data, utf8BOM))
+
+		// 以文档目录名作为图片子目录，多个批次中的同名目录添加序号区分
+		namespace := uniqueFilename(sanitizeImageFilename(filepath.Base(doc.dir)), usedNamespaces)
+		markdown, copied := p.mergeImages(markdown, doc.dir, outDir, namespace, opts)
+		result.Images += copied
+
+		if merged.Len() > 0 {
+			merged.WriteString("\n---\n\n")
+		}
+		fmt.Fprintf(&merged, "# %s\n\n%s\n", doc.title, strings.TrimRight(demoteHeadings(markdown), "\n"))
+		result.Documents = append(result.Documents, doc.dir)
+		p.logger.Debug("已合并输出目录", zap.String("dir", doc.dir), zap.String("title", doc.title), zap.Int("images", copied))
+	}
+
+	// 目录中的链接指向同一文件内的锚点
+	content := merged.String()
+	if toc := buildTOC(content, ""); toc != "" {
+		content = toc + "\n" + content
+	}
+	if err := opts.writeFile(result.OutputPath, opts.withBOM([]byte(content))); err != nil {
+		return nil, fmt.Errorf("保存合并的markdown错误: %w", err)
+	}
+
+	p.logger.Info("合并完成",
+		zap.String("output", result.OutputPath), zap.Int("documents", len(result.Documents)), zap.Int("images", result.Images))
+	return result, nil
+}
+
+// findMergeDocuments 返回 dir 中参与合并的文档输出目录，跳过合并输出目录本身
+func (p *Processor) findMergeDocuments(dir, outDir string) ([]mergeDocument, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("获取目录信息失败: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s 不是目录", dir)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "output.md")); err == nil {
+		if sameDir(dir, outDir) {
+			return nil, fmt.Errorf("合并输出目录不能是参与合并的输出目录: %s", dir)
+		}
+		return []mergeDocument{{dir: dir, title: p.mergeTitle(dir)}}, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取目录失败: %w", err)
+	}
+	var documents []mergeDocument
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		subDir := filepath.Join(dir, entry.Name())
+		if sameDir(subDir, outDir) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(subDir, "output.md")); err != nil {
+			continue
+		}
+		documents = append(documents, mergeDocument{dir: subDir, title: p.mergeTitle(subDir)})
+	}
+	if len(documents) == 0 {
+		p.logger.Warn("目录中没有包含 output.md 的输出目录", zap.String("dir", dir))
+	}
+	return documents, nil
+}
+
+// mergeTitle 返回文档在合并输出中的标题，与 AppendTo 相同，优先使用元数据中的源文件名
+func (p *Processor) mergeTitle(dir string) string {
+	if metadata, _, err := loadMetadata(dir, p.openFiles); err == nil {
+		if title := sourceBaseName(metadata.SourcePath); metadata.SourceType != "url" && title != "" {
+			return title
+		}
+	}
+	if absDir, err := filepath.Abs(dir); err == nil {
+		return filepath.Base(absDir)
+	}
+	return filepath.Base(dir)
+}
+
+// demoteHeadings 将markdown中的ATX标题降低一级，六级标题保持不变，忽略代码块中的内容
+func demoteHeadings(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if match := headingPattern.FindStringSubmatch(strings.TrimRight(line, "\r")); match != nil && len(match[1]) < 6 {
+			lines[i] = "#" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mergeImages 将markdown引用的本地图片复制到合并目录的 images/<namespace>/，返回改写链接后的markdown和复制的图片数
+//
+// 只处理指向文档目录内已存在文件的相对链接，网址、绝对路径和缺失的图片保持原样。
+func (p *Processor) mergeImages(markdown, docDir, outDir, namespace string, opts ProcessOptions) (string, int) {
+	imagesDir := filepath.Join(outDir, "images", namespace)
+	linkMap := make(map[string]string)
+	usedFilenames := make(map[string]bool)
+
+	for _, match := range markdownImagePattern.FindAllStringSubmatch(markdown, -1) {
+		link := match[2]
+		if _, ok := linkMap[link]; ok {
+			continue
+		}
+		if u, err := url.Parse(link); err != nil || u.Scheme != "" || strings.HasPrefix(link, "/") {
+			continue
+		}
+		relPath := filepath.FromSlash(link)
+		if !filepath.IsLocal(relPath) {
+			continue
+		}
+		src := filepath.Join(docDir, relPath)
+		if info, err := os.Stat(src); err != nil || info.IsDir() {
+			continue
+		}
+
+		if len(linkMap) == 0 {
+			if err := opts.mkdirAll(imagesDir); err != nil {
+				p.logger.Warn("创建合并images目录失败", zap.String("dir", imagesDir), zap.Error(err))
+				return markdown, 0
+			}
+		}
+		name := uniqueFilename(filepath.Base(relPath), usedFilenames)
+		if err := opts.copyFile(src, filepath.Join(imagesDir, name)); err != nil {
+			p.logger.Warn("复制图片到合并目录失败", zap.String("image", src), zap.Error(err))
+			continue
+		}
+		linkMap[link] = "images/" + namespace + "/" + name
+	}
+
+	return rewriteImageLinks(markdown, linkMap), len(linkMap)
+}
@@ -0,0 +1,56 @@
+package ocr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MetadataFileName 元数据文件的文件名
+const MetadataFileName = "metadata.json"
+
+// LoadMetadata 读取输出目录中的metadata.json
+//
+// 文件中包含 ProcessMetadata 未定义的字段时仍会返回解析结果，同时返回这些字段对应的警告，
+// 便于发现由其他版本生成或被手动修改过的元数据。
+func LoadMetadata(outputDir string) (*ProcessMetadata, []string, error) {
+	return loadMetadata(outputDir, nil)
+}
+
+// loadMetadata 在文件操作上限内读取输出目录中的metadata.json，见 LoadMetadata
+func loadMetadata(outputDir string, limit fileLimiter) (*ProcessMetadata, []string, error) {
+	data, err := limit.readFile(filepath.Join(outputDir, MetadataFileName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取元数据文件失败: %w", err)
+	}
+
+	var warnings []string
+	var metadata ProcessMetadata
+
+	// 先严格解析以发现未知字段，失败时再宽松解析
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&metadata); err != nil {
+		metadata = ProcessMetadata{}
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return nil, nil, fmt.Errorf("解析元数据文件失败: %w", err)
+		}
+		warnings = append(warnings, fmt.Sprintf("元数据与当前版本的格式不完全一致: %v", err))
+	}
+
+	return &metadata, warnings, nil
+}
+
+// sameDir 判断两个路径是否指向同一个目录，路径不存在时按绝对路径比较
+func sameDir(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA == nil && errB == nil {
+		return os.SameFile(infoA, infoB)
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,43 @@
+package ocr
+
+import "time"
+
+// MetricsCollector 接收客户端API请求的指标，用于对接Prometheus等监控系统，见 pkg/metrics
+//
+// op为请求类型：upload（上传文件）、signed_url（获取签名URL）或 ocr（OCR处理）。
+// 方法可能被多个goroutine同时调用，实现需要保证并发安全。
+type MetricsCollector interface {
+	// ObserveRequest 每发送一次HTTP请求后调用。statusCode为响应状态码，请求未收到响应时为0；
+	// latency为从发送请求到读取完响应体的耗时
+	ObserveRequest(op, endpoint string, statusCode int, latency time.Duration)
+	// ObserveRetry 在同一端点上重试请求前调用，更换API密钥不计为重试
+	ObserveRetry(op, endpoint string)
+	// ObserveFailover 换用其他端点重新发送请求前调用
+	ObserveFailover(op, from, to string)
+}
+
+// SetMetricsCollector 设置接收API请求指标的收集器，为nil时不收集指标
+func (c *Client) SetMetricsCollector(collector MetricsCollector) {
+	c.metrics = collector
+}
+
+// observeRequest 向收集器报告一次请求，start为发送请求的时间
+func (c *Client) observeRequest(op, endpoint string, statusCode int, start time.Time) {
+	if c.metrics != nil {
+		c.metrics.ObserveRequest(op, endpoint, statusCode, time.Since(start))
+	}
+}
+
+// observeRetry 向收集器报告一次在同一端点上的重试
+func (c *Client) observeRetry(op, endpoint string) {
+	if c.metrics != nil {
+		c.metrics.ObserveRetry(op, endpoint)
+	}
+}
+
+// observeFailover 向收集器报告一次端点切换，from为空表示这是第一个端点，不报告
+func (c *Client) observeFailover(op, from, to string) {
+	if c.metrics != nil && from != "" {
+		c.metrics.ObserveFailover(op, from, to)
+	}
+}
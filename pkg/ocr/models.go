@@ -1,25 +1,54 @@
 package ocr
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
 
 // OCRResponse 表示Mistral OCR API的响应
 type OCRResponse struct {
-	Pages     []Page `json:"pages"`
-	Model     string `json:"model"`
-	UsageInfo struct {
-		PagesProcessed int  `json:"pages_processed"`
-		DocSizeBytes   *int `json:"doc_size_bytes"`
-	} `json:"usage_info"`
+	Pages     []Page    `json:"pages"`
+	Model     string    `json:"model"`
+	UsageInfo UsageInfo `json:"usage_info"`
+
+	// 文档级结构化标注（请求了document_annotation_format时返回，内容通常为JSON字符串）
+	DocumentAnnotation *string `json:"document_annotation,omitempty"`
 
 	// 原始响应数据，用于保存
 	RawResponse []byte `json:"-"`
+
+	// 流式解析时已保存的图片（图片ID到相对路径）和写入输出目录的原始响应文件
+	streamedImages  map[string]string
+	streamedDedup   *imageDedup
+	streamedFailed  []string
+	rawResponseFile string
+}
+
+// RawOCREnvelope 表示内嵌原始OCR响应的JSON结构，例如 metadata.json 中的 {"raw_response": {"pages": [...]}}
+type RawOCREnvelope struct {
+	RawResponse     *OCRResponse `json:"raw_response"`
+	RawResponseFile string       `json:"raw_response_file"`
+}
+
+// RawResponseFileName 单独保存原始响应时使用的文件名
+const RawResponseFileName = "response.json"
+
+// UsageInfo 表示OCR响应中的用量信息
+type UsageInfo struct {
+	PagesProcessed int  `json:"pages_processed"`
+	DocSizeBytes   *int `json:"doc_size_bytes"`
 }
 
 // Page 表示OCR响应中的单个页面
 type Page struct {
-	Index      int     `json:"index"`
-	Markdown   string  `json:"markdown"`
-	Images     []Image `json:"images"`
+	Index      int               `json:"index"`
+	Markdown   string            `json:"markdown"`
+	Images     []Image           `json:"images"`
+	Tables     []json.RawMessage `json:"tables,omitempty"`     // 结构化表格（新版API可选返回）
+	Words      []Word            `json:"words,omitempty"`      // 单词及其位置和置信度（API返回单词级信息时才有）
+	Confidence *float64          `json:"confidence,omitempty"` // 页面整体的识别置信度（API返回时才有）
 	Dimensions struct {
 		DPI    int `json:"dpi"`
 		Height int `json:"height"`
@@ -35,6 +64,9 @@ type Image struct {
 	BottomRightX int    `json:"bottom_right_x"`
 	BottomRightY int    `json:"bottom_right_y"`
 	ImageBase64  string `json:"image_base64"`
+
+	// 图片的结构化标注（请求了bbox_annotation_format时返回）
+	ImageAnnotation *string `json:"image_annotation,omitempty"`
 }
 
 // UploadResponse 表示上传文件时的响应
@@ -60,28 +92,209 @@ type ProcessResult struct {
 	ImagesDir    string
 	MetadataPath string
 	Pages        int
+	Images       int // 保存的图片数量
 	ProcessedAt  string
+	Usage        UsageInfo    // 本次API调用的用量（使用缓存或跳过处理时为空）
+	Attempts     AttemptStats // 各API请求的尝试次数和使用的端点（使用缓存或跳过处理时为空）
+	PageResults  []PageResult // 每页的内容，仅在设置 ReturnContent 时填充，跳过处理时为nil
+	Timings      PhaseTimings // 上传、获取签名URL、OCR和保存各阶段的耗时（跳过处理时为空）
+}
+
+// PageResult 表示单个页面的处理结果，内容与写入输出文件的内容一致
+type PageResult struct {
+	Index    int      // OCR响应中的页面索引（从0开始）
+	Markdown string   // 按图片选项改写链接并完成规范化和自定义处理后的markdown
+	Text     string   // 从markdown提取的文本
+	Images   []string // 页面中图片的引用：已保存或链接到本地时为相对输出目录的路径，否则为图片ID
+	Omitted  bool     // 是否因文本过短（MinPageTextLength）未加入合并输出
+}
+
+// UsageSummary 汇总多个文件的API用量
+type UsageSummary struct {
+	Files          int   // 产生API用量的文件数
+	PagesProcessed int   // 处理的总页数
+	DocSizeBytes   int64 // 文档总大小（字节）
 }
 
 // ProcessOptions 表示处理选项
+//
+// 图片相关的选项：
+//   - IncludeImages 为兼容旧版本的简写，等同于同时设置 SaveImages、LinkImages 和 KeepImagesInText
+//   - SaveImages 请求图片数据并保存到 images 子目录
+//   - LinkImages 将markdown中的图片链接改写为 images/ 下的本地路径，不保存图片时可引用之前已下载的图片
+//   - KeepImagesInText 在markdown中保留图片链接，为false时移除所有图片链接，得到纯文本内容
+//
+// 常见组合：只设置 SaveImages 时保存图片并从 output.md 中移除图片链接；
+// 设置 LinkImages 和 KeepImagesInText 时引用本地图片而不重新下载。
+// 四个选项都未设置时与旧版本行为一致，不保存图片，图片链接原样保留。
 type ProcessOptions struct {
-	IncludeImages    bool
-	OutputDir        string
-	CustomOutputName string
-	ContinueOnError  bool // 当处理多个文件时，如果一个文件处理失败，是否继续处理其他文件
+	IncludeImages        bool
+	SaveImages           bool // 是否保存图片
+	LinkImages           bool // 是否将图片链接改写为本地路径
+	NoImages             bool // 不请求、不保存图片，并从markdown中移除所有图片链接，优先于其他图片选项
+	KeepImagesInText     bool // 是否在markdown中保留图片链接
+	OutputDir            string
+	CustomOutputName     string
+	OutputNameTemplate   string            // 未指定CustomOutputName时生成输出名称的模板，可用字段见 OutputNameData
+	ContinueOnError      bool              // 当处理多个文件时，如果一个文件处理失败，是否继续处理其他文件
+	SplitPages           bool              // 是否额外将每页保存为单独的markdown文件（page-N.md），并生成index.md
+	CacheDir             string            // OCR响应缓存目录，为空时不使用缓存
+	ForceImageURL        bool              // 处理URL时强制按图片（image_url）处理，用于扩展名无法判断的情况
+	MinPageTextLength    int               // 文本长度（字符数）低于该值的页面不加入合并输出，0表示不过滤
+	FallbackToUpload     bool              // 处理URL时，如果API无法访问该URL，则在本地下载后上传处理
+	SeparateRawResponse  bool              // 将原始响应单独保存到response.json，而不是内嵌在metadata.json中
+	GenerateTOC          bool              // 根据合并输出中的标题生成目录文件toc.md
+	KeepUpload           bool              // 以info级别输出上传文件的ID和签名URL，用于排查OCR质量问题
+	AppendTo             string            // 将每个文档的markdown追加到该文件中，图片复制到其所在目录的images子目录；各文档的输出目录仍会生成
+	ModifiedSince        time.Time         // 扫描目录时跳过修改时间早于该时间的文件，零值表示不过滤
+	FileMode             os.FileMode       // 输出文件的权限，0表示使用 DefaultFileMode（0644）
+	DirMode              os.FileMode       // 输出目录的权限，0表示使用 DefaultDirMode（0755）
+	DehyphenateText      bool              // 合并output.txt中因排版在行尾用连字符断开的单词，保留真正的复合词
+	StreamLargeResponses bool              // 流式解析OCR响应，图片在解析时直接写入磁盘，原始响应保存到response.json而不保留在内存中
+	NormalizeUnicode     bool              // 对每页markdown进行NFC规范化，合并组合字符，并将不换行空格替换为普通空格
+	FullWidthToHalfWidth bool              // 将每页markdown中的全角字母和数字转换为半角，全角标点保持不变
+	ResumeManifest       string            // 批量处理时读取该清单，跳过其中已处理成功或跳过的文件，即使其输出目录或输入目录已被移走（按相对于输入目录的路径匹配）
+	BundleImages         string            // 保存图片后额外合并：BundleImagesPDF 按页面顺序生成 images.pdf，空或 BundleImagesNone 不合并
+	AtomicOutput         bool              // 先在本地临时目录中生成所有输出，完成后再移动到输出目录，适用于NFS/SMB等网络文件系统
+	CorpusFile           string            // 将每个文档提取的文本追加到该文件，文档前添加 "---DOC: <名称>---" 分隔行，用于构建检索语料；output.txt仍会生成
+	SkipTextPDFs         bool              // 批量处理时跳过已包含文本层的PDF（见 ProbePDFTextLayer），这类文件直接提取文本即可，不必OCR
+	ReturnContent        bool              // 在结果的 PageResults 中返回每页的markdown、文本和图片引用，默认只写入文件以节省内存
+	PDFPassword          string            // 加密PDF的密码（用户密码或所有者密码），设置后在本地解密再上传，见 DecryptPDF
+	PDFPasswords         map[string]string // 批量处理时按文件指定密码，键为文件路径或文件名，优先于 PDFPassword
+	OutputBOM            bool              // 在output.md和output.txt开头添加UTF-8 BOM，便于旧版Windows编辑器识别编码；追加到合并输出或语料文件时只在新文件开头添加
+	ImageLinkBase        string            // 加在markdown图片链接之前的路径，如 /assets/doc1/ 得到 /assets/doc1/images/x.jpeg，用于静态网站；为空时使用相对路径 images/x.jpeg
+	ImageNaming          string            // 保存图片的命名方式：ImageNamingID（默认）按图片ID命名，ImageNamingPositional 按页面和页内序号命名
+	MaxPages             int               // 每个文档最多处理的页数，只请求前MaxPages页，用于试用时控制费用；0表示不限制，无法读取页数时也不限制
+	ChunkPages           int               // PDF页数超过该值时按每批该页数分别请求OCR，再按顺序合并结果，避免大文档单次请求超时；0表示不分批
+	SortOrder            string            // 批量处理时文件的排序方式：SortOrderLexical（默认）按字节顺序，SortOrderNatural 按文件名中数字的大小
+	StartAt              int               // 批量处理时从排序后的第StartAt个文件（从1开始）开始处理，用于从出错的位置重新开始；0表示从头开始
+	StartAfter           string            // 批量处理时从该文件（路径或文件名）之后开始处理，不能与 StartAt 同时使用
+	StripImageMetadata   bool              // 保存图片前解码并重新编码JPEG和PNG图片，去除EXIF、XMP等元数据；其他格式保存原始数据并输出警告
+	DedupImages          bool              // 按内容哈希对图片去重，内容相同的图片（如每页重复的页眉标志）只保存一次，所有引用指向同一个文件
+	OutputFormats        []string          // output.md和output.txt之外额外生成的格式：OutputFormatLaTeX 将合并的markdown转换为独立的output.tex
+	MarkdownTemplate     string            // 生成output.md的Go模板文件路径，可用字段见 MarkdownTemplateData；为空时直接合并各页
+	ArchiveSource        bool              // 上传后通过签名URL下载Mistral实际处理的内容，保存到缓存目录并与上传的内容核对；需要设置 CacheDir
+	WordBoxes            bool              // 将每页的单词、位置和置信度写入 words/page-N.json，仅在API响应包含单词级信息时生成
+	NameFromMetadata     bool              // 从JSON转换时按JSON中记录的原始来源（metadata.json 的 source_path 或内嵌的 metadata.source_path）命名输出，没有记录时使用JSON文件名
+	OnImageError         string            // 图片数据无法解码时的处理方式：OnImageErrorSkip（默认）保留原链接，OnImageErrorPlaceholder 链接到占位图片，OnImageErrorRemoveLink 移除链接
+	IndexDB              string            // 批量处理时将每个文件的来源、输出目录、页数、图片数、模型、时间和SHA-256写入该SQLite数据库，需要导入注册 IndexDBDriver 的驱动
+
+	// OnProgress 处理进度回调，为nil时不报告进度
+	OnProgress func(ProgressEvent)
+
+	// OnBatchDone 批量处理（ProcessMultipleFiles、ConvertMultipleJSON）结束时调用，包括出错返回时，为nil时不调用
+	//
+	// 启用 ContinueOnError 时部分文件失败不会返回错误，可通过摘要中的 Failed 判断。
+	// ConvertMultipleJSON 的摘要只包含文件计数。
+	OnBatchDone func(*BatchSummary)
+
+	// OnFileComplete ProcessMultipleFiles 每处理完一个文件（无论成功或失败）时调用，为nil时不调用
+	//
+	// 成功时err为nil，输出目录已存在而跳过的文件同样以 Pages 为0的结果回调；
+	// 失败时result为nil，err中包含文件路径。从清单恢复或因文本层跳过的文件不会回调。
+	// 调用时不持有任何锁，回调返回后才继续处理下一个文件。
+	OnFileComplete func(result *ProcessResult, err error)
+
+	// PageTransform 对每页markdown进行自定义处理（如修正连字、去除页眉页脚），为nil时不做修改
+	//
+	// pageIndex 为OCR响应中的页面索引（从0开始）。调用时图片链接已按图片选项改写或移除，
+	// 并已按 NormalizeUnicode、FullWidthToHalfWidth 完成规范化，
+	// 返回值用于output.md、单页文件和文本提取，短页面过滤也基于处理后的内容。
+	PageTransform func(pageIndex int, markdown string) string
+
+	// stagingDir 启用 AtomicOutput 且流式解析时，解析过程中写入图片和原始响应的临时目录
+	stagingDir string
+
+	// documentPages 设置 ChunkPages 或 MaxPages 时读取的PDF页数，0表示未知
+	documentPages int
+
+	// openFiles 处理器的文件操作上限，由处理器在入口处设置，供没有处理器的辅助函数使用，见 Processor.SetMaxOpenFiles
+	openFiles fileLimiter
+}
+
+// imageBehavior 返回实际生效的图片选项：是否保存图片、是否改写链接、是否保留链接
+func (o ProcessOptions) imageBehavior() (save, link, keep bool) {
+	if o.NoImages {
+		return false, false, false
+	}
+	if o.IncludeImages {
+		return true, true, true
+	}
+	if !o.SaveImages && !o.LinkImages && !o.KeepImagesInText {
+		// 未设置任何图片选项时保留原有链接，与旧版本行为一致
+		return false, false, true
+	}
+	return o.SaveImages, o.LinkImages, o.KeepImagesInText
+}
+
+// savesImages 返回是否需要请求并保存图片数据
+func (o ProcessOptions) savesImages() bool {
+	save, _, _ := o.imageBehavior()
+	return save
+}
+
+// pdfPasswordFor 返回处理指定文件时使用的PDF密码，按文件路径、文件名、PDFPassword的顺序查找
+func (o ProcessOptions) pdfPasswordFor(path string) string {
+	for _, key := range []string{filepath.Clean(path), filepath.Base(path)} {
+		for name, password := range o.PDFPasswords {
+			if filepath.Clean(name) == key {
+				return password
+			}
+		}
+	}
+	return o.PDFPassword
+}
+
+// 处理阶段
+const (
+	StageUpload    = "upload"     // 上传文件
+	StageSignedURL = "signed_url" // 获取签名URL
+	StageOCR       = "ocr"        // 等待OCR结果
+	StageSave      = "save"       // 保存页面和图片
+	StageDone      = "done"       // 处理完成
+)
+
+// ProgressEvent 表示一次进度更新
+//
+// 在上传和OCR阶段总量未知，Total为0；进入保存阶段后Total为总页数，Current为已保存的页数。
+type ProgressEvent struct {
+	Stage   string
+	Current int
+	Total   int
 }
 
 // ProcessMetadata 存储处理元数据
 type ProcessMetadata struct {
-	SourceType      string          `json:"source_type"`       // "file" 或 "url"
-	SourcePath      string          `json:"source_path"`       // 原始文件路径或URL
-	OutputDir       string          `json:"output_dir"`        // 输出目录
-	PagesProcessed  int             `json:"pages_processed"`   // 处理的页数
-	ProcessedAt     string          `json:"processed_at"`      // 处理时间
-	DocumentURL     string          `json:"document_url"`      // 文档URL
-	FileID          string          `json:"file_id,omitempty"` // 文件ID（如果是上传的文件）
-	IncludeImages   bool            `json:"include_images"`    // 是否包含图片
-	ImagesSaved     int             `json:"images_saved"`      // 保存的图片数量
-	OCRResponseInfo map[string]any  `json:"ocr_response_info"` // OCR响应信息
-	RawResponse     json.RawMessage `json:"raw_response"`      // 原始OCR响应
+	SourceType      string            `json:"source_type"`                 // "file" 或 "url"
+	SourcePath      string            `json:"source_path"`                 // 原始文件路径或URL
+	SourceSHA256    string            `json:"source_sha256,omitempty"`     // 源文件内容的SHA-256（URL来源时为空）
+	SourceSizeBytes int64             `json:"source_size_bytes,omitempty"` // 源文件大小（字节）
+	OutputDir       string            `json:"output_dir"`                  // 输出目录
+	PagesProcessed  int               `json:"pages_processed"`             // 处理的页数
+	ProcessedAt     string            `json:"processed_at"`                // 处理时间
+	ProcessingTime  string            `json:"processing_time,omitempty"`   // 处理耗时
+	DocumentURL     string            `json:"document_url"`                // 文档URL
+	DocumentType    string            `json:"document_type,omitempty"`     // 文档类型（document_url 或 image_url）
+	FileID          string            `json:"file_id,omitempty"`           // 文件ID（如果是上传的文件）
+	CacheKey        string            `json:"cache_key,omitempty"`         // 缓存键（启用缓存时）
+	ArchivedSource  string            `json:"archived_source,omitempty"`   // 启用 ArchiveSource 时从签名URL下载的上传内容的归档文件
+	ArchivedSHA256  string            `json:"archived_sha256,omitempty"`   // 归档内容的SHA-256，未解密的文件应与 source_sha256 相同
+	FromCache       bool              `json:"from_cache,omitempty"`        // 是否使用了缓存的OCR响应
+	IncludeImages   bool              `json:"include_images"`              // 是否包含图片
+	ImagesSaved     int               `json:"images_saved"`                // 保存的图片数量
+	DuplicateImages int               `json:"duplicate_images,omitempty"`  // 启用图片去重时，与已保存图片内容相同而未单独保存的图片数量
+	DedupBytesSaved int64             `json:"dedup_bytes_saved,omitempty"` // 图片去重节省的字节数
+	ImageFiles      map[string]string `json:"image_files,omitempty"`       // 图片ID到保存的文件（相对于输出目录）的映射
+	FailedImages    []string          `json:"failed_images,omitempty"`     // 响应中的图片数据无法解码而未保存的图片ID
+	PagesDropped    int               `json:"pages_dropped,omitempty"`     // 因文本过短从合并输出中移除的页数
+	MaxPages        int               `json:"max_pages,omitempty"`         // 设置的每个文档最多处理的页数，无法读取页数而未限制时不记录
+	ImagesPDF       string            `json:"images_pdf,omitempty"`        // 合并图片生成的PDF文件（相对于输出目录）
+	AnnotationsFile string            `json:"annotations_file,omitempty"`  // 结构化标注文件（相对于输出目录）
+	WordsDir        string            `json:"words_dir,omitempty"`         // 每页单词位置文件所在的子目录（相对于输出目录）
+	Attempts        *AttemptStats     `json:"attempts,omitempty"`          // 各API请求的尝试次数和使用的端点
+	Timings         *PhaseTimings     `json:"timings,omitempty"`           // 保存结果前各阶段的耗时
+	PageInfo        []PageInfo        `json:"page_info,omitempty"`         // 每页的尺寸和方向（横向或纵向）
+	OCRResponseInfo map[string]any    `json:"ocr_response_info"`           // OCR响应信息
+	RawResponse     json.RawMessage   `json:"raw_response,omitempty"`      // 原始OCR响应
+	RawResponseFile string            `json:"raw_response_file,omitempty"` // 单独保存的原始响应文件（相对于输出目录）
 }
@@ -0,0 +1,118 @@
+package ocr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultOutputBase 没有源文件名（如处理URL）时使用的基础名称
+const defaultOutputBase = "ocr-result"
+
+// OutputNameData 表示输出名称模板中可用的字段
+//
+// 例如模板 "{{.Date}}_{{.Base}}_{{printf \"%03d\" .Index}}" 会生成 "2024-06-01_report_001"。
+type OutputNameData struct {
+	Base  string // 源文件名（不含扩展名），没有源文件名时为 "ocr-result"
+	Date  string // 当前日期，格式为 2006-01-02
+	Index int    // 批量处理中的序号，从1开始，单独处理时为1
+	Unix  int64  // 当前Unix时间戳
+}
+
+// sourceBaseName 返回源文件名去掉扩展名后的部分
+func sourceBaseName(source string) string {
+	if source == "" {
+		return ""
+	}
+	return strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+}
+
+// recordedSourceName 返回JSON中记录的原始来源去掉扩展名后的文件名，用于 NameFromMetadata
+//
+// 依次查找顶层的 source_path（metadata.json）和内嵌的 metadata.source_path，来源为URL时使用URL路径中的文件名。
+// 由JSON转换生成的元数据记录的是JSON文件而不是原始文档，不使用。没有记录原始来源时返回空字符串。
+func recordedSourceName(jsonData []byte) string {
+	type sourceInfo struct {
+		SourceType string `json:"source_type"`
+		SourcePath string `json:"source_path"`
+	}
+	var recorded struct {
+		sourceInfo
+		Metadata *sourceInfo `json:"metadata"`
+	}
+	if err := json.Unmarshal(jsonData, &recorded); err != nil {
+		return ""
+	}
+
+	for _, info := range []*sourceInfo{&recorded.sourceInfo, recorded.Metadata} {
+		if info == nil || info.SourcePath == "" || info.SourceType == "json" {
+			continue
+		}
+		source := info.SourcePath
+		if info.SourceType == "url" {
+			parsed, err := url.Parse(source)
+			if err != nil {
+				continue
+			}
+			source = path.Base(parsed.Path)
+			if source == "/" || source == "." {
+				continue
+			}
+		}
+		if name := sourceBaseName(source); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// resolveOutputName 确定输出目录名称
+//
+// 优先使用 CustomOutputName，其次按 OutputNameTemplate 生成，都未设置时使用base，
+// base为空时使用 "ocr-result-<unix>"。模板生成的名称包含路径分隔符或为上级目录时返回错误，
+// 保证输出目录位于 OutputDir 之内。
+func (o ProcessOptions) resolveOutputName(base string, index int) (string, error) {
+	if o.CustomOutputName != "" {
+		return o.CustomOutputName, nil
+	}
+
+	now := time.Now()
+	if o.OutputNameTemplate == "" {
+		if base == "" {
+			return fmt.Sprintf("%s-%d", defaultOutputBase, now.Unix()), nil
+		}
+		return base, nil
+	}
+
+	tmpl, err := template.New("output_name").Option("missingkey=error").Parse(o.OutputNameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("解析输出名称模板失败: %w", err)
+	}
+
+	if base == "" {
+		base = defaultOutputBase
+	}
+	var name strings.Builder
+	if err := tmpl.Execute(&name, OutputNameData{
+		Base:  base,
+		Date:  now.Format("2006-01-02"),
+		Index: index,
+		Unix:  now.Unix(),
+	}); err != nil {
+		return "", fmt.Errorf("生成输出名称失败: %w", err)
+	}
+
+	result := strings.TrimSpace(name.String())
+	if result == "" {
+		return "", fmt.Errorf("输出名称模板生成了空名称: %s", o.OutputNameTemplate)
+	}
+	if result == "." || result == ".." || strings.ContainsAny(result, `/\`) || filepath.VolumeName(result) != "" {
+		return "", fmt.Errorf("输出名称模板生成的名称不能包含路径分隔符或上级目录: %q", result)
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package ocr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveOutputName(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+
+	tests := []struct {
+		name     string
+		opts     ProcessOptions
+		base     string
+		index    int
+		want     string
+		wantErr  string
+		checkFmt func(string) bool
+	}{
+		{name: "源文件名", base: "report", index: 1, want: "report"},
+		{name: "自定义名称优先", opts: ProcessOptions{CustomOutputName: "custom", OutputNameTemplate: "{{.Base}}"}, base: "report", index: 1, want: "custom"},
+		{name: "模板字段", opts: ProcessOptions{OutputNameTemplate: `{{.Date}}_{{.Base}}_{{printf "%03d" .Index}}`}, base: "report", index: 7, want: today + "_report_007"},
+		{name: "没有源文件名", opts: ProcessOptions{OutputNameTemplate: "{{.Base}}-{{.Index}}"}, index: 2, want: "ocr-result-2"},
+		{name: "Unix时间戳", opts: ProcessOptions{OutputNameTemplate: "{{.Unix}}"}, base: "report", index: 1, checkFmt: func(name string) bool {
+			unix, err := strconv.ParseInt(name, 10, 64)
+			return err == nil && time.Since(time.Unix(unix, 0)) < time.Minute
+		}},
+		{name: "首尾空白", opts: ProcessOptions{OutputNameTemplate: " {{.Base}} "}, base: "report", index: 1, want: "report"},
+		{name: "空名称", opts: ProcessOptions{OutputNameTemplate: "{{if false}}x{{end}}"}, base: "report", index: 1, wantErr: "空名称"},
+		{name: "未知字段", opts: ProcessOptions{OutputNameTemplate: "{{.Title}}"}, base: "report", index: 1, wantErr: "生成输出名称失败"},
+		{name: "语法错误", opts: ProcessOptions{OutputNameTemplate: "{{.Base"}, base: "report", index: 1, wantErr: "解析输出名称模板失败"},
+		{name: "上级目录", opts: ProcessOptions{OutputNameTemplate: "{{.Base}}/../../x"}, base: "report", index: 1, wantErr: "路径分隔符"},
+		{name: "反斜杠", opts: ProcessOptions{OutputNameTemplate: `..\{{.Base}}`}, base: "report", index: 1, wantErr: "路径分隔符"},
+		{name: "绝对路径", opts: ProcessOptions{OutputNameTemplate: "/tmp/{{.Base}}"}, base: "report", index: 1, wantErr: "路径分隔符"},
+		{name: "源文件名为上级目录", opts: ProcessOptions{OutputNameTemplate: "{{.Base}}"}, base: "..", index: 1, wantErr: "上级目录"},
+		{name: "当前目录", opts: ProcessOptions{OutputNameTemplate: "."}, base: "report", index: 1, wantErr: "上级目录"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.resolveOutputName(tt.base, tt.index)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveOutputName 返回 %q, %v，期望包含 %q 的错误", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOutputName 返回错误: %v", err)
+			}
+			if tt.checkFmt != nil {
+				if !tt.checkFmt(got) {
+					t.Errorf("resolveOutputName = %q，格式不正确", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("resolveOutputName = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOutputNameTemplateBatchIndex 批量处理时模板中的 Index 为文件在批次中的序号
+func TestOutputNameTemplateBatchIndex(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "a.pdf", "b.pdf", "c.pdf")
+
+	results, err := newTestProcessor(&fakeBackend{pages: 1}).ProcessMultipleFiles(context.Background(), []string{inputDir}, ProcessOptions{
+		OutputDir:          t.TempDir(),
+		OutputNameTemplate: `{{printf "%03d" .Index}}_{{.Base}}`,
+		NoImages:           true,
+	})
+	if err != nil {
+		t.Fatalf("ProcessMultipleFiles 返回错误: %v", err)
+	}
+	var names []string
+	for _, result := range results {
+		names = append(names, filepath.Base(result.OutputDir))
+	}
+	if want := []string{"001_a", "002_b", "003_c"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("输出目录 = %v，期望 %v", names, want)
+	}
+}
+
+// TestOutputNameTemplateOutsideOutputDir 模板生成的名称指向输出目录之外时不处理文件
+func TestOutputNameTemplateOutsideOutputDir(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "report.pdf")
+
+	root := t.TempDir()
+	backend := &fakeBackend{pages: 1}
+	_, err := newTestProcessor(backend).ProcessFile(context.Background(), filepath.Join(inputDir, "report.pdf"), ProcessOptions{
+		OutputDir:          filepath.Join(root, "out"),
+		OutputNameTemplate: "../{{.Base}}",
+		NoImages:           true,
+	})
+	if err == nil {
+		t.Fatal("模板生成的名称指向上级目录时 ProcessFile 没有返回错误")
+	}
+	if len(backend.uploads) != 0 {
+		t.Errorf("名称无效时仍上传了文件: %v", backend.uploads)
+	}
+	if _, err := os.Stat(filepath.Join(root, "report")); !os.IsNotExist(err) {
+		t.Errorf("在输出目录之外创建了目录: %v", err)
+	}
+}
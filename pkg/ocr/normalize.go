@@ -0,0 +1,32 @@
+package ocr
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeUnicode 对文本进行NFC规范化，合并组合字符，并将不换行空格替换为普通空格
+func normalizeUnicode(text string) string {
+	text = norm.NFC.String(text)
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\u00a0', '\u202f': // 不换行空格、窄不换行空格
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// fullWidthToHalfWidth 将全角字母和数字转换为半角
+//
+// 全角标点（如中文中常用的，：（））保持不变，避免破坏中文排版。
+func fullWidthToHalfWidth(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '０' && r <= '９', r >= 'Ａ' && r <= 'Ｚ', r >= 'ａ' && r <= 'ｚ':
+			return r - 0xfee0
+		}
+		return r
+	}, text)
+}
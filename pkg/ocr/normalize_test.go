@@ -0,0 +1,56 @@
+package ocr
+
+import "testing"
+
+func TestNormalizeUnicode(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "ASCII不变", in: "plain text", want: "plain text"},
+		{name: "组合重音合并为预组字符", in: "cafe\u0301", want: "caf\u00e9"},
+		{name: "预组字符不变", in: "caf\u00e9", want: "caf\u00e9"},
+		{name: "韩文字母合并为音节", in: "\u1100\u1161", want: "\uac00"},
+		{name: "多个组合符号按规范顺序排列", in: "a\u0323\u0302", want: "\u1ead"},
+		{name: "NFKC兼容字符保持不变", in: "\ufb01le \u2460 \u33a1", want: "\ufb01le \u2460 \u33a1"},
+		{name: "全角字母不受NFC影响", in: "ＡＢＣ１２３", want: "ＡＢＣ１２３"},
+		{name: "不换行空格", in: "10\u00a0kg", want: "10 kg"},
+		{name: "窄不换行空格", in: "10\u202f%", want: "10 %"},
+		{name: "全角空格保持不变", in: "中文\u3000排版", want: "中文\u3000排版"},
+		{name: "制表符和换行保持不变", in: "a\tb\nc", want: "a\tb\nc"},
+		{name: "中文不变", in: "识别结果，包含标点。", want: "识别结果，包含标点。"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeUnicode(tt.in); got != tt.want {
+				t.Errorf("normalizeUnicode(%q) = %q，期望 %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFullWidthToHalfWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "全角数字", in: "２０２４年", want: "2024年"},
+		{name: "全角大写字母", in: "ＰＤＦ", want: "PDF"},
+		{name: "全角小写字母", in: "ｍｉｓｔｒａｌ", want: "mistral"},
+		{name: "全角标点保持不变", in: "（注：见第１页）", want: "（注：见第1页）"},
+		{name: "全角空格保持不变", in: "Ａ\u3000Ｂ", want: "A\u3000B"},
+		{name: "半角字符不变", in: "abc 123", want: "abc 123"},
+		{name: "边界字符", in: "０９ＡＺａｚ", want: "09AZaz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fullWidthToHalfWidth(tt.in); got != tt.want {
+				t.Errorf("fullWidthToHalfWidth(%q) = %q，期望 %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
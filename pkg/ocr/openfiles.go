@@ -0,0 +1,43 @@
+package ocr
+
+import (
+	"os"
+)
+
+// fileLimiter 限制同时进行的文件操作数的信号量，为nil时不限制
+type fileLimiter chan struct{}
+
+// newFileLimiter 创建上限为n的信号量，非正数返回nil，表示不限制
+func newFileLimiter(n int) fileLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(fileLimiter, n)
+}
+
+// SetMaxOpenFiles 设置该处理器同时进行的文件操作（读取源文件、上传、写入输出和图片、读写缓存等）的上限，非正数表示不限制
+//
+// 上限由该处理器的所有调用共同遵守，在多个goroutine中用同一个处理器并发处理大量文件时可避免 "too many open files" 错误。
+// 复制文件时同时打开源文件和目标文件，计为一个操作，因此打开的文件数最多为上限的两倍；
+// 流式解析时持续写入的原始响应文件和HTTP连接不计入。应在开始处理前调用。
+func (p *Processor) SetMaxOpenFiles(n int) {
+	p.openFiles = newFileLimiter(n)
+}
+
+// acquire 占用一个文件操作的名额，达到上限时等待，返回释放名额的函数
+//
+// 持有名额期间不能再次调用，否则在上限较小时会互相等待。
+func (l fileLimiter) acquire() func() {
+	if l == nil {
+		return func() {}
+	}
+	l <- struct{}{}
+	return func() { <-l }
+}
+
+// readFile 在文件操作上限内读取整个文件
+func (l fileLimiter) readFile(path string) ([]byte, error) {
+	release := l.acquire()
+	defer release()
+	return os.ReadFile(path)
+}
@@ -0,0 +1,133 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrentUploadBackend 记录同时进行的上传数的假后端
+type concurrentUploadBackend struct {
+	*fakeBackend
+	current atomic.Int32
+	peak    atomic.Int32
+}
+
+func (b *concurrentUploadBackend) UploadPDF(ctx context.Context, filePath string) (string, string, error) {
+	n := b.current.Add(1)
+	defer b.current.Add(-1)
+	for {
+		peak := b.peak.Load()
+		if n <= peak || b.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return b.fakeBackend.UploadPDF(ctx, filePath)
+}
+
+// runWithTimeout 在超时时间内运行fn，超时说明获取文件操作名额时互相等待
+func runWithTimeout(t *testing.T, timeout time.Duration, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("%v 内没有完成，可能在等待文件操作名额时死锁", timeout)
+	}
+}
+
+// TestMaxOpenFilesLimitsConcurrentCalls 多个goroutine共用一个处理器时，同时进行的上传不超过该处理器的上限
+func TestMaxOpenFilesLimitsConcurrentCalls(t *testing.T) {
+	inputDir := t.TempDir()
+	var names []string
+	for i := 0; i < 40; i++ {
+		names = append(names, fmt.Sprintf("doc%02d.pdf", i))
+	}
+	writeTestPDFs(t, inputDir, names...)
+
+	backend := &concurrentUploadBackend{fakeBackend: &fakeBackend{pages: 1}}
+	processor := newTestProcessor(backend)
+	processor.SetMaxOpenFiles(2)
+
+	outputDir := t.TempDir()
+	errs := make(chan error, len(names))
+	runWithTimeout(t, 30*time.Second, func() {
+		var wg sync.WaitGroup
+		for _, name := range names {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				_, err := processor.ProcessFile(context.Background(), filepath.Join(inputDir, name), ProcessOptions{
+					OutputDir:     filepath.Join(outputDir, name),
+					IncludeImages: true,
+				})
+				errs <- err
+			}(name)
+		}
+		wg.Wait()
+	})
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("ProcessFile 返回错误: %v", err)
+		}
+	}
+
+	if peak := backend.peak.Load(); peak > 2 {
+		t.Errorf("同时进行了 %d 个上传，超过上限 2", peak)
+	}
+	if len(backend.uploads) != len(names) {
+		t.Errorf("上传了 %d 个文件，期望 %d 个", len(backend.uploads), len(names))
+	}
+}
+
+// TestMaxOpenFilesLargeBatch 上限为1时，启用缓存、合并输出、语料文件、原子输出和图片PDF的大批量处理也能完成
+func TestMaxOpenFilesLargeBatch(t *testing.T) {
+	inputDir := t.TempDir()
+	var names []string
+	for i := 0; i < 200; i++ {
+		names = append(names, fmt.Sprintf("batch/doc%03d.pdf", i))
+	}
+	writeTestPDFs(t, inputDir, names...)
+
+	backend := &fakeBackend{pages: 2}
+	processor := newTestProcessor(backend)
+	processor.SetMaxOpenFiles(1)
+
+	workDir := t.TempDir()
+	opts := ProcessOptions{
+		OutputDir:     filepath.Join(workDir, "out"),
+		IncludeImages: true,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		AppendTo:      filepath.Join(workDir, "combined.md"),
+		CorpusFile:    filepath.Join(workDir, "corpus.txt"),
+		AtomicOutput:  true,
+		BundleImages:  BundleImagesPDF,
+	}
+
+	var results []*ProcessResult
+	runWithTimeout(t, 60*time.Second, func() {
+		var err error
+		results, err = processor.ProcessMultipleFiles(context.Background(), []string{inputDir}, opts)
+		if err != nil {
+			t.Errorf("ProcessMultipleFiles 返回错误: %v", err)
+		}
+	})
+	if len(results) != len(names) {
+		t.Fatalf("处理了 %d 个文件，期望 %d 个", len(results), len(names))
+	}
+	for _, result := range results {
+		if result.Pages != 2 || result.Images != 2 {
+			t.Fatalf("%s: 页数 %d、图片 %d，期望各为 2", result.OutputDir, result.Pages, result.Images)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package ocr
+
+// 页面方向
+const (
+	OrientationPortrait  = "portrait"  // 纵向，高度大于宽度
+	OrientationLandscape = "landscape" // 横向，宽度大于高度（包括扫描时旋转过的页面）
+	OrientationSquare    = "square"    // 宽度与高度相同
+)
+
+// PageInfo 记录页面的尺寸和方向，便于重新组装文档的工具为每页设置正确的页面大小
+type PageInfo struct {
+	Index       int    `json:"index"`                 // OCR响应中的页面索引（从0开始）
+	Width       int    `json:"width,omitempty"`       // 页面宽度（像素）
+	Height      int    `json:"height,omitempty"`      // 页面高度（像素）
+	DPI         int    `json:"dpi,omitempty"`         // 页面分辨率
+	Orientation string `json:"orientation,omitempty"` // 页面方向，响应中没有尺寸时为空
+}
+
+// pageOrientation 根据页面的宽度和高度判断方向，尺寸未知时返回空字符串
+func pageOrientation(width, height int) string {
+	switch {
+	case width <= 0 || height <= 0:
+		return ""
+	case width > height:
+		return OrientationLandscape
+	case width < height:
+		return OrientationPortrait
+	default:
+		return OrientationSquare
+	}
+}
+
+// pageInfos 返回每个页面的尺寸和方向
+func pageInfos(pages []Page) []PageInfo {
+	infos := make([]PageInfo, 0, len(pages))
+	for _, page := range pages {
+		infos = append(infos, PageInfo{
+			Index:       page.Index,
+			Width:       page.Dimensions.Width,
+			Height:      page.Dimensions.Height,
+			DPI:         page.Dimensions.DPI,
+			Orientation: pageOrientation(page.Dimensions.Width, page.Dimensions.Height),
+		})
+	}
+	return infos
+}
+
+// landscapePages 返回横向页面的数量
+func landscapePages(infos []PageInfo) int {
+	count := 0
+	for _, info := range infos {
+		if info.Orientation == OrientationLandscape {
+			count++
+		}
+	}
+	return count
+}
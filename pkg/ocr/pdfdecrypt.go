@@ -0,0 +1,96 @@
+package ocr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrPDFPassword 表示提供的密码既不是PDF的用户密码也不是所有者密码
+var ErrPDFPassword = errors.New("PDF密码错误")
+
+// pdfConfiguration 返回读取PDF时使用的pdfcpu配置，password同时作为用户密码和所有者密码尝试
+//
+// 每次调用构建新的配置，取值与pdfcpu内置的默认配置相同，使用宽松的校验模式，尽量接受不完全符合规范的PDF。
+// 不使用 model.NewDefaultConfiguration：它会在用户配置目录中创建config.yml，失败时直接退出进程。
+// 也不修改 model.ConfigPath 等全局设置，同一程序中其他使用pdfcpu的代码不受影响。
+func pdfConfiguration(password string) *model.Configuration {
+	return &model.Configuration{
+		CreationDate:                    time.Now().Format("2006-01-02 15:04"),
+		Version:                         model.VersionStr,
+		CheckFileNameExt:                true,
+		Reader15:                        true,
+		ValidationMode:                  model.ValidationRelaxed,
+		Eol:                             types.EolLF,
+		WriteObjectStream:               true,
+		WriteXRefStream:                 true,
+		EncryptUsingAES:                 true,
+		EncryptKeyLength:                256,
+		Permissions:                     model.PermissionsPrint,
+		TimestampFormat:                 "2006-01-02 15:04",
+		DateFormat:                      "2006-01-02",
+		Optimize:                        true,
+		OptimizeBeforeWriting:           true,
+		OptimizeResourceDicts:           true,
+		OptimizeDuplicateContentStreams: false,
+		CreateBookmarks:                 true,
+		Timeout:                         5,
+		PreferredCertRevocationChecker:  model.CRL,
+		UserPW:                          password,
+		OwnerPW:                         password,
+	}
+}
+
+// DecryptPDF 使用密码在本地解密以标准安全处理程序加密的PDF，返回不带加密的PDF内容
+//
+// 解析和解密由pdfcpu完成。密码可以是用户密码或所有者密码，支持RC4（40-128位）、AES-128和AES-256加密。
+// data不是加密的PDF时原样返回；密码错误时返回 ErrPDFPassword。
+func DecryptPDF(data []byte, password string) ([]byte, error) {
+	if !isPDFData(data) {
+		return nil, fmt.Errorf("不是有效的PDF文件")
+	}
+	if !bytes.Contains(data, []byte("/Encrypt")) {
+		return data, nil
+	}
+
+	ctx, err := api.ReadContext(bytes.NewReader(data), pdfConfiguration(password))
+	if err != nil {
+		if errors.Is(err, pdfcpu.ErrWrongPassword) {
+			return nil, ErrPDFPassword
+		}
+		return nil, fmt.Errorf("解析PDF失败: %w", err)
+	}
+	if ctx.Encrypt == nil {
+		// "/Encrypt" 只出现在内容中，文件本身没有加密
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	if err := api.Decrypt(bytes.NewReader(data), &buf, pdfConfiguration(password)); err != nil {
+		return nil, fmt.Errorf("解密PDF失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// pdfPageCount 读取PDF页面树根节点的 /Count 得到页数，加密的PDF使用password打开
+//
+// 只读取交叉引用表和页面树根节点，不校验整个文档；无法解析或没有 /Count 时返回错误。
+func pdfPageCount(data []byte, password string) (int, error) {
+	ctx, err := api.ReadContext(bytes.NewReader(data), pdfConfiguration(password))
+	if err != nil {
+		if errors.Is(err, pdfcpu.ErrWrongPassword) {
+			return 0, ErrPDFPassword
+		}
+		return 0, fmt.Errorf("解析PDF失败: %w", err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return 0, fmt.Errorf("读取PDF页数失败: %w", err)
+	}
+	return ctx.PageCount, nil
+}
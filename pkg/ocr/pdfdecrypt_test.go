@@ -0,0 +1,179 @@
+package ocr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// buildTestPDF 生成包含pages页的最简PDF，每页的内容流绘制 "Hello page N"
+func buildTestPDF(pages int) []byte {
+	texts := make([]string, pages)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("Hello page %d", i+1)
+	}
+	return buildTestPDFWithTexts(texts)
+}
+
+// buildTestPDFWithTexts 生成每页绘制texts中对应文本的最简PDF，交叉引用表的偏移量准确
+func buildTestPDFWithTexts(texts []string) []byte {
+	pages := len(texts)
+	var objects []string
+	kids := make([]string, pages)
+	for i := 0; i < pages; i++ {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages),
+	)
+	fontRef := 3 + 2*pages
+	for i := 0; i < pages; i++ {
+		content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", texts[i])
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R /Resources << /Font << /F1 %d 0 R >> >> >>", 4+2*i, fontRef),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// testEncryption 返回用户密码为user、所有者密码为owner的加密配置，不读取用户配置目录
+func testEncryption(aes bool, keyLength int) *model.Configuration {
+	conf := pdfConfiguration("")
+	conf.UserPW = "user"
+	conf.OwnerPW = "owner"
+	conf.EncryptUsingAES = aes
+	conf.EncryptKeyLength = keyLength
+	return conf
+}
+
+// encryptTestPDF 使用pdfcpu按指定方式加密PDF
+func encryptTestPDF(t *testing.T, data []byte, conf *model.Configuration) []byte {
+	t.Helper()
+	conf.Permissions = model.PermissionsAll
+	var buf bytes.Buffer
+	if err := api.Encrypt(bytes.NewReader(data), &buf, conf); err != nil {
+		t.Fatalf("加密测试PDF失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecryptPDF(t *testing.T) {
+	plain := buildTestPDF(2)
+
+	tests := []struct {
+		name string
+		conf func() *model.Configuration
+	}{
+		{name: "RC4 40位", conf: func() *model.Configuration { return testEncryption(false, 40) }},
+		{name: "RC4 128位", conf: func() *model.Configuration { return testEncryption(false, 128) }},
+		{name: "AESV2", conf: func() *model.Configuration { return testEncryption(true, 128) }},
+		{name: "AESV3", conf: func() *model.Configuration { return testEncryption(true, 256) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted := encryptTestPDF(t, plain, tt.conf())
+			if bytes.Contains(encrypted, []byte("Hello page 1")) {
+				t.Fatal("加密后的PDF中仍有明文内容")
+			}
+
+			for _, password := range []string{"user", "owner"} {
+				decrypted, err := DecryptPDF(encrypted, password)
+				if err != nil {
+					t.Fatalf("使用密码 %q 解密失败: %v", password, err)
+				}
+				if bytes.Contains(decrypted, []byte("/Encrypt")) {
+					t.Errorf("使用密码 %q 解密后仍有加密字典", password)
+				}
+				for _, want := range []string{"Hello page 1", "Hello page 2"} {
+					if !bytes.Contains(decrypted, []byte(want)) {
+						t.Errorf("使用密码 %q 解密后缺少内容 %q", password, want)
+					}
+				}
+				if pages, err := api.PageCount(bytes.NewReader(decrypted), pdfConfiguration("")); err != nil || pages != 2 {
+					t.Errorf("使用密码 %q 解密后的PDF有 %d 页（%v），期望 2 页", password, pages, err)
+				}
+			}
+
+			if _, err := DecryptPDF(encrypted, "wrong"); !errors.Is(err, ErrPDFPassword) {
+				t.Errorf("使用错误的密码解密返回 %v，期望 ErrPDFPassword", err)
+			}
+		})
+	}
+}
+
+func TestDecryptPDFUnencrypted(t *testing.T) {
+	plain := buildTestPDF(1)
+	decrypted, err := DecryptPDF(plain, "any")
+	if err != nil {
+		t.Fatalf("DecryptPDF 返回错误: %v", err)
+	}
+	if !bytes.Equal(decrypted, plain) {
+		t.Error("未加密的PDF没有原样返回")
+	}
+
+	// 内容中出现 "/Encrypt" 但文件本身没有加密
+	withName := buildTestPDFWithTexts([]string{"see /Encrypt in the manual"})
+	decrypted, err = DecryptPDF(withName, "any")
+	if err != nil {
+		t.Fatalf("DecryptPDF 返回错误: %v", err)
+	}
+	if !bytes.Equal(decrypted, withName) {
+		t.Error("内容中包含 /Encrypt 的未加密PDF没有原样返回")
+	}
+
+	if _, err := DecryptPDF([]byte("not a pdf"), "any"); err == nil {
+		t.Error("不是PDF的内容没有返回错误")
+	}
+}
+
+// TestPDFConfigurationLeavesGlobalsAlone 读取和解密PDF不修改pdfcpu的全局设置，也不在用户配置目录中创建文件
+func TestPDFConfigurationLeavesGlobalsAlone(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("HOME", configHome)
+	t.Setenv("AppData", configHome)
+
+	encrypted := encryptTestPDF(t, buildTestPDF(2), testEncryption(true, 256))
+	if _, err := DecryptPDF(encrypted, "user"); err != nil {
+		t.Fatalf("DecryptPDF 返回错误: %v", err)
+	}
+	if pages, err := pdfPageCount(encrypted, "owner"); err != nil || pages != 2 {
+		t.Fatalf("pdfPageCount 返回 %d, %v，期望 2 页", pages, err)
+	}
+
+	// 导入本包不改变pdfcpu的默认值
+	if model.ConfigPath != "default" {
+		t.Errorf("model.ConfigPath 被修改为 %q", model.ConfigPath)
+	}
+	entries, err := os.ReadDir(configHome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("在用户配置目录中创建了 %s", entry.Name())
+	}
+}
@@ -1,31 +1,47 @@
 package ocr
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 )
 
 // Processor 处理OCR结果
 type Processor struct {
-	client *Client
-	logger *zap.Logger
+	client    OCRBackend
+	logger    *zap.Logger
+	openFiles fileLimiter // 同时进行的文件操作数的上限，见 SetMaxOpenFiles
 }
 
-// NewProcessor 创建一个新的处理器
-func NewProcessor(client *Client, logger *zap.Logger) *Processor {
+// NewProcessor 创建一个新的处理器，client 通常为 *Client，也可以是任意 OCRBackend 实现
+func NewProcessor(client OCRBackend, logger *zap.Logger) *Processor {
 	return &Processor{
 		client: client,
 		logger: logger,
 	}
 }
 
+// reportProgress 调用进度回调（如果设置了）
+func reportProgress(opts ProcessOptions, event ProgressEvent) {
+	if opts.OnProgress != nil {
+		opts.OnProgress(event)
+	}
+}
+
 // checkOutputDir 检查输出目录是否已经存在并且output.md不为空
 func (p *Processor) checkOutputDir(outputDir string) (bool, error) {
 	// 检查输出目录是否存在
@@ -52,21 +68,29 @@ func (p *Processor) checkOutputDir(outputDir string) (bool, error) {
 }
 
 // ProcessFile 处理文件并返回结果
-func (p *Processor) ProcessFile(filePath string, opts ProcessOptions) (*ProcessResult, error) {
+func (p *Processor) ProcessFile(ctx context.Context, filePath string, opts ProcessOptions) (*ProcessResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts.openFiles = p.openFiles
+
 	startTime := time.Now()
 	p.logger.Info("开始处理文件", zap.String("filePath", filePath))
 
-	// 确定输出文件名
-	outputName := opts.CustomOutputName
-	if outputName == "" {
-		// 使用原始文件名(不带扩展名)
-		outputName = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	// 确定输出文件名，默认使用原始文件名(不带扩展名)
+	outputName, err := opts.resolveOutputName(sourceBaseName(filePath), 1)
+	if err != nil {
+		return nil, err
 	}
+	// 保存结果时沿用同一名称，避免模板中的时间字段前后不一致
+	opts.CustomOutputName = outputName
 
-	// 创建输出目录
+	// 创建输出目录，启用 AtomicOutput 时由 saveOutput 在输出完整后整体移入，处理失败不会留下空目录
 	outputDir := filepath.Join(opts.OutputDir, outputName)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return nil, fmt.Errorf("创建输出目录错误: %w", err)
+	if !opts.AtomicOutput {
+		if err := opts.mkdirAll(outputDir); err != nil {
+			return nil, fmt.Errorf("创建输出目录错误: %w", err)
+		}
 	}
 
 	// 检查输出目录是否已经存在并且output.md不为空
@@ -79,7 +103,7 @@ func (p *Processor) ProcessFile(filePath string, opts ProcessOptions) (*ProcessR
 		return &ProcessResult{
 			OutputDir:    outputDir,
 			ImagesDir:    filepath.Join(outputDir, "images"),
-			MetadataPath: filepath.Join(outputDir, "metadata.json"),
+			MetadataPath: filepath.Join(outputDir, MetadataFileName),
 			Pages:        0,
 			ProcessedAt:  "0s",
 		}, nil
@@ -91,12 +115,49 @@ func (p *Processor) ProcessFile(filePath string, opts ProcessOptions) (*ProcessR
 		SourcePath:    filePath,
 		OutputDir:     opts.OutputDir,
 		ProcessedAt:   startTime.Format(time.RFC3339),
-		IncludeImages: opts.IncludeImages,
+		IncludeImages: opts.savesImages(),
+		Timings:       &PhaseTimings{},
+	}
+
+	// 记录源文件的哈希和大小，便于之后核对输出与输入是否对应
+	if sum, size, err := hashSourceFile(filePath, opts); err != nil {
+		p.logger.Warn("计算源文件哈希失败", zap.String("filePath", filePath), zap.Error(err))
+	} else {
+		metadata.SourceSHA256 = sum
+		metadata.SourceSizeBytes = size
+	}
+
+	// 检查本地缓存，命中时直接使用缓存的响应，不调用API
+	if opts.CacheDir != "" {
+		cacheKey, err := computeCacheKey(filePath, opts)
+		if err != nil {
+			p.logger.Warn("计算缓存键失败，跳过缓存", zap.String("filePath", filePath), zap.Error(err))
+		} else {
+			metadata.CacheKey = cacheKey
+			cached, err := p.loadCachedResponse(opts.CacheDir, cacheKey)
+			if err != nil {
+				p.logger.Warn("读取缓存失败，重新调用API", zap.String("cacheKey", cacheKey), zap.Error(err))
+			} else if cached != nil {
+				p.logger.Info("命中OCR响应缓存", zap.String("filePath", filePath), zap.String("cacheKey", cacheKey))
+				metadata.FromCache = true
+				return p.saveDocument(cached, filePath, opts, metadata, startTime)
+			}
+		}
+	}
+
+	// 记录各请求的尝试次数，写入元数据和处理结果
+	ctx, metadata.Attempts = p.withAttemptStats(ctx)
+	if metadata.SourceSizeBytes > 0 {
+		ctx = p.withDocumentSize(ctx, metadata.SourceSizeBytes)
 	}
+	ctx, _ = p.withFileAPIKey(ctx)
 
 	// 上传PDF文件
 	p.logger.Debug("上传PDF文件...")
-	fileID, apiKey, err := p.client.UploadPDF(filePath)
+	reportProgress(opts, ProgressEvent{Stage: StageUpload})
+	phaseStart := time.Now()
+	fileID, apiKey, err := p.uploadFile(ctx, filePath, opts)
+	metadata.Timings.Upload += time.Since(phaseStart)
 	if err != nil {
 		p.logger.Error("上传PDF文件失败", zap.Error(err), zap.String("filePath", filePath))
 		return nil, fmt.Errorf("上传PDF文件失败: %w", err)
@@ -106,20 +167,131 @@ func (p *Processor) ProcessFile(filePath string, opts ProcessOptions) (*ProcessR
 
 	// 获取签名URL
 	p.logger.Debug("获取签名URL...")
-	signedURL, err := p.client.GetSignedURL(fileID, apiKey)
+	reportProgress(opts, ProgressEvent{Stage: StageSignedURL})
+	phaseStart = time.Now()
+	signedURL, err := p.client.GetSignedURL(ctx, fileID, apiKey)
+	metadata.Timings.SignedURL += time.Since(phaseStart)
 	if err != nil {
 		p.logger.Error("获取签名URL失败", zap.Error(err), zap.String("fileID", fileID))
 		return nil, fmt.Errorf("获取签名URL失败: %w", err)
 	}
 	metadata.DocumentURL = signedURL
-	p.logger.Debug("获取到签名URL", zap.String("url", signedURL))
+	p.logUploadedFile(opts, fileID, signedURL)
+
+	// 归档Mistral实际处理的内容，解密后上传的PDF与源文件不同，不做核对
+	expectedSHA256 := metadata.SourceSHA256
+	if opts.pdfPasswordFor(filePath) != "" {
+		expectedSHA256 = ""
+	}
+	p.archiveSource(ctx, signedURL, filePath, expectedSHA256, opts, &metadata)
 
 	// 使用OCR处理文档
-	return p.processDocument(signedURL, filePath, opts, metadata, startTime, apiKey)
+	opts.documentPages = p.countPDFFilePages(filePath, opts)
+	return p.processDocument(ctx, signedURL, filePath, opts, metadata, startTime, apiKey)
+}
+
+// uploadFile 上传本地文件，设置了PDF密码且文件已加密时先在内存中解密再上传
+func (p *Processor) uploadFile(ctx context.Context, filePath string, opts ProcessOptions) (string, string, error) {
+	password := opts.pdfPasswordFor(filePath)
+	if password == "" {
+		return p.uploadPDF(ctx, filePath)
+	}
+
+	data, err := p.openFiles.readFile(filePath)
+	if err != nil {
+		return "", "", fmt.Errorf("读取文件失败: %w", err)
+	}
+	decrypted, err := p.decryptPDF(data, filePath, password)
+	if err != nil {
+		return "", "", err
+	}
+	return p.client.UploadReader(ctx, bytes.NewReader(decrypted), filepath.Base(filePath))
+}
+
+// uploadPDF 上传本地文件，上传期间文件一直打开，占用一个文件操作的名额
+func (p *Processor) uploadPDF(ctx context.Context, filePath string) (string, string, error) {
+	release := p.openFiles.acquire()
+	defer release()
+	return p.client.UploadPDF(ctx, filePath)
+}
+
+// downloadToFile 将URL的内容下载到本地文件，下载期间目标文件一直打开，占用一个文件操作的名额
+func (p *Processor) downloadToFile(ctx context.Context, sourceURL string, dstPath string) error {
+	release := p.openFiles.acquire()
+	defer release()
+	return p.client.DownloadToFile(ctx, sourceURL, dstPath)
+}
+
+// decryptPDF 使用密码解密PDF内容，未加密的内容原样返回
+func (p *Processor) decryptPDF(data []byte, name string, password string) ([]byte, error) {
+	decrypted, err := DecryptPDF(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("解密PDF失败 %s: %w", name, err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		p.logger.Info("已在本地解密PDF", zap.String("name", name))
+	}
+	return decrypted, nil
+}
+
+// withAttemptStats 为上下文附加尝试次数记录，调用方已提供记录时沿用调用方的记录
+func (p *Processor) withAttemptStats(ctx context.Context) (context.Context, *AttemptStats) {
+	if stats := attemptStatsFromContext(ctx); stats != nil {
+		return ctx, stats
+	}
+	stats := &AttemptStats{}
+	return WithAttemptStats(ctx, stats), stats
+}
+
+// withFileAPIKey 为一个文件的处理流程从轮询中取一个API密钥并固定在上下文中，调用方已固定密钥时沿用调用方的密钥
+//
+// 上传、获取签名URL和OCR请求都使用固定的密钥，每个文件只推进一次轮询，见 WithAPIKey。
+func (p *Processor) withFileAPIKey(ctx context.Context) (context.Context, string) {
+	if apiKey := pinnedAPIKey(ctx, ""); apiKey != "" {
+		return ctx, apiKey
+	}
+	apiKey := p.client.NextAPIKey()
+	return WithAPIKey(ctx, apiKey), apiKey
+}
+
+// checkUploadSize 检查发送的内容是否超过客户端配置的上传大小上限，后端没有大小上限时不检查
+func (p *Processor) checkUploadSize(size int64) error {
+	if limited, ok := p.client.(interface {
+		checkUploadSize(size int64) error
+	}); ok {
+		return limited.checkUploadSize(size)
+	}
+	return nil
+}
+
+// withDocumentSize 为上下文附加文档大小，客户端启用了按大小计算的超时时间时记录计算结果
+func (p *Processor) withDocumentSize(ctx context.Context, sizeBytes int64) context.Context {
+	if adaptive, ok := p.client.(interface {
+		AdaptiveTimeout(sizeBytes int64) (time.Duration, bool)
+	}); ok {
+		if timeout, ok := adaptive.AdaptiveTimeout(sizeBytes); ok {
+			p.logger.Debug("按文件大小计算请求超时时间", zap.Int64("sizeBytes", sizeBytes), zap.Duration("timeout", timeout))
+		}
+	}
+	return WithDocumentSize(ctx, sizeBytes)
+}
+
+// logUploadedFile 记录上传文件的ID和签名URL，设置KeepUpload时以info级别输出，便于检查Mistral实际收到的文件
+func (p *Processor) logUploadedFile(opts ProcessOptions, fileID, signedURL string) {
+	if opts.KeepUpload {
+		p.logger.Info("保留上传的文件", zap.String("fileID", fileID), zap.String("signedURL", signedURL))
+		return
+	}
+	p.logger.Debug("获取到签名URL", zap.String("fileID", fileID), zap.String("url", signedURL))
 }
 
 // ProcessURL 直接处理URL
-func (p *Processor) ProcessURL(documentURL string, opts ProcessOptions) (*ProcessResult, error) {
+func (p *Processor) ProcessURL(ctx context.Context, documentURL string, opts ProcessOptions) (*ProcessResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts.openFiles = p.openFiles
+
 	startTime := time.Now()
 	p.logger.Info("开始处理URL", zap.String("url", documentURL))
 
@@ -129,45 +301,397 @@ func (p *Processor) ProcessURL(documentURL string, opts ProcessOptions) (*Proces
 		SourcePath:    documentURL,
 		OutputDir:     opts.OutputDir,
 		ProcessedAt:   startTime.Format(time.RFC3339),
-		IncludeImages: opts.IncludeImages,
+		IncludeImages: opts.savesImages(),
 		DocumentURL:   documentURL,
+		Timings:       &PhaseTimings{},
+	}
+
+	// 根据URL判断是文档还是图片
+	metadata.DocumentType = p.detectDocumentType(ctx, documentURL, opts)
+	p.logger.Debug("确定文档类型", zap.String("documentType", metadata.DocumentType))
+
+	// 记录各请求的尝试次数，写入元数据和处理结果
+	ctx, metadata.Attempts = p.withAttemptStats(ctx)
+
+	// 使用OCR处理文档，下载后上传时沿用同一个API密钥
+	ctx, apiKey := p.withFileAPIKey(ctx)
+	result, err := p.processDocument(ctx, documentURL, "", opts, metadata, startTime, apiKey)
+	if err != nil && opts.FallbackToUpload && ctx.Err() == nil && isDocumentFetchError(err) {
+		p.logger.Warn("API无法访问该URL，改为下载后上传", zap.String("url", documentURL), zap.Error(err))
+		return p.processURLViaUpload(ctx, documentURL, opts, metadata, startTime)
+	}
+	return result, err
+}
+
+// processURLViaUpload 在本地下载URL指向的文件，再通过上传流程进行OCR
+func (p *Processor) processURLViaUpload(ctx context.Context, documentURL string, opts ProcessOptions, metadata ProcessMetadata, startTime time.Time) (*ProcessResult, error) {
+	// 保留原始扩展名，便于API识别文件类型
+	ext := ""
+	if parsed, err := url.Parse(documentURL); err == nil {
+		ext = path.Ext(parsed.Path)
+	}
+
+	tmpFile, err := os.CreateTemp("", "mistral-ocr-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	phaseStart := time.Now()
+	if err := p.downloadToFile(ctx, documentURL, tmpPath); err != nil {
+		p.logger.Error("下载文件失败", zap.Error(err), zap.String("url", documentURL))
+		return nil, fmt.Errorf("下载文件失败: %w", err)
+	}
+
+	reportProgress(opts, ProgressEvent{Stage: StageUpload})
+	fileID, apiKey, err := p.uploadPDF(ctx, tmpPath)
+	metadata.Timings.Upload += time.Since(phaseStart)
+	if err != nil {
+		p.logger.Error("上传文件失败", zap.Error(err), zap.String("url", documentURL))
+		return nil, fmt.Errorf("上传文件失败: %w", err)
+	}
+	metadata.FileID = fileID
+
+	reportProgress(opts, ProgressEvent{Stage: StageSignedURL})
+	phaseStart = time.Now()
+	signedURL, err := p.client.GetSignedURL(ctx, fileID, apiKey)
+	metadata.Timings.SignedURL += time.Since(phaseStart)
+	if err != nil {
+		p.logger.Error("获取签名URL失败", zap.Error(err), zap.String("fileID", fileID))
+		return nil, fmt.Errorf("获取签名URL失败: %w", err)
+	}
+	metadata.DocumentURL = signedURL
+	p.logUploadedFile(opts, fileID, signedURL)
+
+	opts.documentPages = p.countPDFFilePages(tmpPath, opts)
+	return p.processDocument(ctx, signedURL, "", opts, metadata, startTime, apiKey)
+}
+
+// inlineDocumentMaxBytes 直接以data URL内联发送的文档大小上限，超过时先上传再处理。
+// base64编码会使请求体增大约三分之一，因此该上限远小于上传文件的大小上限。
+const inlineDocumentMaxBytes = 4 * 1024 * 1024
+
+// ProcessBytes 处理内存中的文档内容，name用于确定输出目录名和上传时的文件名
+//
+// 较小的内容以base64 data URL直接发送给OCR接口，较大的内容通过上传流程处理，
+// 不需要先写入临时文件。内联发送的上限为4MB；无论内联还是上传，内容（加密的PDF为解密后的内容）
+// 都不能超过客户端配置的上传大小上限（默认50MB，见 SetMaxUploadSizeMB）。
+func (p *Processor) ProcessBytes(ctx context.Context, data []byte, name string, opts ProcessOptions) (*ProcessResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts.openFiles = p.openFiles
+
+	startTime := time.Now()
+	p.logger.Info("开始处理内存中的文档", zap.String("name", name), zap.Int("size", len(data)))
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("文档内容为空")
+	}
+
+	// 根据内容识别图片，其余按文档处理
+	mimeType := "application/pdf"
+	documentType := DocumentTypeDocument
+	if format := detectImageFormat(data); format != "" {
+		mimeType = "image/" + strings.TrimPrefix(format, ".")
+		documentType = DocumentTypeImage
+	}
+
+	// 创建元数据
+	metadata := ProcessMetadata{
+		SourceType:    "bytes",
+		SourcePath:    name,
+		OutputDir:     opts.OutputDir,
+		ProcessedAt:   startTime.Format(time.RFC3339),
+		IncludeImages: opts.savesImages(),
+		DocumentType:  documentType,
+		Timings:       &PhaseTimings{},
+	}
+	sum := sha256.Sum256(data)
+	metadata.SourceSHA256 = hex.EncodeToString(sum[:])
+	metadata.SourceSizeBytes = int64(len(data))
+
+	// 检查本地缓存，命中时直接使用缓存的响应，不调用API
+	if opts.CacheDir != "" {
+		cacheKey, err := computeCacheKeyFromReader(bytes.NewReader(data), opts)
+		if err != nil {
+			p.logger.Warn("计算缓存键失败，跳过缓存", zap.String("name", name), zap.Error(err))
+		} else {
+			metadata.CacheKey = cacheKey
+			cached, err := p.loadCachedResponse(opts.CacheDir, cacheKey)
+			if err != nil {
+				p.logger.Warn("读取缓存失败，重新调用API", zap.String("cacheKey", cacheKey), zap.Error(err))
+			} else if cached != nil {
+				p.logger.Info("命中OCR响应缓存", zap.String("name", name), zap.String("cacheKey", cacheKey))
+				metadata.FromCache = true
+				return p.saveDocument(cached, name, opts, metadata, startTime)
+			}
+		}
+	}
+
+	// 加密的PDF先在本地解密
+	if password := opts.pdfPasswordFor(name); password != "" && documentType == DocumentTypeDocument {
+		decrypted, err := p.decryptPDF(data, name, password)
+		if err != nil {
+			return nil, err
+		}
+		data = decrypted
+	}
+	if documentType == DocumentTypeDocument {
+		opts.documentPages = p.countPDFPages(data, name, opts)
+	}
+
+	// 记录各请求的尝试次数，写入元数据和处理结果
+	ctx, metadata.Attempts = p.withAttemptStats(ctx)
+	ctx = p.withDocumentSize(ctx, int64(len(data)))
+	ctx, apiKey := p.withFileAPIKey(ctx)
+
+	// 内联发送同样遵守客户端的上传大小上限，上限设置得比内联上限小时不会绕过
+	if err := p.checkUploadSize(int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	// 小文件直接内联，元数据中不记录完整的data URL
+	if len(data) <= inlineDocumentMaxBytes {
+		p.logger.Debug("以data URL内联发送文档", zap.String("mimeType", mimeType))
+		documentURL := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+		metadata.DocumentURL = "data:" + mimeType + ";base64,..."
+		return p.processDocument(ctx, documentURL, name, opts, metadata, startTime, apiKey)
+	}
+
+	reportProgress(opts, ProgressEvent{Stage: StageUpload})
+	phaseStart := time.Now()
+	fileID, apiKey, err := p.client.UploadReader(ctx, bytes.NewReader(data), filepath.Base(name))
+	metadata.Timings.Upload += time.Since(phaseStart)
+	if err != nil {
+		p.logger.Error("上传文档失败", zap.Error(err), zap.String("name", name))
+		return nil, fmt.Errorf("上传文档失败: %w", err)
+	}
+	metadata.FileID = fileID
+
+	reportProgress(opts, ProgressEvent{Stage: StageSignedURL})
+	phaseStart = time.Now()
+	signedURL, err := p.client.GetSignedURL(ctx, fileID, apiKey)
+	metadata.Timings.SignedURL += time.Since(phaseStart)
+	if err != nil {
+		p.logger.Error("获取签名URL失败", zap.Error(err), zap.String("fileID", fileID))
+		return nil, fmt.Errorf("获取签名URL失败: %w", err)
 	}
+	metadata.DocumentURL = signedURL
+	p.logUploadedFile(opts, fileID, signedURL)
+
+	// 归档Mistral实际处理的内容，与上传的内容（解密后）核对
+	uploadedSum := sha256.Sum256(data)
+	p.archiveSource(ctx, signedURL, name, hex.EncodeToString(uploadedSum[:]), opts, &metadata)
+
+	return p.processDocument(ctx, signedURL, name, opts, metadata, startTime, apiKey)
+}
+
+// imageExtensions 可以作为image_url处理的图片扩展名
+var imageExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".webp": true,
+	".bmp":  true,
+	".tif":  true,
+	".tiff": true,
+	".avif": true,
+}
 
-	// 使用OCR处理文档 - 对于直接URL，我们可以使用随机的API密钥
-	apiKey := p.client.getNextAPIKey()
-	return p.processDocument(documentURL, "", opts, metadata, startTime, apiKey)
+// detectDocumentType 根据URL扩展名（必要时通过HEAD请求的Content-Type）判断文档类型
+func (p *Processor) detectDocumentType(ctx context.Context, documentURL string, opts ProcessOptions) string {
+	if opts.ForceImageURL {
+		return DocumentTypeImage
+	}
+
+	// 优先根据URL路径的扩展名判断
+	if parsed, err := url.Parse(documentURL); err == nil {
+		ext := strings.ToLower(path.Ext(parsed.Path))
+		if imageExtensions[ext] {
+			return DocumentTypeImage
+		}
+		if ext == ".pdf" {
+			return DocumentTypeDocument
+		}
+	}
+
+	// 扩展名无法判断时，通过Content-Type判断
+	contentType, err := p.client.DetectContentType(ctx, documentURL)
+	if err != nil {
+		p.logger.Debug("获取Content-Type失败，按文档处理", zap.String("url", documentURL), zap.Error(err))
+		return DocumentTypeDocument
+	}
+	if strings.HasPrefix(strings.ToLower(contentType), "image/") {
+		return DocumentTypeImage
+	}
+	return DocumentTypeDocument
 }
 
 // processDocument 处理文档并返回结果
-func (p *Processor) processDocument(documentURL string, originalFile string, opts ProcessOptions, metadata ProcessMetadata, startTime time.Time, apiKey string) (*ProcessResult, error) {
+func (p *Processor) processDocument(ctx context.Context, documentURL string, originalFile string, opts ProcessOptions, metadata ProcessMetadata, startTime time.Time, apiKey string) (*ProcessResult, error) {
 	// 使用OCR处理文档
 	p.logger.Debug("进行OCR处理...")
-	ocrResponse, err := p.client.ProcessOCR(documentURL, opts.IncludeImages, apiKey)
+	reportProgress(opts, ProgressEvent{Stage: StageOCR})
+	documentType := metadata.DocumentType
+	if documentType == "" {
+		documentType = DocumentTypeDocument
+	}
+
+	// 流式解析时图片在解析过程中直接写入输出目录，需要先确定输出目录
+	runOCR := func(documentURL string) (*OCRResponse, error) {
+		return p.client.ProcessOCRWithType(ctx, documentURL, documentType, opts.savesImages(), apiKey)
+	}
+	outputDir := ""
+	if opts.StreamLargeResponses {
+		if backend, ok := p.client.(StreamingOCRBackend); ok {
+			outputName, err := opts.resolveOutputName(sourceBaseName(originalFile), 1)
+			if err != nil {
+				return nil, err
+			}
+			opts.CustomOutputName = outputName
+			outputDir = filepath.Join(opts.OutputDir, outputName)
+			if opts.AtomicOutput {
+				stagingDir, err := opts.newStagingDir()
+				if err != nil {
+					return nil, err
+				}
+				defer os.RemoveAll(stagingDir)
+				opts.stagingDir = stagingDir
+				outputDir = stagingDir
+			}
+			runOCR = func(documentURL string) (*OCRResponse, error) {
+				return p.streamOCR(ctx, backend, documentURL, documentType, apiKey, outputDir, opts)
+			}
+		} else {
+			p.logger.Warn("OCR后端不支持流式解析，使用普通方式处理")
+		}
+	}
+
+	// 设置了 MaxPages 时只处理前 MaxPages 页；页数超过 ChunkPages 时分批请求页面，再按顺序合并
+	totalPages, limited := opts.documentPages, false
+	if opts.MaxPages > 0 && documentType == DocumentTypeDocument {
+		switch {
+		case totalPages == 0:
+			// 页数未知时无法确定哪些页面存在，请求不存在的页面会导致整个请求失败，因此处理整个文档
+			p.logger.Warn("无法读取文档页数，忽略最大页数限制并处理整个文档", zap.Int("maxPages", opts.MaxPages))
+			opts.MaxPages = 0
+		case totalPages > opts.MaxPages:
+			totalPages, limited = opts.MaxPages, true
+		}
+	}
+	if chunked := opts.ChunkPages > 0 && totalPages > opts.ChunkPages; chunked || limited {
+		if backend, ok := p.client.(PageRangeOCRBackend); ok {
+			runOCR = func(documentURL string) (*OCRResponse, error) {
+				if chunked {
+					return p.chunkedOCR(ctx, backend, documentURL, documentType, apiKey, totalPages, opts)
+				}
+				p.logger.Info("只处理文档的前几页", zap.Int("maxPages", opts.MaxPages))
+				return backend.ProcessOCRPages(ctx, documentURL, documentType, opts.savesImages(), apiKey, pageRange(0, totalPages))
+			}
+		} else {
+			p.logger.Warn("OCR后端不支持按页面范围处理，一次处理整个文档")
+		}
+	}
+
+	if metadata.Timings == nil {
+		metadata.Timings = &PhaseTimings{}
+	}
+	phaseStart := time.Now()
+	ocrResponse, err := runOCR(documentURL)
+
+	// 对于上传的文件，签名URL过期或失效时重新获取一次签名URL再重试OCR
+	if err != nil && metadata.FileID != "" && isDocumentURLExpired(err) {
+		p.logger.Warn("签名URL已失效，重新获取后重试", zap.String("fileID", metadata.FileID), zap.Error(err))
+		signedURL, signErr := p.client.GetSignedURL(ctx, metadata.FileID, apiKey)
+		if signErr != nil {
+			p.logger.Error("重新获取签名URL失败", zap.Error(signErr), zap.String("fileID", metadata.FileID))
+		} else {
+			documentURL = signedURL
+			metadata.DocumentURL = signedURL
+			ocrResponse, err = runOCR(documentURL)
+		}
+	}
+	metadata.Timings.OCR += time.Since(phaseStart)
 	if err != nil {
-		p.logger.Error("OCR处理失败", zap.Error(err), zap.String("documentURL", documentURL))
+		p.logger.Error("OCR处理失败", zap.Error(err), zap.String("documentURL", displayURL(documentURL)))
 		return nil, fmt.Errorf("OCR处理失败: %w", err)
 	}
 	p.logger.Debug("OCR处理完成", zap.Int("pages", len(ocrResponse.Pages)))
 
-	// 确定输出文件名
-	outputName := opts.CustomOutputName
-	if outputName == "" && originalFile != "" {
-		// 使用原始文件名(不带扩展名)
-		outputName = strings.TrimSuffix(filepath.Base(originalFile), filepath.Ext(originalFile))
-	} else if outputName == "" {
-		// 使用时间戳作为默认名称
-		outputName = fmt.Sprintf("ocr-result-%d", time.Now().Unix())
+	// 写入缓存，供下次处理相同文件时使用
+	if opts.CacheDir != "" && metadata.CacheKey != "" && ocrResponse.RawResponse != nil {
+		p.saveCachedResponse(opts.CacheDir, metadata.CacheKey, ocrResponse.RawResponse, opts)
+	}
+	if opts.CacheDir != "" && metadata.CacheKey != "" && ocrResponse.rawResponseFile != "" {
+		p.saveCachedResponseFile(opts.CacheDir, metadata.CacheKey, filepath.Join(outputDir, ocrResponse.rawResponseFile), opts)
 	}
 
-	// 创建输出目录
-	outputDir := filepath.Join(opts.OutputDir, outputName)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
+	return p.saveDocument(ocrResponse, originalFile, opts, metadata, startTime)
+}
+
+// streamOCR 流式进行OCR，解析过程中将图片直接保存到输出目录，原始响应写入response.json
+func (p *Processor) streamOCR(ctx context.Context, backend StreamingOCRBackend, documentURL string, documentType string, apiKey string, outputDir string, opts ProcessOptions) (*OCRResponse, error) {
+	saveImages, _, _ := opts.imageBehavior()
+	imagesDir := filepath.Join(outputDir, "images")
+	dir := outputDir
+	if saveImages {
+		dir = imagesDir
+	}
+	if err := opts.mkdirAll(dir); err != nil {
 		return nil, fmt.Errorf("创建输出目录错误: %w", err)
 	}
 
+	rawFile, err := opts.createFile(filepath.Join(outputDir, RawResponseFileName))
+	if err != nil {
+		return nil, fmt.Errorf("创建原始响应文件错误: %w", err)
+	}
+	defer rawFile.Close()
+
+	imageMap := make(map[string]string)
+	usedFilenames := make(map[string]bool)
+	dedup := newImageDedup(opts)
+	var failedImages []string
+	resp, err := backend.ProcessOCRStream(ctx, documentURL, documentType, saveImages, apiKey, rawFile, func(page Page) error {
+		if saveImages {
+			p.savePageImages(page, imagesDir, imageMap, usedFilenames, dedup, &failedImages, opts)
+		}
+		p.logger.Debug("流式解析了页面", zap.Int("pageIndex", page.Index), zap.Int("images", len(page.Images)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := rawFile.Close(); err != nil {
+		return nil, fmt.Errorf("写入原始响应文件错误: %w", err)
+	}
+
+	resp.streamedImages = imageMap
+	resp.streamedDedup = dedup
+	resp.streamedFailed = failedImages
+	resp.rawResponseFile = RawResponseFileName
+	return resp, nil
+}
+
+// saveDocument 根据OCR响应生成输出目录和结果文件
+func (p *Processor) saveDocument(ocrResponse *OCRResponse, originalFile string, opts ProcessOptions, metadata ProcessMetadata, startTime time.Time) (*ProcessResult, error) {
+	// 确定输出文件名，默认使用原始文件名(不带扩展名)，没有原始文件时使用时间戳
+	outputName, err := opts.resolveOutputName(sourceBaseName(originalFile), 1)
+	if err != nil {
+		return nil, err
+	}
+
+	outputDir := filepath.Join(opts.OutputDir, outputName)
+
 	// 更新元数据
 	metadata.PagesProcessed = len(ocrResponse.Pages)
 	metadata.OutputDir = outputDir
+	if opts.MaxPages > 0 && metadata.DocumentType != DocumentTypeImage {
+		metadata.MaxPages = opts.MaxPages
+	}
 	metadata.OCRResponseInfo = map[string]any{
 		"model":           ocrResponse.Model,
 		"pages_processed": ocrResponse.UsageInfo.PagesProcessed,
@@ -176,159 +700,488 @@ func (p *Processor) processDocument(documentURL string, originalFile string, opt
 		metadata.OCRResponseInfo["doc_size_bytes"] = *ocrResponse.UsageInfo.DocSizeBytes
 	}
 
-	// 设置原始响应到元数据
+	// 设置原始响应到元数据，流式解析时原始响应已单独写入文件
 	if ocrResponse.RawResponse != nil {
 		metadata.RawResponse = json.RawMessage(ocrResponse.RawResponse)
+	} else if ocrResponse.rawResponseFile != "" {
+		metadata.RawResponseFile = ocrResponse.rawResponseFile
 	}
 
+	// 记录到保存结果前为止的处理耗时
+	metadata.ProcessingTime = time.Since(startTime).Round(time.Millisecond).String()
+
 	// 处理并保存结果
-	result, err := p.saveResults(ocrResponse, outputDir, metadata, opts.IncludeImages)
+	if metadata.Timings == nil {
+		metadata.Timings = &PhaseTimings{}
+	}
+	phaseStart := time.Now()
+	result, err := p.saveOutput(ocrResponse, outputDir, metadata, opts)
 	if err != nil {
 		return nil, fmt.Errorf("保存结果失败: %w", err)
 	}
+	metadata.Timings.Save += time.Since(phaseStart)
+	result.Timings = *metadata.Timings
+
+	// 记录本次API调用的用量，缓存命中不产生费用
+	if !metadata.FromCache {
+		result.Usage = ocrResponse.UsageInfo
+	}
+	if metadata.Attempts != nil {
+		result.Attempts = *metadata.Attempts
+		p.logger.Info("请求尝试统计",
+			zap.Int("attemptsUpload", result.Attempts.AttemptsUpload),
+			zap.Int("attemptsSignedURL", result.Attempts.AttemptsSignedURL),
+			zap.Int("attemptsOCR", result.Attempts.AttemptsOCR),
+			zap.Int("retries", result.Attempts.Retries()),
+			zap.Int("endpointSwitches", result.Attempts.EndpointSwitches),
+			zap.String("endpointUsed", result.Attempts.EndpointUsed))
+	}
+
+	reportProgress(opts, ProgressEvent{Stage: StageDone, Current: result.Pages, Total: result.Pages})
 
 	elapsedTime := time.Since(startTime)
 	result.ProcessedAt = elapsedTime.String()
 	p.logger.Info("处理完成",
 		zap.String("outputDir", result.OutputDir),
 		zap.Int("pages", result.Pages),
-		zap.String("processTime", result.ProcessedAt))
+		zap.String("processTime", result.ProcessedAt),
+		zap.Duration("upload", result.Timings.Upload),
+		zap.Duration("signedURL", result.Timings.SignedURL),
+		zap.Duration("ocr", result.Timings.OCR),
+		zap.Duration("save", result.Timings.Save))
 
 	return result, nil
 }
 
 // saveResults 保存OCR处理结果
-func (p *Processor) saveResults(resp *OCRResponse, outputDir string, metadata ProcessMetadata, includeImages bool) (*ProcessResult, error) {
+func (p *Processor) saveResults(resp *OCRResponse, outputDir string, metadata ProcessMetadata, opts ProcessOptions) (*ProcessResult, error) {
 	var allMarkdown strings.Builder
 	var allText strings.Builder
+	var pageMarkdowns []string
+	var pageResults []PageResult
+	var templatePages []MarkdownTemplatePage
 	imageCount := 0
 	imagesDir := outputDir
+	saveImages, linkImages, keepImages := opts.imageBehavior()
 
 	// 如果需要保存图片，创建images子目录
-	if includeImages {
+	if saveImages {
 		imagesDir = filepath.Join(outputDir, "images")
-		if err := os.MkdirAll(imagesDir, 0755); err != nil {
+		if err := opts.mkdirAll(imagesDir); err != nil {
 			return nil, fmt.Errorf("创建images子目录错误: %w", err)
 		}
 	}
 
-	// 图片ID到本地路径的映射
-	imageMap := make(map[string]string)
+	// 进入保存阶段，此时已知总页数
+	reportProgress(opts, ProgressEvent{Stage: StageSave, Total: len(resp.Pages)})
 
-	// 保存图片（如果有）
-	if includeImages {
+	// 图片ID到本地路径的映射，流式解析时图片已经在解析过程中保存
+	imageMap := make(map[string]string)
+	usedFilenames := make(map[string]bool)
+	dedup := newImageDedup(opts)
+	var failedImages []string
+	if resp.streamedImages != nil {
+		imageMap = resp.streamedImages
+		dedup = resp.streamedDedup
+		failedImages = resp.streamedFailed
+		imageCount = len(imageMap) - dedup.duplicateCount()
+	} else if saveImages {
+		// 保存图片（如果有）
 		for _, page := range resp.Pages {
-			for _, img := range page.Images {
-				if img.ImageBase64 != "" && img.ImageBase64 != "..." {
-					// 处理data:image/jpeg;base64,格式的图片数据
-					imgData := img.ImageBase64
-					// 检查是否是Data URL格式
-					if strings.HasPrefix(imgData, "data:") {
-						// 提取base64部分
-						parts := strings.Split(imgData, ",")
-						if len(parts) == 2 {
-							imgData = parts[1]
-							// 处理URL编码的换行符
-							imgData = strings.ReplaceAll(imgData, "\n", "")
-							imgData = strings.ReplaceAll(imgData, "\r", "")
-							// 移除所有空白字符
-							imgData = strings.ReplaceAll(imgData, " ", "")
-						} else {
-							p.logger.Warn("解析图片数据URL格式失败", zap.String("imageID", img.ID))
-							continue
-						}
-					}
-
-					// 解码base64数据
-					decodedData, err := base64.StdEncoding.DecodeString(imgData)
-					if err != nil {
-						p.logger.Warn("解码图片失败", zap.String("imageID", img.ID), zap.Error(err))
-						continue
-					}
-
-					// 确定图片文件名
-					imgFilename := img.ID
-					if !strings.Contains(imgFilename, ".") {
-						imgFilename += ".jpeg" // 添加默认扩展名
-					}
-
-					imgPath := filepath.Join(imagesDir, imgFilename)
-					if err = os.WriteFile(imgPath, decodedData, 0644); err != nil {
-						p.logger.Warn("保存图片失败", zap.String("imageID", img.ID), zap.Error(err))
-						continue
-					}
+			imageCount += p.savePageImages(page, imagesDir, imageMap, usedFilenames, dedup, &failedImages, opts)
+		}
+	}
 
-					// 记录图片ID到相对路径的映射
-					imageMap[img.ID] = filepath.Join("images", imgFilename)
-					imageCount++
-					p.logger.Debug("保存图片", zap.String("imageID", img.ID), zap.String("path", imgPath))
+	// 按 OnImageError 处理无法解码的图片的链接，占位图片不计入保存的图片，也不加入 image_files
+	linkMap := imageMap
+	failedIDs := make(map[string]bool, len(failedImages))
+	for _, id := range failedImages {
+		failedIDs[id] = true
+	}
+	if len(failedImages) > 0 {
+		metadata.FailedImages = failedImages
+		p.logger.Warn("部分图片无法解码", zap.Int("failed", len(failedImages)), zap.String("onImageError", opts.OnImageError))
+		if opts.OnImageError == OnImageErrorPlaceholder {
+			if placeholder, err := savePlaceholderImage(imagesDir, imageMap, opts); err != nil {
+				p.logger.Warn("保存占位图片失败", zap.Error(err))
+			} else {
+				linkMap = make(map[string]string, len(imageMap)+len(failedImages))
+				for id, relPath := range imageMap {
+					linkMap[id] = relPath
+				}
+				for _, id := range failedImages {
+					linkMap[id] = placeholder
 				}
 			}
 		}
 	}
 
-	// 更新元数据中的图片计数
+	// 更新元数据中的图片计数，并记录图片ID与保存的文件的对应关系
 	metadata.ImagesSaved = imageCount
+	if dedup != nil && dedup.duplicates > 0 {
+		metadata.DuplicateImages = dedup.duplicates
+		metadata.DedupBytesSaved = dedup.bytesSaved
+		p.logger.Debug("图片去重", zap.Int("duplicates", dedup.duplicates), zap.Int64("bytesSaved", dedup.bytesSaved))
+	}
+	if imageCount > 0 {
+		metadata.ImageFiles = make(map[string]string, len(imageMap))
+		for id, relPath := range imageMap {
+			metadata.ImageFiles[id] = filepath.ToSlash(relPath)
+		}
+	}
+
+	// 记录每页的尺寸和方向，不影响markdown输出
+	metadata.PageInfo = pageInfos(resp.Pages)
+	if landscape := landscapePages(metadata.PageInfo); landscape > 0 {
+		p.logger.Debug("文档包含横向页面", zap.Int("landscape", landscape), zap.Int("pages", len(resp.Pages)))
+	}
+
+	// 按页面顺序将保存的图片合并为PDF
+	if saveImages && opts.BundleImages == BundleImagesPDF && imageCount > 0 {
+		if bundled, err := p.bundleImagesPDF(resp.Pages, imageMap, outputDir, opts); err != nil {
+			p.logger.Warn("合并图片为PDF失败", zap.Error(err))
+		} else if bundled > 0 {
+			metadata.ImagesPDF = ImagesPDFFileName
+			p.logger.Debug("合并图片为PDF", zap.String("path", filepath.Join(outputDir, ImagesPDFFileName)), zap.Int("pages", bundled))
+		}
+	}
 
 	// 处理每个页面的内容
 	for i, page := range resp.Pages {
 		p.logger.Debug("处理页面", zap.Int("pageNum", i+1))
+		reportProgress(opts, ProgressEvent{Stage: StageSave, Current: i + 1, Total: len(resp.Pages)})
 
 		// 替换markdown中的图片链接（如果有图片）
 		markdown := page.Markdown
-		if includeImages {
-			for imgID, localPath := range imageMap {
-				// 替换形如 ![img-0.jpeg](img-0.jpeg) 的链接
-				markdown = strings.ReplaceAll(markdown,
-					"!["+imgID+"]("+imgID+")",
-					"!["+imgID+"]("+localPath+")")
+		switch {
+		case !keepImages:
+			// 移除所有图片链接，只保留正文
+			markdown = removeImageLinks(markdown)
+		case linkImages:
+			// 不保存图片时引用images目录下已有的图片，文件名规则（包括同名时的序号后缀）与保存时一致
+			if !saveImages {
+				for j, img := range page.Images {
+					if _, ok := imageMap[img.ID]; !ok {
+						imageMap[img.ID] = path.Join("images", opts.uniqueImageFileName(page, j, nil, usedFilenames))
+					}
+				}
 			}
+			// 替换形如 ![img-0.jpeg](img-0.jpeg) 的链接
+			markdown = rewriteImageLinks(markdown, opts.imageLinks(linkMap))
+		}
+		if keepImages && opts.OnImageError == OnImageErrorRemoveLink {
+			markdown = removeImageLinksTo(markdown, failedIDs)
 		}
 
-		allMarkdown.WriteString(markdown)
-		allMarkdown.WriteString("\n\n")
+		// Unicode规范化和自定义处理在图片链接改写之后、文本提取之前进行
+		if opts.NormalizeUnicode {
+			markdown = normalizeUnicode(markdown)
+		}
+		if opts.FullWidthToHalfWidth {
+			markdown = fullWidthToHalfWidth(markdown)
+		}
+		if opts.PageTransform != nil {
+			markdown = opts.PageTransform(page.Index, markdown)
+		}
+
+		pageMarkdowns = append(pageMarkdowns, markdown)
 
 		// 提取文本
 		text := extractTextFromMarkdown(markdown)
+
+		// 文本过短的页面（如空白分隔页）不加入合并输出
+		omitted := opts.MinPageTextLength > 0 && utf8.RuneCountInString(strings.TrimSpace(text)) < opts.MinPageTextLength
+
+		if opts.ReturnContent {
+			pageText := text
+			if opts.DehyphenateText {
+				pageText = dehyphenate(pageText)
+			}
+			pageResults = append(pageResults, PageResult{
+				Index:    page.Index,
+				Markdown: markdown,
+				Text:     pageText,
+				Images:   pageImageRefs(page, imageMap),
+				Omitted:  omitted,
+			})
+		}
+
+		if omitted {
+			p.logger.Debug("页面文本过短，从合并输出中移除", zap.Int("pageNum", i+1))
+			metadata.PagesDropped++
+			continue
+		}
+
+		allMarkdown.WriteString(markdown)
+		allMarkdown.WriteString("\n\n")
+		if opts.MarkdownTemplate != "" {
+			templatePages = append(templatePages, MarkdownTemplatePage{Index: page.Index, Number: page.Index + 1, Markdown: markdown})
+		}
 		allText.WriteString(text)
 		allText.WriteString("\n\n")
 	}
 
+	// 响应中包含结构化标注（表格、图片标注等）时单独保存
+	if saved, err := p.saveAnnotations(resp, outputDir, opts); err != nil {
+		p.logger.Warn("保存结构化标注失败", zap.Error(err))
+	} else if saved {
+		metadata.AnnotationsFile = AnnotationsFileName
+		p.logger.Debug("保存了结构化标注文件", zap.String("path", filepath.Join(outputDir, AnnotationsFileName)))
+	}
+
+	// 响应中包含单词级信息时按页保存单词位置
+	if opts.WordBoxes {
+		if saved, err := p.saveWordFiles(resp.Pages, outputDir, opts); err != nil {
+			p.logger.Warn("保存单词位置失败", zap.Error(err))
+		} else if saved > 0 {
+			metadata.WordsDir = WordsDirName
+		}
+	}
+
+	// 将原始响应单独保存到response.json，保持metadata.json精简
+	if opts.SeparateRawResponse && len(metadata.RawResponse) > 0 {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, metadata.RawResponse, "", "  "); err != nil {
+			p.logger.Warn("格式化原始响应失败", zap.Error(err))
+		} else {
+			responsePath := filepath.Join(outputDir, RawResponseFileName)
+			if err := opts.writeFile(responsePath, indented.Bytes()); err != nil {
+				p.logger.Warn("写入原始响应文件失败", zap.Error(err))
+			} else {
+				p.logger.Debug("保存了原始响应文件", zap.String("path", responsePath))
+				metadata.RawResponse = nil
+				metadata.RawResponseFile = RawResponseFileName
+			}
+		}
+	}
+
 	// 保存元数据到JSON文件
-	metadataPath := filepath.Join(outputDir, "metadata.json")
+	metadataPath := filepath.Join(outputDir, MetadataFileName)
 	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
 		p.logger.Warn("保存元数据失败", zap.Error(err))
 	} else {
-		if err := os.WriteFile(metadataPath, metadataJSON, 0644); err != nil {
+		if err := opts.writeFile(metadataPath, metadataJSON); err != nil {
 			p.logger.Warn("写入元数据文件失败", zap.Error(err))
 		} else {
 			p.logger.Debug("保存了元数据文件", zap.String("path", metadataPath))
 		}
 	}
 
-	// 保存markdown
+	// 保存markdown，设置了模板时按模板生成
+	outputMarkdown, err := opts.renderMarkdownTemplate(allMarkdown.String(), templatePages, metadata)
+	if err != nil {
+		return nil, err
+	}
 	mdPath := filepath.Join(outputDir, "output.md")
-	if err := os.WriteFile(mdPath, []byte(allMarkdown.String()), 0644); err != nil {
+	if err := opts.writeFile(mdPath, opts.withBOM([]byte(outputMarkdown))); err != nil {
 		return nil, fmt.Errorf("保存markdown输出错误: %w", err)
 	}
 	p.logger.Debug("保存了markdown文件", zap.String("path", mdPath))
 
+	// 根据标题生成目录，链接到output.md中的对应位置
+	if opts.GenerateTOC {
+		if toc := buildTOC(outputMarkdown, "output.md"); toc != "" {
+			tocPath := filepath.Join(outputDir, TOCFileName)
+			if err := opts.writeFile(tocPath, []byte(toc)); err != nil {
+				return nil, fmt.Errorf("保存目录错误: %w", err)
+			}
+			p.logger.Debug("保存了目录文件", zap.String("path", tocPath))
+		} else {
+			p.logger.Debug("文档中没有标题，跳过生成目录")
+		}
+	}
+
+	// 转换为LaTeX，只插入已保存到输出目录中的图片
+	if opts.wantsOutputFormat(OutputFormatLaTeX) {
+		images := make(map[string]string, len(imageMap))
+		for _, relPath := range imageMap {
+			if _, err := os.Stat(filepath.Join(outputDir, relPath)); err == nil {
+				images[opts.imageLink(relPath)] = relPath
+			}
+		}
+		texPath := filepath.Join(outputDir, LaTeXFileName)
+		if err := opts.writeFile(texPath, []byte(markdownToLaTeX(allMarkdown.String(), images))); err != nil {
+			return nil, fmt.Errorf("保存LaTeX输出错误: %w", err)
+		}
+		p.logger.Debug("保存了LaTeX文件", zap.String("path", texPath))
+	}
+
 	// 保存文本
+	text := allText.String()
+	if opts.DehyphenateText {
+		text = dehyphenate(text)
+	}
 	txtPath := filepath.Join(outputDir, "output.txt")
-	if err := os.WriteFile(txtPath, []byte(allText.String()), 0644); err != nil {
+	if err := opts.writeFile(txtPath, opts.withBOM([]byte(text))); err != nil {
 		return nil, fmt.Errorf("保存文本输出错误: %w", err)
 	}
 	p.logger.Debug("保存了文本文件", zap.String("path", txtPath))
 
+	// 追加到合并输出文件，用于逐步构建知识库
+	if opts.AppendTo != "" {
+		title := sourceBaseName(metadata.SourcePath)
+		if metadata.SourceType == "url" || title == "" {
+			title = filepath.Base(metadata.OutputDir)
+		}
+		sharedImages := imageMap
+		if !saveImages {
+			sharedImages = nil
+		}
+		if err := p.appendToCombined(opts.AppendTo, allMarkdown.String(), sharedImages, outputDir, title, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// 追加到语料文件，与output.txt的内容相同
+	if opts.CorpusFile != "" {
+		name := filepath.Base(metadata.SourcePath)
+		if metadata.SourceType == "url" {
+			name = displayURL(metadata.SourcePath)
+		}
+		if err := p.appendToCorpus(opts.CorpusFile, name, text, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// 按页拆分保存markdown
+	if opts.SplitPages {
+		if err := p.savePageFiles(pageMarkdowns, outputDir, opts); err != nil {
+			return nil, err
+		}
+	}
+
 	return &ProcessResult{
 		OutputDir:    outputDir,
 		ImagesDir:    imagesDir,
 		MetadataPath: metadataPath,
 		Pages:        len(resp.Pages),
+		Images:       imageCount,
+		PageResults:  pageResults,
 	}, nil
 }
 
+// pageImageRefs 返回页面中图片的引用，图片已保存或链接到本地时使用本地路径，否则使用图片ID
+func pageImageRefs(page Page, imageMap map[string]string) []string {
+	var refs []string
+	for _, img := range page.Images {
+		if path, ok := imageMap[img.ID]; ok {
+			refs = append(refs, filepath.ToSlash(path))
+			continue
+		}
+		refs = append(refs, img.ID)
+	}
+	return refs
+}
+
+// ExtractImages 解码响应中的图片，返回图片ID到图片内容的映射，不写入磁盘
+//
+// 解码方式与保存结果时相同（支持data URL和纯base64），没有图片数据的图片会被忽略，
+// 可以配合 ImageContentType 在Web服务中直接返回图片。流式解析的响应不保留图片数据，返回空映射。
+// 任一图片解码失败时返回错误。
+func (p *Processor) ExtractImages(resp *OCRResponse) (map[string][]byte, error) {
+	images := make(map[string][]byte)
+	if resp == nil {
+		return images, nil
+	}
+	for _, page := range resp.Pages {
+		for _, img := range page.Images {
+			if !hasImageData(img) {
+				continue
+			}
+			data, err := decodeImageData(img.ImageBase64)
+			if err != nil {
+				return nil, fmt.Errorf("解码图片 %s 失败: %w", img.ID, err)
+			}
+			images[img.ID] = data
+		}
+	}
+	return images, nil
+}
+
+// savePageImages 将页面中的图片保存到imagesDir，记录图片ID到相对路径（以 / 分隔，用作markdown链接）的映射，返回保存的图片数量
+//
+// dedup不为nil时，与已保存图片内容相同的图片不再保存，其ID映射到已有的文件，不计入返回的数量。
+// 无法解码的图片的ID追加到failedImages，由调用方按 OnImageError 处理其链接。
+func (p *Processor) savePageImages(page Page, imagesDir string, imageMap map[string]string, usedFilenames map[string]bool, dedup *imageDedup, failedImages *[]string, opts ProcessOptions) int {
+	saved := 0
+	for i, img := range page.Images {
+		if hasImageData(img) {
+			decodedData, err := decodeImageData(img.ImageBase64)
+			if err != nil {
+				p.logger.Warn("解码图片失败", zap.String("imageID", img.ID), zap.Error(err))
+				*failedImages = append(*failedImages, img.ID)
+				continue
+			}
+
+			// 重新编码图片以去除元数据，无法处理的格式保存原始数据
+			if opts.StripImageMetadata {
+				if stripped, err := stripImageMetadata(decodedData); err != nil {
+					p.logger.Warn("去除图片元数据失败，保存原始图片", zap.String("imageID", img.ID), zap.Error(err))
+				} else {
+					decodedData = stripped
+				}
+			}
+
+			if relPath, ok := dedup.lookup(decodedData); ok {
+				imageMap[img.ID] = relPath
+				dedup.duplicates++
+				dedup.bytesSaved += int64(len(decodedData))
+				p.logger.Debug("图片与已保存的图片相同", zap.String("imageID", img.ID), zap.String("path", relPath))
+				continue
+			}
+
+			// 确定图片文件名，图片ID可能包含路径分隔符等不安全字符，
+			// 没有扩展名时根据图片内容识别格式
+			imgFilename := opts.uniqueImageFileName(page, i, decodedData, usedFilenames)
+			if imgFilename != img.ID {
+				p.logger.Debug("图片文件名已规范化", zap.String("imageID", img.ID), zap.String("filename", imgFilename))
+			}
+
+			imgPath := filepath.Join(imagesDir, imgFilename)
+			if err = opts.writeFile(imgPath, decodedData); err != nil {
+				p.logger.Warn("保存图片失败", zap.String("imageID", img.ID), zap.Error(err))
+				continue
+			}
+
+			// 记录图片ID到相对路径的映射
+			imageMap[img.ID] = path.Join("images", imgFilename)
+			dedup.record(decodedData, imageMap[img.ID])
+			saved++
+			p.logger.Debug("保存图片", zap.String("imageID", img.ID), zap.String("path", imgPath))
+		}
+	}
+	return saved
+}
+
+// savePageFiles 将每页markdown保存为单独的文件，并生成链接到所有页面的index.md
+func (p *Processor) savePageFiles(pageMarkdowns []string, outputDir string, opts ProcessOptions) error {
+	// 页码按总页数的位数补零，保证文件名排序与页面顺序一致
+	width := len(strconv.Itoa(len(pageMarkdowns) - 1))
+
+	var index strings.Builder
+	index.WriteString("# 页面索引\n\n")
+
+	for i, markdown := range pageMarkdowns {
+		pageName := fmt.Sprintf("page-%0*d.md", width, i)
+		pagePath := filepath.Join(outputDir, pageName)
+		if err := opts.writeFile(pagePath, []byte(markdown+"\n")); err != nil {
+			return fmt.Errorf("保存页面markdown错误: %w", err)
+		}
+		p.logger.Debug("保存了页面markdown文件", zap.String("path", pagePath))
+
+		index.WriteString(fmt.Sprintf("- [第 %d 页](%s)\n", i+1, pageName))
+	}
+
+	indexPath := filepath.Join(outputDir, "index.md")
+	if err := opts.writeFile(indexPath, []byte(index.String())); err != nil {
+		return fmt.Errorf("保存页面索引错误: %w", err)
+	}
+	p.logger.Debug("保存了页面索引文件", zap.String("path", indexPath))
+
+	return nil
+}
+
 // extractTextFromMarkdown 从markdown提取纯文本内容
 func extractTextFromMarkdown(markdown string) string {
 	// 移除图片链接
@@ -360,112 +1213,92 @@ func extractTextFromMarkdown(markdown string) string {
 	return result
 }
 
+// parseOCRJSON 解析OCR响应JSON，兼容原始API响应和包含raw_response的元数据文件
+//
+// 元数据文件将原始响应单独保存时，从baseDir下的raw_response_file读取。
+func (p *Processor) parseOCRJSON(jsonData []byte, baseDir string) (*OCRResponse, error) {
+	var ocrResponse OCRResponse
+	if err := json.Unmarshal(jsonData, &ocrResponse); err != nil {
+		return nil, fmt.Errorf("解析JSON数据失败: %w", err)
+	}
+
+	// 顶层没有pages时，尝试按metadata.json的结构从raw_response中提取
+	if len(ocrResponse.Pages) == 0 {
+		var envelope RawOCREnvelope
+		if err := json.Unmarshal(jsonData, &envelope); err != nil {
+			return nil, fmt.Errorf("解析raw_response数据失败: %w", err)
+		}
+
+		if envelope.RawResponse != nil && len(envelope.RawResponse.Pages) > 0 {
+			// 只保留raw_response部分作为原始响应，避免重新生成的metadata.json嵌套整个旧元数据
+			var raw struct {
+				RawResponse json.RawMessage `json:"raw_response"`
+			}
+			if err := json.Unmarshal(jsonData, &raw); err != nil {
+				return nil, fmt.Errorf("解析raw_response数据失败: %w", err)
+			}
+			p.logger.Debug("从raw_response中提取pages数据", zap.Int("pages_count", len(envelope.RawResponse.Pages)))
+			return p.parseOCRJSON(raw.RawResponse, baseDir)
+		} else if envelope.RawResponseFile != "" {
+			responsePath := filepath.Join(baseDir, filepath.Base(envelope.RawResponseFile))
+			p.logger.Debug("从单独的原始响应文件中读取", zap.String("path", responsePath))
+			responseData, err := p.openFiles.readFile(responsePath)
+			if err != nil {
+				return nil, fmt.Errorf("读取原始响应文件失败: %w", err)
+			}
+			return p.parseOCRJSON(responseData, baseDir)
+		}
+	}
+
+	ocrResponse.RawResponse = jsonData
+	return &ocrResponse, nil
+}
+
 // ConvertJSONToMarkdown 从JSON文件生成Markdown文件
 func (p *Processor) ConvertJSONToMarkdown(jsonFilePath string, opts ProcessOptions) (*ProcessResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts.openFiles = p.openFiles
+
 	startTime := time.Now()
 	p.logger.Info("开始从JSON文件生成Markdown", zap.String("jsonFile", jsonFilePath))
 
 	// 读取JSON文件
-	jsonData, err := os.ReadFile(jsonFilePath)
+	jsonData, err := p.openFiles.readFile(jsonFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("读取JSON文件失败: %w", err)
 	}
 
 	// 解析JSON数据
-	var ocrResponse OCRResponse
-	if err := json.Unmarshal(jsonData, &ocrResponse); err != nil {
-		return nil, fmt.Errorf("解析JSON数据失败: %w", err)
+	ocrResponse, err := p.parseOCRJSON(jsonData, filepath.Dir(jsonFilePath))
+	if err != nil {
+		return nil, err
 	}
 
-	// 检查是否需要从raw_response中提取pages数据
-	if len(ocrResponse.Pages) == 0 {
-		// 尝试从raw_response中提取pages数据
-		var rawResponse map[string]interface{}
-		if err := json.Unmarshal(jsonData, &rawResponse); err != nil {
-			return nil, fmt.Errorf("解析raw_response数据失败: %w", err)
+	// 确定输出文件名，默认使用原始文件名(不带扩展名)；从输出目录的metadata.json转换时使用该目录的名称
+	baseName := sourceBaseName(jsonFilePath)
+	if filepath.Base(jsonFilePath) == MetadataFileName {
+		if absDir, err := filepath.Abs(filepath.Dir(jsonFilePath)); err == nil {
+			baseName = filepath.Base(absDir)
 		}
-
-		// 检查raw_response中是否包含pages字段
-		if rawResponseData, ok := rawResponse["raw_response"].(map[string]interface{}); ok {
-			if pagesData, ok := rawResponseData["pages"].([]interface{}); ok {
-				p.logger.Debug("从raw_response中提取pages数据", zap.Int("pages_count", len(pagesData)))
-
-				// 将pages数据转换为OCRResponse.Pages
-				for pageIndex, pageData := range pagesData {
-					if pageMap, ok := pageData.(map[string]interface{}); ok {
-						page := Page{}
-
-						// 提取index
-						if index, ok := pageMap["index"].(float64); ok {
-							page.Index = int(index)
-						}
-
-						// 提取markdown
-						if markdown, ok := pageMap["markdown"].(string); ok {
-							page.Markdown = markdown
-						}
-
-						// 提取images
-						if imagesData, ok := pageMap["images"].([]interface{}); ok {
-							p.logger.Debug("提取images数据", zap.Int("images_count", len(imagesData)), zap.Int("page_index", pageIndex))
-							for _, imgData := range imagesData {
-								if imgMap, ok := imgData.(map[string]interface{}); ok {
-									image := Image{}
-
-									// 提取image_id
-									if id, ok := imgMap["id"].(string); ok {
-										image.ID = id
-									}
-
-									// 提取坐标
-									if x, ok := imgMap["top_left_x"].(float64); ok {
-										image.TopLeftX = int(x)
-									}
-									if y, ok := imgMap["top_left_y"].(float64); ok {
-										image.TopLeftY = int(y)
-									}
-									if x, ok := imgMap["bottom_right_x"].(float64); ok {
-										image.BottomRightX = int(x)
-									}
-									if y, ok := imgMap["bottom_right_y"].(float64); ok {
-										image.BottomRightY = int(y)
-									}
-
-									// 提取base64图像数据
-									if base64Data, ok := imgMap["image_base64"].(string); ok {
-										image.ImageBase64 = base64Data
-									}
-
-									page.Images = append(page.Images, image)
-								}
-							}
-						}
-
-						// 添加到pages数组
-						ocrResponse.Pages = append(ocrResponse.Pages, page)
-					}
-				}
-
-				p.logger.Debug("成功从raw_response提取pages数据", zap.Int("extracted_pages", len(ocrResponse.Pages)))
-			}
+	}
+	if opts.NameFromMetadata {
+		if name := recordedSourceName(jsonData); name != "" {
+			baseName = name
+		} else {
+			p.logger.Debug("JSON中没有记录原始来源，按文件名命名输出", zap.String("jsonFile", jsonFilePath))
 		}
 	}
-
-	ocrResponse.RawResponse = jsonData
-
-	// 确定输出文件名
-	outputName := opts.CustomOutputName
-	if outputName == "" {
-		// 使用原始文件名(不带扩展名)
-		outputName = strings.TrimSuffix(filepath.Base(jsonFilePath), filepath.Ext(jsonFilePath))
+	outputName, err := opts.resolveOutputName(baseName, 1)
+	if err != nil {
+		return nil, err
 	}
 
-	// 创建输出目录
 	outputDir := filepath.Join(opts.OutputDir, outputName)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return nil, fmt.Errorf("创建输出目录失败: %w", err)
+	if sameDir(outputDir, filepath.Dir(jsonFilePath)) {
+		return nil, fmt.Errorf("输出目录与JSON文件所在目录相同，会覆盖原有输出，请指定其他输出目录: %s", outputDir)
 	}
-	p.logger.Debug("创建输出目录", zap.String("dir", outputDir))
 
 	// 创建元数据
 	metadata := ProcessMetadata{
@@ -473,7 +1306,7 @@ func (p *Processor) ConvertJSONToMarkdown(jsonFilePath string, opts ProcessOptio
 		SourcePath:     jsonFilePath,
 		OutputDir:      outputDir,
 		ProcessedAt:    startTime.Format(time.RFC3339),
-		IncludeImages:  opts.IncludeImages,
+		IncludeImages:  opts.savesImages(),
 		PagesProcessed: len(ocrResponse.Pages),
 		OCRResponseInfo: map[string]any{
 			"model":           ocrResponse.Model,
@@ -483,7 +1316,7 @@ func (p *Processor) ConvertJSONToMarkdown(jsonFilePath string, opts ProcessOptio
 	}
 
 	// 保存结果
-	result, err := p.saveResults(&ocrResponse, outputDir, metadata, opts.IncludeImages)
+	result, err := p.saveOutput(ocrResponse, outputDir, metadata, opts)
 	if err != nil {
 		return nil, fmt.Errorf("保存结果失败: %w", err)
 	}
@@ -496,22 +1329,250 @@ func (p *Processor) ConvertJSONToMarkdown(jsonFilePath string, opts ProcessOptio
 	return result, nil
 }
 
+// skipConvertJSON 判断扫描目录时是否忽略该JSON文件
+//
+// 忽略批量处理的清单 manifest.json 和摘要 summary.json，以及处理输出中的 metadata.json、response.json、
+// annotations.json 和 *.ocr.json 边车文件，避免把已有的输出目录当作原始响应重复转换。
+func skipConvertJSON(name string) bool {
+	switch name {
+	case ManifestFileName, SummaryJSONFileName, MetadataFileName, RawResponseFileName, AnnotationsFileName:
+		return true
+	}
+	return strings.HasSuffix(name, SidecarSuffix)
+}
+
+// ConvertMultipleJSON 将多个JSON文件或目录中的所有JSON文件转换为Markdown
+//
+// 目录中的文件按相对于该目录的路径（不含扩展名）确定输出子目录，保留原有的目录结构，
+// 扫描目录时会忽略处理输出和批量处理生成的JSON文件（见 skipConvertJSON），直接指定的JSON文件不受影响。错误处理与 ProcessMultipleFiles 相同：
+// 失败时返回 *BatchError，启用 ContinueOnError 时只要有文件转换成功就返回 nil 错误。
+func (p *Processor) ConvertMultipleJSON(ctx context.Context, paths []string, opts ProcessOptions) ([]*ProcessResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts.openFiles = p.openFiles
+
+	var results []*ProcessResult
+	var errors []error
+
+	// 收集所有需要转换的文件及其输出名称
+	type jsonFile struct {
+		path       string
+		outputName string
+	}
+	var filesToConvert []jsonFile
+
+	for _, path := range paths {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			p.logger.Error("获取文件信息失败", zap.String("path", path), zap.Error(err))
+			errors = append(errors, fmt.Errorf("获取文件信息失败 %s: %w", path, err))
+			if !opts.ContinueOnError {
+				return results, &BatchError{Results: results, Errors: errors}
+			}
+			continue
+		}
+
+		if !fileInfo.IsDir() {
+			filesToConvert = append(filesToConvert, jsonFile{
+				path:       path,
+				outputName: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+			})
+			continue
+		}
+
+		p.logger.Info("扫描目录中的JSON文件", zap.String("dir", path))
+		err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || strings.ToLower(filepath.Ext(filePath)) != ".json" || skipConvertJSON(info.Name()) {
+				return nil
+			}
+			rel, err := filepath.Rel(path, filePath)
+			if err != nil {
+				rel = filepath.Base(filePath)
+			}
+			filesToConvert = append(filesToConvert, jsonFile{
+				path:       filePath,
+				outputName: strings.TrimSuffix(rel, filepath.Ext(rel)),
+			})
+			return nil
+		})
+		if err != nil {
+			p.logger.Error("扫描目录失败", zap.String("dir", path), zap.Error(err))
+			errors = append(errors, fmt.Errorf("扫描目录失败 %s: %w", path, err))
+			if !opts.ContinueOnError {
+				return results, &BatchError{Results: results, Errors: errors}
+			}
+		}
+	}
+
+	if len(filesToConvert) == 0 {
+		return results, noFilesError("没有找到可转换的JSON文件", errors)
+	}
+
+	p.logger.Info("开始转换文件", zap.Int("total", len(filesToConvert)))
+
+	// 按原始来源命名时不同的JSON文件可能记录了同名的来源，已使用的名称添加序号区分
+	usedNames := make(map[string]bool)
+
+	// 返回时报告转换结果
+	failed := 0
+	batchStart := time.Now()
+	defer func() {
+		if opts.OnBatchDone == nil {
+			return
+		}
+		summary := &BatchSummary{
+			StartedAt:   batchStart.Format(time.RFC3339),
+			FinishedAt:  time.Now().Format(time.RFC3339),
+			Elapsed:     time.Since(batchStart).Round(time.Millisecond).String(),
+			Interrupted: ctx.Err() != nil,
+			TotalFiles:  len(filesToConvert),
+			Succeeded:   len(results),
+			Failed:      failed,
+		}
+		summary.Pending = summary.TotalFiles - summary.Succeeded - summary.Failed
+		opts.OnBatchDone(summary)
+	}()
+
+	for i, file := range filesToConvert {
+		// 上下文被取消时停止转换剩余文件
+		if err := ctx.Err(); err != nil {
+			p.logger.Warn("批量转换被中断", zap.Int("completed", len(results)), zap.Int("total", len(filesToConvert)))
+			errors = append(errors, fmt.Errorf("批量转换被中断: %w", err))
+			return results, &BatchError{Results: results, Errors: errors}
+		}
+
+		p.logger.Info("转换文件", zap.Int("current", i+1), zap.Int("total", len(filesToConvert)), zap.String("file", file.path))
+
+		fileOpts := opts
+		if fileOpts.CustomOutputName == "" {
+			outputBase := file.outputName
+			if opts.NameFromMetadata {
+				outputBase = p.recordedOutputBase(file.path, file.outputName, usedNames)
+			}
+			name, err := fileOpts.resolveOutputName(outputBase, i+1)
+			if err != nil {
+				errors = append(errors, err)
+				return results, &BatchError{Results: results, Errors: errors}
+			}
+			fileOpts.CustomOutputName = name
+		} else if len(filesToConvert) > 1 {
+			// 如果转换多个文件但指定了输出名称，则添加序号
+			fileOpts.CustomOutputName = fmt.Sprintf("%s_%d", fileOpts.CustomOutputName, i+1)
+		}
+
+		result, err := p.ConvertJSONToMarkdown(file.path, fileOpts)
+		if err != nil {
+			p.logger.Error("转换文件失败", zap.String("file", file.path), zap.Error(err))
+			errors = append(errors, fmt.Errorf("转换文件失败 %s: %w", file.path, err))
+			failed++
+			if !opts.ContinueOnError {
+				return results, &BatchError{Results: results, Errors: errors}
+			}
+			continue
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return results, &BatchError{Results: results, Errors: errors}
+	}
+
+	if len(errors) > 0 {
+		p.logger.Warn("部分文件转换失败", zap.Int("success", len(results)), zap.Int("failed", len(errors)), zap.Int("total", len(filesToConvert)))
+	}
+	p.logger.Info("所有文件转换完成", zap.Int("success", len(results)), zap.Int("total", len(filesToConvert)))
+	return results, nil
+}
+
+// recordedOutputBase 按JSON中记录的原始来源确定批量转换的输出名称，保留 outputName 中的目录部分，
+// 没有记录原始来源时使用 outputName，与 used 中已有的名称重复时添加序号
+func (p *Processor) recordedOutputBase(jsonPath, outputName string, used map[string]bool) string {
+	base := outputName
+	if data, err := p.openFiles.readFile(jsonPath); err == nil {
+		if name := recordedSourceName(data); name != "" {
+			base = filepath.Join(filepath.Dir(outputName), name)
+		}
+	}
+
+	unique := base
+	for i := 1; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", base, i)
+	}
+	used[unique] = true
+	return unique
+}
+
 // ProcessMultipleFiles 处理多个PDF文件或目录中的所有PDF文件
-func (p *Processor) ProcessMultipleFiles(paths []string, opts ProcessOptions) ([]*ProcessResult, error) {
+//
+// 发生错误时，返回值中的结果切片始终包含出错前已成功处理的文件，
+// 错误为 *BatchError 类型，同时携带成功的结果和每个失败文件的错误，
+// 调用方可以通过 errors.As 取出并保留已完成的工作，也可以通过 errors.Is、errors.As 直接检查各文件的错误。
+// 没有找到可处理的文件（包括paths为空）时同样返回 *BatchError，其中的错误包装了收集文件时发生的各个错误。
+// 启用 ContinueOnError 时，只要有文件处理成功就返回 nil 错误。
+//
+// 处理过程中会在输出目录写入 manifest.json，记录每个文件的处理状态。
+// 上下文被取消时，不再处理剩余文件，清单会标记为已中断后写入。
+//
+// 设置 ModifiedSince 时，扫描目录会跳过修改时间早于该时间的文件（直接指定的文件不受影响），
+// 没有需要处理的新文件时返回空结果和 nil 错误。
+//
+// 设置 ResumeManifest 时，清单中已处理成功或跳过的文件不再处理（不检查输出目录），先按绝对路径匹配，
+// 再按相对于批次根目录的路径匹配，输入目录移动到其他位置或其他机器上时同样适用。这些文件会标记为恢复（Resumed）
+// 保留在新写入的清单中，但不计入本次的成功数、页数和图片数，也不出现在返回的结果中；清单中没有的文件正常处理。
+//
+// 设置 SkipTextPDFs 时，已包含文本层的PDF不再处理，也不会写入清单。
+//
+// 收集到的文件按路径排序（SortOrder 可选字节顺序或自然顺序），保证多次运行的处理顺序一致。设置 StartAt 或 StartAfter 时，
+// 在排序后、按清单和文本层过滤前跳过起始位置之前的文件，这些文件不会写入清单。
+//
+// 设置 IndexDB 时，每个处理成功的文件在索引数据库中写入或更新一行（按源文件路径），
+// 输出目录已存在而跳过的文件保留原有记录；写入失败只输出警告，不影响处理结果。
+//
+// 输入文件旁有处理选项文件（如 report.pdf.ocr.json，见 SidecarOptions）时，其中的选项覆盖该文件的选项，
+// 没有处理选项文件的文件使用 opts；处理选项文件无法读取或解析时，该文件按处理失败处理。
+//
+// 设置 OnFileComplete 时，每个文件处理完成后立即回调，便于调用方逐个展示结果。
+func (p *Processor) ProcessMultipleFiles(ctx context.Context, paths []string, opts ProcessOptions) ([]*ProcessResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts.openFiles = p.openFiles
+
 	var results []*ProcessResult
 	var filesToProcess []string
 	var errors []error
 	var skippedFiles int
+	var skippedByTime int
+	var skippedByResume int
+	var skippedByText int
+	var skippedByStart int
+
+	// 从之前的清单恢复时，已完成的文件不再处理
+	var resumed []ManifestEntry
+	var completed *completedSet
+	if opts.ResumeManifest != "" {
+		previous, err := LoadManifest(opts.ResumeManifest)
+		if err != nil {
+			return nil, err
+		}
+		completed = previous.completedFiles()
+		p.logger.Info("从清单恢复批量处理", zap.String("manifest", opts.ResumeManifest), zap.Int("completed", completed.len()))
+	}
 
-	// 收集所有需要处理的文件
+	// 收集所有需要处理的文件，同时记录每个文件所属的批次根目录，清单中按相对于该目录的路径记录文件
+	roots := make(map[string]string)
 	for _, path := range paths {
 		fileInfo, err := os.Stat(path)
 		if err != nil {
 			p.logger.Error("获取文件信息失败", zap.String("path", path), zap.Error(err))
+			errors = append(errors, fmt.Errorf("获取文件信息失败 %s: %w", path, err))
 			if !opts.ContinueOnError {
-				return nil, fmt.Errorf("获取文件信息失败: %w", err)
+				return results, &BatchError{Results: results, Errors: errors}
 			}
-			errors = append(errors, fmt.Errorf("获取文件信息失败 %s: %w", path, err))
 			continue
 		}
 
@@ -523,81 +1584,248 @@ func (p *Processor) ProcessMultipleFiles(paths []string, opts ProcessOptions) ([
 					return err
 				}
 				if !info.IsDir() && strings.ToLower(filepath.Ext(filePath)) == ".pdf" {
+					// 增量处理时跳过截止时间之前修改的文件
+					if !opts.ModifiedSince.IsZero() && info.ModTime().Before(opts.ModifiedSince) {
+						p.logger.Debug("跳过修改时间早于截止时间的文件", zap.String("file", filePath), zap.Time("modTime", info.ModTime()))
+						skippedByTime++
+						return nil
+					}
 					filesToProcess = append(filesToProcess, filePath)
+					roots[filePath] = path
 				}
 				return nil
 			})
 			if err != nil {
 				p.logger.Error("扫描目录失败", zap.String("dir", path), zap.Error(err))
+				errors = append(errors, fmt.Errorf("扫描目录失败 %s: %w", path, err))
 				if !opts.ContinueOnError {
-					return nil, fmt.Errorf("扫描目录失败: %w", err)
+					return results, &BatchError{Results: results, Errors: errors}
 				}
-				errors = append(errors, fmt.Errorf("扫描目录失败 %s: %w", path, err))
 				continue
 			}
 		} else if strings.ToLower(filepath.Ext(path)) == ".pdf" {
 			// 如果是PDF文件，直接添加到处理列表
 			filesToProcess = append(filesToProcess, path)
+			roots[path] = filepath.Dir(path)
 		} else {
 			p.logger.Warn("跳过非PDF文件", zap.String("file", path))
 		}
 	}
 
+	if skippedByTime > 0 {
+		p.logger.Info("跳过了修改时间早于截止时间的文件", zap.Int("count", skippedByTime), zap.Time("since", opts.ModifiedSince))
+	}
+
+	// 排序保证处理顺序稳定，StartAt 和 StartAfter 才能在多次运行中指向同一个文件，
+	// 输出名称重复时添加的 _1、_2 后缀也按该顺序分配
+	sortFiles(filesToProcess, opts.SortOrder)
+	if opts.StartAt > 1 || opts.StartAfter != "" {
+		remaining, err := applyStartPosition(filesToProcess, opts)
+		if err != nil {
+			return nil, err
+		}
+		skippedByStart = len(filesToProcess) - len(remaining)
+		filesToProcess = remaining
+		p.logger.Info("从指定位置开始处理", zap.Int("skipped", skippedByStart), zap.Int("remaining", len(filesToProcess)))
+	}
+
+	// 过滤清单中已完成的文件，清单中没有的新文件正常处理
+	if completed != nil {
+		remaining := filesToProcess[:0]
+		for _, filePath := range filesToProcess {
+			relPath := batchRelPath(roots[filePath], filePath)
+			if entry, ok := completed.lookup(filePath, relPath); ok {
+				p.logger.Debug("清单中已完成，跳过处理", zap.String("file", filePath), zap.String("status", entry.Status))
+				entry.Path = filePath
+				entry.RelPath = relPath
+				entry.Resumed = true
+				resumed = append(resumed, entry)
+				skippedByResume++
+				continue
+			}
+			remaining = append(remaining, filePath)
+		}
+		filesToProcess = remaining
+		p.logger.Info("跳过了清单中已完成的文件", zap.Int("count", skippedByResume), zap.Int("remaining", len(filesToProcess)))
+	}
+
+	// 跳过已包含文本层的PDF，这些文件可以直接提取文本，不必付费OCR
+	if opts.SkipTextPDFs {
+		remaining := filesToProcess[:0]
+		for _, filePath := range filesToProcess {
+			layer, err := probePDFTextLayerFile(filePath, p.openFiles)
+			if err != nil {
+				// 探测失败时照常处理，由OCR决定能否处理该文件
+				p.logger.Warn("探测PDF文本层失败", zap.String("file", filePath), zap.Error(err))
+			} else if layer.HasText() {
+				p.logger.Info("PDF已包含文本层，跳过处理", zap.String("file", filePath), zap.Int("pages", layer.Pages), zap.Int("textChars", layer.TextChars))
+				skippedByText++
+				continue
+			}
+			remaining = append(remaining, filePath)
+		}
+		filesToProcess = remaining
+		if skippedByText > 0 {
+			p.logger.Info("跳过了已包含文本层的PDF文件", zap.Int("count", skippedByText), zap.Int("remaining", len(filesToProcess)))
+		}
+	}
+
 	if len(filesToProcess) == 0 {
-		if len(errors) > 0 {
-			return nil, fmt.Errorf("没有找到可处理的PDF文件，发生了 %d 个错误", len(errors))
+		// 增量处理、从清单恢复、跳过文本PDF或从最后一个文件之后开始时没有需要处理的文件不视为错误
+		if skippedByTime+skippedByResume+skippedByText+skippedByStart > 0 && len(errors) == 0 {
+			p.logger.Info("没有需要处理的新PDF文件")
+			return results, nil
 		}
-		return nil, fmt.Errorf("没有找到可处理的PDF文件")
+		return results, noFilesError("没有找到可处理的PDF文件", errors)
 	}
 
 	p.logger.Info("开始处理文件", zap.Int("total", len(filesToProcess)))
 
+	// 打开索引数据库，在处理任何文件之前发现驱动或路径的问题
+	var index *indexDB
+	if opts.IndexDB != "" {
+		db, err := openIndexDB(ctx, opts.IndexDB, p.openFiles)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		index = db
+	}
+
+	// 创建清单，函数返回时将清单和摘要写入输出目录
+	batchStart := time.Now()
+	manifest := newBatchManifest(filesToProcess, roots)
+	defer func() {
+		// 保留恢复前已完成的文件，使清单始终覆盖整个批次
+		manifest.Files = append(manifest.Files, resumed...)
+		p.writeManifest(opts, manifest)
+		summary := newBatchSummary(manifest, time.Since(batchStart))
+		p.writeSummary(opts, summary)
+		if opts.OnBatchDone != nil {
+			opts.OnBatchDone(summary)
+		}
+	}()
+
 	// 处理每个文件
 	for i, filePath := range filesToProcess {
+		// 上下文被取消时停止处理剩余文件
+		if err := ctx.Err(); err != nil {
+			p.logger.Warn("批量处理被中断", zap.Int("completed", len(results)), zap.Int("total", len(filesToProcess)))
+			manifest.Interrupted = true
+			errors = append(errors, fmt.Errorf("批量处理被中断: %w", err))
+			return results, &BatchError{Results: results, Errors: errors}
+		}
+
 		p.logger.Info("处理文件", zap.Int("current", i+1), zap.Int("total", len(filesToProcess)), zap.String("file", filePath))
 
 		// 为每个文件创建单独的输出名称
 		fileOpts := opts
 		if fileOpts.CustomOutputName == "" {
-			// 使用文件名作为输出名称
-			fileOpts.CustomOutputName = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+			// 使用文件名或输出名称模板生成输出名称，模板中的序号为文件在本批中的位置
+			name, err := fileOpts.resolveOutputName(sourceBaseName(filePath), i+1)
+			if err != nil {
+				errors = append(errors, err)
+				return results, &BatchError{Results: results, Errors: errors}
+			}
+			fileOpts.CustomOutputName = name
 		} else if len(filesToProcess) > 1 {
 			// 如果处理多个文件但指定了输出名称，则添加序号
 			fileOpts.CustomOutputName = fmt.Sprintf("%s_%d", fileOpts.CustomOutputName, i+1)
 		}
 
-		result, err := p.ProcessFile(filePath, fileOpts)
+		// 文件旁有处理选项文件时，用其中的选项覆盖该文件的选项，读取失败按处理失败处理
+		var result *ProcessResult
+		sidecar, err := loadSidecarOptions(filePath, p.openFiles)
+		if err == nil {
+			if sidecar != nil {
+				p.logger.Info("使用文件的处理选项文件", zap.String("file", filePath), zap.String("sidecar", sidecarPath(filePath)))
+				fileOpts = sidecar.apply(fileOpts)
+			}
+			result, err = p.ProcessFile(ctx, filePath, fileOpts)
+		}
 		if err != nil {
 			p.logger.Error("处理文件失败", zap.String("file", filePath), zap.Error(err))
-			errors = append(errors, fmt.Errorf("处理文件失败 %s: %w", filePath, err))
+			fileErr := fmt.Errorf("处理文件失败 %s: %w", filePath, err)
+			errors = append(errors, fileErr)
+			manifest.Files[i].Status = ManifestStatusFailed
+			manifest.Files[i].Error = err.Error()
+			if opts.OnFileComplete != nil {
+				opts.OnFileComplete(nil, fileErr)
+			}
+			// 上下文被取消时，无论是否继续处理都立即返回
+			if ctx.Err() != nil {
+				manifest.Interrupted = true
+				return results, &BatchError{Results: results, Errors: errors}
+			}
 			// 如果不继续处理，则返回错误
 			if !opts.ContinueOnError {
-				return results, fmt.Errorf("处理文件失败: %w", err)
+				return results, &BatchError{Results: results, Errors: errors}
 			}
 			// 继续处理其他文件，不中断整个过程
 			continue
 		}
 
 		// 如果结果中的页数为0，说明文件被跳过了
+		manifest.Files[i].OutputDir = result.OutputDir
+		manifest.Files[i].Pages = result.Pages
+		manifest.Files[i].Images = result.Images
+		manifest.Files[i].Status = ManifestStatusProcessed
+		if result.Timings.Total() > 0 {
+			timings := result.Timings
+			manifest.Files[i].Timings = &timings
+		}
 		if result.Pages == 0 {
 			skippedFiles++
+			manifest.Files[i].Status = ManifestStatusSkipped
+		} else if index != nil {
+			if err := index.record(ctx, filePath, result); err != nil {
+				p.logger.Warn("记录到索引数据库失败", zap.String("file", filePath), zap.Error(err))
+			}
 		}
 
 		results = append(results, result)
+		if opts.OnFileComplete != nil {
+			opts.OnFileComplete(result, nil)
+		}
 	}
 
 	if len(results) == 0 {
-		return nil, fmt.Errorf("所有文件处理失败，发生了 %d 个错误", len(errors))
+		return results, &BatchError{Results: results, Errors: errors}
 	}
 
 	// 如果有错误但仍然处理了一些文件，记录错误数量
 	if len(errors) > 0 {
-		p.logger.Warn("部分文件处理失败", zap.Int("success", len(results)), zap.Int("failed", len(errors)), zap.Int("total", len(filesToProcess)))
+		p.logger.Warn("部分文件处理失败", zap.Int("success", len(results)-skippedFiles), zap.Int("failed", len(errors)), zap.Int("total", len(filesToProcess)))
 	}
 
+	usage := SummarizeUsage(results)
 	p.logger.Info("所有文件处理完成",
-		zap.Int("success", len(results)),
+		zap.Int("success", len(results)-skippedFiles),
 		zap.Int("skipped", skippedFiles),
-		zap.Int("total", len(filesToProcess)))
+		zap.Int("resumed", skippedByResume),
+		zap.Int("total", len(filesToProcess)),
+		zap.Int("pagesProcessed", usage.PagesProcessed),
+		zap.Int64("docSizeBytes", usage.DocSizeBytes))
 	return results, nil
 }
+
+// SummarizeUsage 汇总处理结果中的API用量
+func SummarizeUsage(results []*ProcessResult) UsageSummary {
+	var summary UsageSummary
+	for _, result := range results {
+		if result == nil || result.Usage.PagesProcessed == 0 {
+			continue
+		}
+		summary.Files++
+		summary.PagesProcessed += result.Usage.PagesProcessed
+		if result.Usage.DocSizeBytes != nil {
+			summary.DocSizeBytes += int64(*result.Usage.DocSizeBytes)
+		}
+	}
+	return summary
+}
+
+// EstimatedCost 根据每页单价估算费用
+func (u UsageSummary) EstimatedCost(costPerPage float64) float64 {
+	return float64(u.PagesProcessed) * costPerPage
+}
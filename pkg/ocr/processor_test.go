@@ -0,0 +1,335 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// documentTypeServer 模拟文档服务器和OCR端点：HEAD请求按路径返回Content-Type，OCR请求记录文档类型
+func documentTypeServer(t *testing.T, heads *atomic.Int32, documents chan<- map[string]string) *httptest.Server {
+	t.Helper()
+	contentTypes := map[string]string{
+		"/files/scan":     "image/png",
+		"/files/document": "application/pdf",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead:
+			heads.Add(1)
+			contentType, ok := contentTypes[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", contentType)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/ocr"):
+			var body struct {
+				Document map[string]string `json:"document"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("解析OCR请求失败: %v", err)
+			}
+			documents <- body.Document
+			w.Write([]byte(`{"pages":[{"index":0,"markdown":"remote text","images":[]}],"model":"mistral-ocr-latest","usage_info":{"pages_processed":1}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDetectDocumentType(t *testing.T) {
+	var heads atomic.Int32
+	server := documentTypeServer(t, &heads, nil)
+	processor := NewProcessor(newTestClient(t, server.URL), zap.NewNop())
+
+	tests := []struct {
+		name     string
+		path     string
+		force    bool
+		want     string
+		wantHEAD bool
+	}{
+		{name: "PDF扩展名", path: "/files/report.pdf", want: DocumentTypeDocument},
+		{name: "PNG扩展名", path: "/files/scan.png", want: DocumentTypeImage},
+		{name: "大写扩展名和查询参数", path: "/files/PHOTO.JPG?download=1", want: DocumentTypeImage},
+		{name: "无扩展名的图片", path: "/files/scan", want: DocumentTypeImage, wantHEAD: true},
+		{name: "无扩展名的PDF", path: "/files/document", want: DocumentTypeDocument, wantHEAD: true},
+		{name: "HEAD请求失败", path: "/files/missing", want: DocumentTypeDocument, wantHEAD: true},
+		{name: "强制按图片处理", path: "/files/report.pdf", force: true, want: DocumentTypeImage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heads.Store(0)
+			got := processor.detectDocumentType(context.Background(), server.URL+tt.path, ProcessOptions{ForceImageURL: tt.force})
+			if got != tt.want {
+				t.Errorf("detectDocumentType = %s，期望 %s", got, tt.want)
+			}
+			if sent := heads.Load() > 0; sent != tt.wantHEAD {
+				t.Errorf("发送了 %d 个HEAD请求，期望发送: %v", heads.Load(), tt.wantHEAD)
+			}
+		})
+	}
+}
+
+// TestProcessURLImageType 没有扩展名的图片URL以 image_url 类型发送给OCR端点
+func TestProcessURLImageType(t *testing.T) {
+	var heads atomic.Int32
+	documents := make(chan map[string]string, 1)
+	server := documentTypeServer(t, &heads, documents)
+	processor := NewProcessor(newTestClient(t, server.URL), zap.NewNop())
+
+	imageURL := server.URL + "/files/scan"
+	result, err := processor.ProcessURL(context.Background(), imageURL, ProcessOptions{OutputDir: t.TempDir(), NoImages: true})
+	if err != nil {
+		t.Fatalf("ProcessURL 返回错误: %v", err)
+	}
+	document := <-documents
+	if document["type"] != DocumentTypeImage || document[DocumentTypeImage] != imageURL {
+		t.Errorf("OCR请求中的文档 = %v，期望 image_url %s", document, imageURL)
+	}
+	metadata, _, err := LoadMetadata(result.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.DocumentType != DocumentTypeImage {
+		t.Errorf("元数据中的文档类型 = %s，期望 %s", metadata.DocumentType, DocumentTypeImage)
+	}
+}
+
+// TestConvertMetadataWithoutImages 包含图片的处理结果只凭metadata.json就能重新生成不含图片的输出
+func TestConvertMetadataWithoutImages(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "report.pdf")
+
+	processor := newTestProcessor(&fakeBackend{pages: 2})
+	first, err := processor.ProcessFile(context.Background(), filepath.Join(inputDir, "report.pdf"), ProcessOptions{
+		OutputDir:     filepath.Join(t.TempDir(), "with-images"),
+		IncludeImages: true,
+	})
+	if err != nil {
+		t.Fatalf("ProcessFile 返回错误: %v", err)
+	}
+	if first.Images != 2 {
+		t.Fatalf("包含图片的处理保存了 %d 张图片，期望 2 张", first.Images)
+	}
+
+	// 只保留metadata.json，确认重新生成不依赖原输出目录中的其他文件
+	metadataOnly := filepath.Join(t.TempDir(), "report")
+	if err := os.MkdirAll(metadataOnly, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(first.MetadataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadataPath := filepath.Join(metadataOnly, MetadataFileName)
+	if err := os.WriteFile(metadataPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := processor.ConvertJSONToMarkdown(metadataPath, ProcessOptions{
+		OutputDir: filepath.Join(t.TempDir(), "text-only"),
+		NoImages:  true,
+	})
+	if err != nil {
+		t.Fatalf("ConvertJSONToMarkdown 返回错误: %v", err)
+	}
+	if second.Pages != 2 {
+		t.Errorf("重新生成了 %d 页，期望 2 页", second.Pages)
+	}
+	if second.Images != 0 {
+		t.Errorf("不含图片的输出保存了 %d 张图片", second.Images)
+	}
+	if filepath.Base(second.OutputDir) != "report" {
+		t.Errorf("输出目录 = %s，期望以元数据所在目录命名", second.OutputDir)
+	}
+	if _, err := os.Stat(filepath.Join(second.OutputDir, "images")); !os.IsNotExist(err) {
+		t.Errorf("不含图片的输出中存在images目录: %v", err)
+	}
+
+	markdown, err := os.ReadFile(filepath.Join(second.OutputDir, "output.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(markdown), "![") || strings.Contains(string(markdown), "img-") {
+		t.Errorf("不含图片的输出中仍有图片链接:\n%s", markdown)
+	}
+	for _, want := range []string{"Text of page 1.", "Text of page 2."} {
+		if !strings.Contains(string(markdown), want) {
+			t.Errorf("输出中缺少 %q:\n%s", want, markdown)
+		}
+	}
+
+	metadata, _, err := LoadMetadata(second.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.IncludeImages {
+		t.Error("不含图片的输出的元数据记录为包含图片")
+	}
+	if len(metadata.RawResponse) == 0 {
+		t.Error("重新生成的元数据中没有原始响应，无法再次重新生成")
+	}
+}
+
+// TestConvertMultipleJSONSkipsOutputFiles 扫描目录时只转换原始响应，忽略处理输出、边车文件和批量处理生成的JSON
+func TestConvertMultipleJSONSkipsOutputFiles(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "report.pdf")
+
+	archive := t.TempDir()
+	processor := newTestProcessor(&fakeBackend{pages: 2})
+	first, err := processor.ProcessFile(context.Background(), filepath.Join(inputDir, "report.pdf"), ProcessOptions{
+		OutputDir:           archive,
+		NoImages:            true,
+		SeparateRawResponse: true,
+	})
+	if err != nil {
+		t.Fatalf("ProcessFile 返回错误: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(first.OutputDir, RawResponseFileName))
+	if err != nil {
+		t.Fatalf("读取原始响应失败: %v", err)
+	}
+
+	files := map[string][]byte{
+		filepath.Join(first.OutputDir, AnnotationsFileName):     []byte("{}"),
+		filepath.Join(archive, ManifestFileName):                []byte("{}"),
+		filepath.Join(archive, SummaryJSONFileName):             []byte("{}"),
+		filepath.Join(archive, "raw", "scan.json"):              raw,
+		filepath.Join(archive, "raw", "scan.pdf"+SidecarSuffix): raw,
+	}
+	for path, data := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := processor.ConvertMultipleJSON(context.Background(), []string{archive}, ProcessOptions{
+		OutputDir: filepath.Join(t.TempDir(), "rendered"),
+		NoImages:  true,
+	})
+	if err != nil {
+		t.Fatalf("ConvertMultipleJSON 返回错误: %v", err)
+	}
+	if len(results) != 1 {
+		var dirs []string
+		for _, result := range results {
+			dirs = append(dirs, result.OutputDir)
+		}
+		t.Fatalf("转换了 %d 个文件 %v，期望只转换 raw/scan.json", len(results), dirs)
+	}
+	if got := filepath.ToSlash(results[0].OutputDir); !strings.HasSuffix(got, "rendered/raw/scan") {
+		t.Errorf("输出目录 = %s，期望 rendered/raw/scan", got)
+	}
+	if results[0].Pages != 2 {
+		t.Errorf("转换了 %d 页，期望 2 页", results[0].Pages)
+	}
+
+	// 直接指定的metadata.json仍然可以转换
+	if _, err := processor.ConvertMultipleJSON(context.Background(), []string{first.MetadataPath}, ProcessOptions{
+		OutputDir: filepath.Join(t.TempDir(), "direct"),
+		NoImages:  true,
+	}); err != nil {
+		t.Errorf("转换直接指定的metadata.json失败: %v", err)
+	}
+}
+
+// TestProcessBytesUploadSizeLimit 内联发送的内容同样受客户端上传大小上限的限制，超过时不发送任何请求
+func TestProcessBytesUploadSizeLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"pages":[{"index":0,"markdown":"inline text","images":[]}],"model":"mistral-ocr-latest","usage_info":{"pages_processed":1}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	client.SetMaxUploadSizeMB(0.001)
+	processor := NewProcessor(client, zap.NewNop())
+	opts := ProcessOptions{OutputDir: t.TempDir(), NoImages: true}
+
+	large := append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("%"), 2048)...)
+	_, err := processor.ProcessBytes(context.Background(), large, "large.pdf", opts)
+	if err == nil || !strings.Contains(err.Error(), "文件大小超过限制") {
+		t.Fatalf("ProcessBytes 返回 %v，期望超过大小上限的错误", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("超过大小上限时发送了 %d 个请求", n)
+	}
+
+	small := []byte("%PDF-1.4\n%%EOF\n")
+	result, err := processor.ProcessBytes(context.Background(), small, "small.pdf", opts)
+	if err != nil {
+		t.Fatalf("ProcessBytes 返回错误: %v", err)
+	}
+	if result.Pages != 1 || requests.Load() != 1 {
+		t.Errorf("处理了 %d 页、发送了 %d 个请求，期望内联发送一次", result.Pages, requests.Load())
+	}
+}
+
+// TestParseOCRJSONFixtures 归档的原始API响应和内嵌raw_response的metadata.json都能解析出相同的页面和图片
+func TestParseOCRJSONFixtures(t *testing.T) {
+	for _, name := range []string{"ocr_response.json", "metadata_raw_response.json"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := newTestProcessor(&fakeBackend{}).parseOCRJSON(data, "testdata")
+			if err != nil {
+				t.Fatalf("parseOCRJSON 返回错误: %v", err)
+			}
+
+			if resp.Model != "mistral-ocr-2505-completion" || resp.UsageInfo.PagesProcessed != 2 {
+				t.Errorf("模型 = %s、处理页数 = %d，期望 mistral-ocr-2505-completion、2", resp.Model, resp.UsageInfo.PagesProcessed)
+			}
+			if resp.UsageInfo.DocSizeBytes == nil || *resp.UsageInfo.DocSizeBytes != 48213 {
+				t.Errorf("doc_size_bytes = %v，期望 48213", resp.UsageInfo.DocSizeBytes)
+			}
+			if len(resp.Pages) != 2 {
+				t.Fatalf("解析出 %d 页，期望 2 页", len(resp.Pages))
+			}
+			for i, page := range resp.Pages {
+				if page.Index != i || page.Dimensions.DPI != 200 {
+					t.Errorf("第 %d 页: index = %d、dpi = %d", i, page.Index, page.Dimensions.DPI)
+				}
+				if len(page.Images) != 1 {
+					t.Fatalf("第 %d 页有 %d 张图片，期望 1 张", i, len(page.Images))
+				}
+				image := page.Images[0]
+				if want := fmt.Sprintf("img-%d.jpeg", i); image.ID != want {
+					t.Errorf("第 %d 页的图片ID = %q，期望 %q", i, image.ID, want)
+				}
+				if !strings.HasPrefix(image.ImageBase64, "data:image/jpeg;base64,") || image.BottomRightX == 0 {
+					t.Errorf("第 %d 页的图片数据或位置没有解析", i)
+				}
+			}
+			if !strings.Contains(resp.Pages[0].Markdown, "![img-0.jpeg](img-0.jpeg)") {
+				t.Errorf("第 1 页的markdown = %q", resp.Pages[0].Markdown)
+			}
+
+			// 原始响应只保留OCR响应本身，不包含外层元数据
+			if bytes.Contains(resp.RawResponse, []byte("source_path")) || !bytes.Contains(resp.RawResponse, []byte(`"pages"`)) {
+				t.Errorf("原始响应应只包含OCR响应:\n%.200s", resp.RawResponse)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ClientOptions 创建客户端的选项，各字段按原值传给 Client 对应的设置方法
+//
+// 零值字段与 NewClient 的默认值不同（例如 MaxRetries 为0表示不重试），从 DefaultClientOptions 开始修改可保留默认行为。
+type ClientOptions struct {
+	APIKeys                []string
+	BaseURLs               []string
+	Timeout                time.Duration     // 见 SetTimeout
+	OCRTimeout             time.Duration     // 见 SetOCRTimeout，0表示与 Timeout 相同
+	MaxRetries             int               // 见 SetMaxRetries
+	MaxBackoff             time.Duration     // 重试等待时间的上限，见 SetBackoff，0表示不限制
+	AdaptiveTimeoutBase    time.Duration     // 按文件大小计算的超时时间的基础时间，见 SetAdaptiveTimeout
+	AdaptiveTimeoutPerMB   time.Duration     // 每MB增加的超时时间，见 SetAdaptiveTimeout
+	AdaptiveTimeoutMax     time.Duration     // 超时时间上限，见 SetAdaptiveTimeout
+	RetryDifferentEndpoint bool              // 见 SetRetryDifferentEndpoint
+	ProbeConcurrency       int               // 见 SetProbeConcurrency
+	SignedURLExpiryHours   int               // 见 SetSignedURLExpiry，0表示使用默认的24小时
+	MaxUploadSizeMB        float64           // 见 SetMaxUploadSizeMB
+	EndpointPaths          EndpointPaths     // 见 SetEndpointPaths
+	UploadURL              string            // 见 SetUploadURL
+	SignedURLEndpoint      string            // 见 SetSignedURLEndpoint
+	OCRURL                 string            // 见 SetOCRURL
+	QueryParams            map[string]string // 见 SetQueryParams
+	CACertFile             string            // 见 SetCACertFile
+	InsecureSkipVerify     bool              // 见 SetInsecureSkipVerify
+}
+
+// DefaultClientOptions 返回与 NewClient 的默认行为相同的选项
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:                5 * time.Minute,
+		MaxRetries:             3,
+		MaxBackoff:             defaultBackoffMax,
+		RetryDifferentEndpoint: true,
+		SignedURLExpiryHours:   defaultSignedURLExpiryHours,
+		MaxUploadSizeMB:        DefaultMaxUploadSizeMB,
+	}
+}
+
+// NewClientWithOptions 根据选项创建客户端，应用超时（包括按文件大小计算的超时）、重试、退避、端点切换、端点探测并发数、签名URL有效期、上传大小上限、接口路径、完整请求URL、查询参数和TLS设置
+//
+// 只会因签名URL有效期为负数或CA证书无法读取或解析而返回错误。
+func NewClientWithOptions(opts ClientOptions) (*Client, error) {
+	client := NewClient(opts.APIKeys, opts.BaseURLs)
+	client.SetTimeout(opts.Timeout)
+	client.SetOCRTimeout(opts.OCRTimeout)
+	client.SetMaxRetries(opts.MaxRetries)
+	client.SetBackoff(defaultBackoffBase, opts.MaxBackoff)
+	client.SetAdaptiveTimeout(opts.AdaptiveTimeoutBase, opts.AdaptiveTimeoutPerMB, opts.AdaptiveTimeoutMax)
+	client.SetRetryDifferentEndpoint(opts.RetryDifferentEndpoint)
+	client.SetProbeConcurrency(opts.ProbeConcurrency)
+	if opts.SignedURLExpiryHours != 0 {
+		if err := client.SetSignedURLExpiry(opts.SignedURLExpiryHours); err != nil {
+			return nil, err
+		}
+	}
+	client.SetMaxUploadSizeMB(opts.MaxUploadSizeMB)
+	client.SetEndpointPaths(opts.EndpointPaths)
+	client.SetUploadURL(opts.UploadURL)
+	client.SetSignedURLEndpoint(opts.SignedURLEndpoint)
+	client.SetOCRURL(opts.OCRURL)
+	client.SetQueryParams(opts.QueryParams)
+	if opts.CACertFile != "" {
+		if err := client.SetCACertFile(opts.CACertFile); err != nil {
+			return nil, err
+		}
+	}
+	if opts.InsecureSkipVerify {
+		client.SetInsecureSkipVerify(true)
+	}
+	return client, nil
+}
+
+// Run 根据选项创建客户端和处理器并处理单个来源，不输出日志
+//
+// 来源的处理方式见 Processor.Run。
+func Run(ctx context.Context, clientOpts ClientOptions, source string, opts ProcessOptions) (*ProcessResult, error) {
+	client, err := NewClientWithOptions(clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	return NewProcessor(client, zap.NewNop()).Run(ctx, source, opts)
+}
+
+// Run 根据来源类型选择处理方式
+//
+// 本地存在的路径优先按文件处理：.json文件转换为Markdown，其他文件进行OCR；
+// 本地不存在且是http(s)地址时按URL处理。目录请使用 ProcessMultipleFiles。
+func (p *Processor) Run(ctx context.Context, source string, opts ProcessOptions) (*ProcessResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	info, statErr := os.Stat(source)
+	if statErr != nil {
+		if IsHTTPURL(source) {
+			return p.ProcessURL(ctx, source, opts)
+		}
+		return nil, fmt.Errorf("获取文件信息失败: %w", statErr)
+	}
+
+	if info.IsDir() {
+		return nil, fmt.Errorf("不支持直接处理目录，请使用批量处理: %s", source)
+	}
+	if strings.EqualFold(filepath.Ext(source), ".json") {
+		return p.ConvertJSONToMarkdown(source, opts)
+	}
+	return p.ProcessFile(ctx, source, opts)
+}
+
+// IsHTTPURL 判断字符串是否为http或https地址
+func IsHTTPURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
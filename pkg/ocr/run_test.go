@@ -0,0 +1,88 @@
+package ocr
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestDefaultClientOptions 以默认选项创建的客户端与 NewClient 的默认设置相同
+func TestDefaultClientOptions(t *testing.T) {
+	opts := DefaultClientOptions()
+	opts.APIKeys = []string{"key"}
+	opts.BaseURLs = []string{"https://api.example.com/v1"}
+	got, err := NewClientWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewClient(opts.APIKeys, opts.BaseURLs)
+
+	if got.httpTimeout != want.httpTimeout || got.maxRetries != want.maxRetries ||
+		got.backoffBase != want.backoffBase || got.backoffMax != want.backoffMax ||
+		got.retryDifferentEndpoint != want.retryDifferentEndpoint ||
+		got.signedURLExpiryHours != want.signedURLExpiryHours || got.maxUploadSizeMB != want.maxUploadSizeMB ||
+		!reflect.DeepEqual(got.paths, want.paths) || !reflect.DeepEqual(got.baseURLs, want.baseURLs) {
+		t.Errorf("默认选项创建的客户端与 NewClient 的默认设置不同")
+	}
+}
+
+func TestNewClientWithOptions(t *testing.T) {
+	client, err := NewClientWithOptions(ClientOptions{
+		APIKeys:                []string{"a", "b"},
+		BaseURLs:               []string{"https://api.example.com/v1"},
+		Timeout:                90 * time.Second,
+		OCRTimeout:             20 * time.Minute,
+		MaxRetries:             5,
+		MaxBackoff:             10 * time.Second,
+		AdaptiveTimeoutBase:    time.Minute,
+		AdaptiveTimeoutPerMB:   20 * time.Second,
+		AdaptiveTimeoutMax:     30 * time.Minute,
+		RetryDifferentEndpoint: false,
+		ProbeConcurrency:       4,
+		SignedURLExpiryHours:   2,
+		MaxUploadSizeMB:        100,
+		EndpointPaths:          EndpointPaths{OCR: "/v2/ocr"},
+		UploadURL:              "https://upload.example.com/files",
+		OCRURL:                 " https://ocr.example.com/ocr ",
+		QueryParams:            map[string]string{"api-version": "2025-01-01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"httpTimeout", client.httpTimeout, 90 * time.Second},
+		{"ocrTimeout", client.ocrTimeout, 20 * time.Minute},
+		{"maxRetries", client.maxRetries, 5},
+		{"backoffMax", client.backoffMax, 10 * time.Second},
+		{"adaptiveTimeout", *client.adaptiveTimeout, adaptiveTimeout{base: time.Minute, perMB: 20 * time.Second, max: 30 * time.Minute}},
+		{"retryDifferentEndpoint", client.retryDifferentEndpoint, false},
+		{"probeConcurrency", client.probeConcurrency, 4},
+		{"signedURLExpiryHours", client.signedURLExpiryHours, 2},
+		{"maxUploadSizeMB", client.maxUploadSizeMB, 100.0},
+		{"paths.OCR", client.paths.OCR, "v2/ocr"},
+		{"paths.Files", client.paths.Files, DefaultEndpointPaths().Files},
+		{"uploadURL", client.uploadURL, "https://upload.example.com/files"},
+		{"ocrURL", client.ocrURL, "https://ocr.example.com/ocr"},
+		{"queryParams", client.queryParams, map[string]string{"api-version": "2025-01-01"}},
+		{"baseURLs", client.baseURLs, []string{"https://api.example.com/v1/"}},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v，期望 %v", c.name, c.got, c.want)
+		}
+	}
+
+	if _, err := NewClientWithOptions(ClientOptions{CACertFile: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("CA证书不存在时没有返回错误")
+	}
+	if _, err := NewClientWithOptions(ClientOptions{SignedURLExpiryHours: -5}); err == nil {
+		t.Error("签名URL有效期为负数时没有返回错误")
+	}
+	if client, err := NewClientWithOptions(ClientOptions{}); err != nil || client.signedURLExpiryHours != defaultSignedURLExpiryHours {
+		t.Errorf("未设置签名URL有效期时应使用默认值，得到 %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package ocr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// SidecarSuffix 单个文件处理选项文件的后缀，如 report.pdf 对应 report.pdf.ocr.json
+const SidecarSuffix = ".ocr.json"
+
+// SidecarOptions 表示与输入文件放在一起的处理选项文件的内容，批量处理时覆盖该文件的 ProcessOptions
+//
+// 只有文件中出现的字段会覆盖批量处理的选项，其余选项保持不变。字段含义与 ProcessOptions 中的同名选项相同，
+// 包含未知字段时报错，避免拼写错误的选项被静默忽略。例如：
+//
+//	{"output_name": "annual-report", "no_images": true, "max_pages": 10}
+type SidecarOptions struct {
+	OutputName         *string  `json:"output_name"`          // 对应 CustomOutputName，批量处理时不再添加序号
+	IncludeImages      *bool    `json:"include_images"`       // 对应 IncludeImages
+	SaveImages         *bool    `json:"save_images"`          // 对应 SaveImages
+	LinkImages         *bool    `json:"link_images"`          // 对应 LinkImages
+	KeepImagesInText   *bool    `json:"keep_images_in_text"`  // 对应 KeepImagesInText
+	NoImages           *bool    `json:"no_images"`            // 对应 NoImages
+	ImageNaming        *string  `json:"image_naming"`         // 对应 ImageNaming
+	ImageLinkBase      *string  `json:"image_link_base"`      // 对应 ImageLinkBase
+	DedupImages        *bool    `json:"dedup_images"`         // 对应 DedupImages
+	StripImageMetadata *bool    `json:"strip_image_metadata"` // 对应 StripImageMetadata
+	OnImageError       *string  `json:"on_image_error"`       // 对应 OnImageError
+	SplitPages         *bool    `json:"split_pages"`          // 对应 SplitPages
+	GenerateTOC        *bool    `json:"toc"`                  // 对应 GenerateTOC
+	MinPageTextLength  *int     `json:"min_page_text_length"` // 对应 MinPageTextLength
+	MaxPages           *int     `json:"max_pages"`            // 对应 MaxPages
+	ChunkPages         *int     `json:"chunk_pages"`          // 对应 ChunkPages
+	PDFPassword        *string  `json:"pdf_password"`         // 对应 PDFPassword，优先于 PDFPasswords 中为该文件指定的密码
+	DehyphenateText    *bool    `json:"dehyphenate"`          // 对应 DehyphenateText
+	NormalizeUnicode   *bool    `json:"normalize_unicode"`    // 对应 NormalizeUnicode
+	HalfWidth          *bool    `json:"half_width"`           // 对应 FullWidthToHalfWidth
+	OutputFormats      []string `json:"formats"`              // 对应 OutputFormats
+	MarkdownTemplate   *string  `json:"markdown_template"`    // 对应 MarkdownTemplate
+	WordBoxes          *bool    `json:"word_boxes"`           // 对应 WordBoxes
+}
+
+// sidecarPath 返回输入文件对应的处理选项文件路径
+func sidecarPath(filePath string) string {
+	return filePath + SidecarSuffix
+}
+
+// loadSidecarOptions 在文件操作上限内读取输入文件对应的处理选项文件，文件不存在时返回nil
+func loadSidecarOptions(filePath string, limit fileLimiter) (*SidecarOptions, error) {
+	path := sidecarPath(filePath)
+	data, err := limit.readFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取处理选项文件失败: %w", err)
+	}
+
+	var sidecar SidecarOptions
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&sidecar); err != nil {
+		return nil, fmt.Errorf("解析处理选项文件 %s 失败: %w", path, err)
+	}
+	return &sidecar, nil
+}
+
+// apply 返回用处理选项文件中出现的字段覆盖后的选项
+func (s *SidecarOptions) apply(opts ProcessOptions) ProcessOptions {
+	setString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setBool := func(dst *bool, src *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setInt := func(dst *int, src *int) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	setString(&opts.CustomOutputName, s.OutputName)
+	setBool(&opts.IncludeImages, s.IncludeImages)
+	setBool(&opts.SaveImages, s.SaveImages)
+	setBool(&opts.LinkImages, s.LinkImages)
+	setBool(&opts.KeepImagesInText, s.KeepImagesInText)
+	setBool(&opts.NoImages, s.NoImages)
+	setString(&opts.ImageNaming, s.ImageNaming)
+	setString(&opts.ImageLinkBase, s.ImageLinkBase)
+	setBool(&opts.DedupImages, s.DedupImages)
+	setBool(&opts.StripImageMetadata, s.StripImageMetadata)
+	setString(&opts.OnImageError, s.OnImageError)
+	setBool(&opts.SplitPages, s.SplitPages)
+	setBool(&opts.GenerateTOC, s.GenerateTOC)
+	setInt(&opts.MinPageTextLength, s.MinPageTextLength)
+	setInt(&opts.MaxPages, s.MaxPages)
+	setInt(&opts.ChunkPages, s.ChunkPages)
+	setBool(&opts.DehyphenateText, s.DehyphenateText)
+	setBool(&opts.NormalizeUnicode, s.NormalizeUnicode)
+	setBool(&opts.FullWidthToHalfWidth, s.HalfWidth)
+	setString(&opts.MarkdownTemplate, s.MarkdownTemplate)
+	setBool(&opts.WordBoxes, s.WordBoxes)
+	if s.OutputFormats != nil {
+		opts.OutputFormats = s.OutputFormats
+	}
+	if s.PDFPassword != nil {
+		// 按文件指定的密码优先于 PDFPasswords，清空后 pdfPasswordFor 使用该密码
+		opts.PDFPassword = *s.PDFPassword
+		opts.PDFPasswords = nil
+	}
+	return opts
+}
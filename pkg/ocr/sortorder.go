@@ -0,0 +1,77 @@
+package ocr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortOrder 的可选值
+const (
+	SortOrderLexical = "lexical" // 按路径的字节顺序排序，如 file10.pdf 排在 file2.pdf 之前
+	SortOrderNatural = "natural" // 按路径中数字的大小排序，如 file2.pdf 排在 file10.pdf 之前
+)
+
+// validateSortOrder 校验 SortOrder 的取值
+func (o ProcessOptions) validateSortOrder() error {
+	switch o.SortOrder {
+	case "", SortOrderLexical, SortOrderNatural:
+		return nil
+	}
+	return fmt.Errorf("无效的排序方式: %s，可选值为 %s 或 %s", o.SortOrder, SortOrderLexical, SortOrderNatural)
+}
+
+// sortFiles 按 SortOrder 对待处理的文件排序，默认按字节顺序
+func sortFiles(files []string, order string) {
+	if order == SortOrderNatural {
+		sort.SliceStable(files, func(i, j int) bool {
+			return naturalLess(files[i], files[j])
+		})
+		return
+	}
+	sort.Strings(files)
+}
+
+// naturalLess 按自然顺序比较两个路径：连续的数字按数值比较，其他部分按字节比较，
+// 数值相同（如 01 和 1）时按字节顺序区分
+func naturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			ni, nj := digitRunEnd(a, i), digitRunEnd(b, j)
+			da := strings.TrimLeft(a[i:ni], "0")
+			db := strings.TrimLeft(b[j:nj], "0")
+			if len(da) != len(db) {
+				return len(da) < len(db)
+			}
+			if da != db {
+				return da < db
+			}
+			i, j = ni, nj
+			continue
+		}
+		if a[i] != b[j] {
+			return a[i] < b[j]
+		}
+		i++
+		j++
+	}
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	return a < b
+}
+
+// digitRunEnd 返回s中从start开始的连续数字的结束位置
+func digitRunEnd(s string, start int) int {
+	end := start
+	for end < len(s) && isDigit(s[end]) {
+		end++
+	}
+	return end
+}
+
+// isDigit 判断字节是否为ASCII数字
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,106 @@
+package ocr
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSortFiles(t *testing.T) {
+	files := []string{"b/file10.pdf", "a/file2.pdf", "file1.pdf", "a/file10.pdf", "a/file01.pdf", "a/file1.pdf", "b/file9.pdf"}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{
+			order: "",
+			want:  []string{"a/file01.pdf", "a/file1.pdf", "a/file10.pdf", "a/file2.pdf", "b/file10.pdf", "b/file9.pdf", "file1.pdf"},
+		},
+		{
+			order: SortOrderLexical,
+			want:  []string{"a/file01.pdf", "a/file1.pdf", "a/file10.pdf", "a/file2.pdf", "b/file10.pdf", "b/file9.pdf", "file1.pdf"},
+		},
+		{
+			order: SortOrderNatural,
+			want:  []string{"a/file01.pdf", "a/file1.pdf", "a/file2.pdf", "a/file10.pdf", "b/file9.pdf", "b/file10.pdf", "file1.pdf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("order="+tt.order, func(t *testing.T) {
+			got := append([]string(nil), files...)
+			sortFiles(got, tt.order)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortFiles(%q) = %v，期望 %v", tt.order, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProcessMultipleFilesDeterministicOrder 同一目录结构无论以什么顺序传入路径，处理顺序和输出名称的序号都相同
+func TestProcessMultipleFilesDeterministicOrder(t *testing.T) {
+	inputDir := t.TempDir()
+	writeTestPDFs(t, inputDir, "scan10.pdf", "scan2.pdf", "sub/scan1.pdf", "scan1.pdf")
+
+	paths := [][]string{
+		{inputDir},
+		{filepath.Join(inputDir, "sub"), filepath.Join(inputDir, "scan2.pdf"), filepath.Join(inputDir, "scan10.pdf"), filepath.Join(inputDir, "scan1.pdf")},
+		{filepath.Join(inputDir, "scan1.pdf"), filepath.Join(inputDir, "sub"), filepath.Join(inputDir, "scan10.pdf"), filepath.Join(inputDir, "scan2.pdf")},
+	}
+	wantUploads := map[string][]string{
+		SortOrderLexical: {"scan1.pdf", "scan10.pdf", "scan2.pdf", "scan1.pdf"},
+		SortOrderNatural: {"scan1.pdf", "scan2.pdf", "scan10.pdf", "scan1.pdf"},
+	}
+	wantOutputs := map[string][]string{
+		SortOrderLexical: {"doc_1", "doc_2", "doc_3", "doc_4"},
+		SortOrderNatural: {"doc_1", "doc_2", "doc_3", "doc_4"},
+	}
+	wantSources := map[string][]string{
+		SortOrderLexical: {"scan1.pdf", "scan10.pdf", "scan2.pdf", "sub/scan1.pdf"},
+		SortOrderNatural: {"scan1.pdf", "scan2.pdf", "scan10.pdf", "sub/scan1.pdf"},
+	}
+
+	for _, order := range []string{SortOrderLexical, SortOrderNatural} {
+		for i, input := range paths {
+			backend := &fakeBackend{pages: 1}
+			var sources []string
+			opts := ProcessOptions{
+				OutputDir:        filepath.Join(t.TempDir(), "out"),
+				CustomOutputName: "doc",
+				SortOrder:        order,
+				NoImages:         true,
+				OnFileComplete: func(result *ProcessResult, err error) {
+					if err != nil {
+						t.Fatalf("处理文件失败: %v", err)
+					}
+				},
+			}
+			results, err := newTestProcessor(backend).ProcessMultipleFiles(context.Background(), input, opts)
+			if err != nil {
+				t.Fatalf("%s 第%d组路径: ProcessMultipleFiles 返回错误: %v", order, i, err)
+			}
+
+			var outputs []string
+			for _, result := range results {
+				outputs = append(outputs, filepath.Base(result.OutputDir))
+				metadata, _, err := LoadMetadata(result.OutputDir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rel, _ := filepath.Rel(inputDir, metadata.SourcePath)
+				sources = append(sources, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(backend.uploads, wantUploads[order]) {
+				t.Errorf("%s 第%d组路径: 上传顺序 = %v，期望 %v", order, i, backend.uploads, wantUploads[order])
+			}
+			if !reflect.DeepEqual(outputs, wantOutputs[order]) {
+				t.Errorf("%s 第%d组路径: 输出目录 = %v，期望 %v", order, i, outputs, wantOutputs[order])
+			}
+			if !reflect.DeepEqual(sources, wantSources[order]) {
+				t.Errorf("%s 第%d组路径: 源文件顺序 = %v，期望 %v", order, i, sources, wantSources[order])
+			}
+		}
+	}
+}
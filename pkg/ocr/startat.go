@@ -0,0 +1,62 @@
+package ocr
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// validateStartPosition 校验 StartAt 和 StartAfter 的取值，两者不能同时设置
+func (o ProcessOptions) validateStartPosition() error {
+	if o.StartAt < 0 {
+		return fmt.Errorf("无效的起始位置 %d，不能为负数", o.StartAt)
+	}
+	if o.StartAt > 0 && o.StartAfter != "" {
+		return fmt.Errorf("起始位置和起始文件不能同时指定")
+	}
+	return nil
+}
+
+// applyStartPosition 按 StartAt 或 StartAfter 跳过排序后的文件列表中起始位置之前的文件，返回剩余的文件
+//
+// StartAt 从1开始计数；StartAfter 可以是文件路径或文件名，按文件名匹配时必须只匹配一个文件。
+func applyStartPosition(files []string, opts ProcessOptions) ([]string, error) {
+	switch {
+	case opts.StartAt > 1:
+		if opts.StartAt > len(files) {
+			return nil, fmt.Errorf("起始位置 %d 超过了文件数量 %d", opts.StartAt, len(files))
+		}
+		return files[opts.StartAt-1:], nil
+	case opts.StartAfter != "":
+		i, err := findStartAfter(files, opts.StartAfter)
+		if err != nil {
+			return nil, err
+		}
+		return files[i+1:], nil
+	}
+	return files, nil
+}
+
+// findStartAfter 返回文件列表中与name对应的文件的位置，先按路径匹配，再按文件名匹配
+func findStartAfter(files []string, name string) (int, error) {
+	key := manifestKey(name)
+	for i, file := range files {
+		if manifestKey(file) == key {
+			return i, nil
+		}
+	}
+
+	found := -1
+	for i, file := range files {
+		if filepath.Base(file) != name {
+			continue
+		}
+		if found >= 0 {
+			return 0, fmt.Errorf("文件名 %s 匹配了多个文件: %s 和 %s，请指定路径", name, files[found], file)
+		}
+		found = i
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("在待处理的文件中找不到起始文件: %s", name)
+	}
+	return found, nil
+}
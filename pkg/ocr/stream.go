@@ -0,0 +1,115 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// StreamingOCRBackend 表示支持流式解析OCR响应的后端
+//
+// 设置 StreamLargeResponses 时，Processor 在后端实现了该接口的情况下使用流式解析，
+// 否则退回到 ProcessOCRWithType。
+type StreamingOCRBackend interface {
+	ProcessOCRStream(ctx context.Context, documentURL string, documentType string, includeImageBase64 bool, apiKey string, raw io.Writer, onPage func(Page) error) (*OCRResponse, error)
+}
+
+// 确保 *Client 实现了 StreamingOCRBackend 接口
+var _ StreamingOCRBackend = (*Client)(nil)
+
+// ProcessOCRStream 使用OCR处理文档，并在解析响应时逐页回调onPage
+//
+// 响应体不会整体读入内存：每页解析完成后立即调用onPage，随后丢弃该页图片的base64数据，
+// 返回的响应中只保留页面文本和图片位置，RawResponse为nil。raw不为nil时原始响应体会同时写入raw。
+// 重试和切换端点的规则与 ProcessOCRWithType 相同，但开始解析后出错不再重试。
+func (c *Client) ProcessOCRStream(ctx context.Context, documentURL string, documentType string, includeImageBase64 bool, apiKey string, raw io.Writer, onPage func(Page) error) (*OCRResponse, error) {
+	if onPage == nil {
+		onPage = func(Page) error { return nil }
+	}
+	return c.processOCR(ctx, documentURL, documentType, includeImageBase64, apiKey, nil, raw, onPage)
+}
+
+// decodeOCRResponseStream 使用json.Decoder逐页解析OCR响应
+func decodeOCRResponseStream(body io.Reader, raw io.Writer, onPage func(Page) error) (*OCRResponse, error) {
+	if raw != nil {
+		body = io.TeeReader(body, raw)
+	}
+	dec := json.NewDecoder(body)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	var resp OCRResponse
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("无效的响应字段: %v", token)
+		}
+
+		switch key {
+		case "pages":
+			if err := expectDelim(dec, '['); err != nil {
+				return nil, err
+			}
+			for dec.More() {
+				var page Page
+				if err := dec.Decode(&page); err != nil {
+					return nil, fmt.Errorf("解析第 %d 页失败: %w", len(resp.Pages)+1, err)
+				}
+				if err := onPage(page); err != nil {
+					return nil, err
+				}
+				// 图片已由回调处理，释放base64数据
+				for i := range page.Images {
+					page.Images[i].ImageBase64 = ""
+				}
+				resp.Pages = append(resp.Pages, page)
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return nil, err
+			}
+		case "model":
+			err = dec.Decode(&resp.Model)
+		case "usage_info":
+			err = dec.Decode(&resp.UsageInfo)
+		case "document_annotation":
+			err = dec.Decode(&resp.DocumentAnnotation)
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析字段 %s 失败: %w", key, err)
+		}
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+
+	// 读完剩余内容，保证原始响应完整写入raw
+	if raw != nil {
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return nil, err
+		}
+	}
+	return &resp, nil
+}
+
+// expectDelim 读取下一个JSON标记并检查是否为指定的分隔符
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("响应格式错误，期望 %v，实际为 %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package ocr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+// stripJPEGQuality 去除元数据时重新编码JPEG图片使用的质量
+const stripJPEGQuality = 95
+
+// stripImageMetadata 解码并重新编码图片，去除其中的EXIF、XMP、文本块等元数据
+//
+// 只支持JPEG和PNG：PNG重新编码是无损的，JPEG按 stripJPEGQuality 重新压缩。
+// EXIF中的方向信息也会被去除，带方向标记的照片可能显示为旋转前的方向。
+// 无法解码或不支持的格式返回错误，由调用方决定是否保存原始数据。
+func stripImageMetadata(data []byte) ([]byte, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码图片失败: %w", err)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: stripJPEGQuality})
+	case "png":
+		err = png.Encode(&buf, img)
+	default:
+		return nil, fmt.Errorf("不支持去除 %s 格式图片的元数据", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("重新编码图片失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,141 @@
+package ocr
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 批量处理摘要的文件名，保存在输出目录下
+const (
+	SummaryJSONFileName     = "summary.json"
+	SummaryMarkdownFileName = "summary.md"
+)
+
+// BatchSummary 汇总一次批量处理的结果
+type BatchSummary struct {
+	StartedAt   string          `json:"started_at"`   // 开始时间
+	FinishedAt  string          `json:"finished_at"`  // 结束时间
+	Elapsed     string          `json:"elapsed"`      // 总耗时
+	Interrupted bool            `json:"interrupted"`  // 是否被中断
+	TotalFiles  int             `json:"total_files"`  // 文件总数
+	Succeeded   int             `json:"succeeded"`    // 处理成功的文件数
+	Skipped     int             `json:"skipped"`      // 输出已存在而跳过的文件数
+	Resumed     int             `json:"resumed"`      // 之前的清单中已完成、本次没有处理的文件数
+	Failed      int             `json:"failed"`       // 处理失败的文件数
+	Pending     int             `json:"pending"`      // 中断时尚未处理的文件数
+	TotalPages  int             `json:"total_pages"`  // 本次处理的总页数
+	TotalImages int             `json:"total_images"` // 本次保存的图片总数
+	Timings     PhaseTimings    `json:"timings"`      // 本次处理的文件各阶段耗时之和
+	Files       []ManifestEntry `json:"files"`        // 每个文件的处理结果
+}
+
+// newBatchSummary 根据清单生成批量处理摘要，从之前的清单恢复的文件只计入 Resumed
+func newBatchSummary(manifest *BatchManifest, elapsed time.Duration) *BatchSummary {
+	summary := &BatchSummary{
+		StartedAt:   manifest.StartedAt,
+		FinishedAt:  manifest.FinishedAt,
+		Elapsed:     elapsed.Round(time.Millisecond).String(),
+		Interrupted: manifest.Interrupted,
+		TotalFiles:  len(manifest.Files),
+		Files:       manifest.Files,
+	}
+	for _, entry := range manifest.Files {
+		if entry.Resumed {
+			summary.Resumed++
+			continue
+		}
+		switch entry.Status {
+		case ManifestStatusProcessed:
+			summary.Succeeded++
+		case ManifestStatusSkipped:
+			summary.Skipped++
+		case ManifestStatusFailed:
+			summary.Failed++
+		default:
+			summary.Pending++
+		}
+		summary.TotalPages += entry.Pages
+		summary.TotalImages += entry.Images
+		if entry.Timings != nil {
+			summary.Timings.Add(*entry.Timings)
+		}
+	}
+	return summary
+}
+
+// Markdown 生成便于阅读的摘要，包含总体统计和每个文件一行的表格
+func (s *BatchSummary) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# 批量处理摘要\n\n")
+	if s.Interrupted {
+		b.WriteString("> 批量处理被中断，部分文件尚未处理。\n\n")
+	}
+	fmt.Fprintf(&b, "- 开始时间: %s\n", s.StartedAt)
+	fmt.Fprintf(&b, "- 结束时间: %s\n", s.FinishedAt)
+	fmt.Fprintf(&b, "- 耗时: %s\n", s.Elapsed)
+	fmt.Fprintf(&b, "- 文件: 共 %d 个，成功 %d，跳过 %d，已完成（恢复） %d，失败 %d，未处理 %d\n", s.TotalFiles, s.Succeeded, s.Skipped, s.Resumed, s.Failed, s.Pending)
+	fmt.Fprintf(&b, "- 页数: %d\n", s.TotalPages)
+	fmt.Fprintf(&b, "- 图片: %d\n", s.TotalImages)
+	fmt.Fprintf(&b, "- 阶段耗时: 上传 %s，获取签名URL %s，OCR %s，保存 %s\n\n",
+		s.Timings.Upload.Round(time.Millisecond), s.Timings.SignedURL.Round(time.Millisecond),
+		s.Timings.OCR.Round(time.Millisecond), s.Timings.Save.Round(time.Millisecond))
+
+	b.WriteString("| 文件 | 状态 | 页数 | 图片 | 输出目录 | 错误 |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, entry := range s.Files {
+		status := entry.Status
+		if entry.Resumed {
+			status += "（恢复）"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %s | %s |\n",
+			markdownTableCell(entry.Path), status, entry.Pages, entry.Images,
+			markdownTableCell(entry.OutputDir), markdownTableCell(entry.Error))
+	}
+	return b.String()
+}
+
+// markdownTableCell 转义表格单元格中的竖线和换行
+func markdownTableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", " ")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// writeSummary 将批量处理摘要写入输出目录的summary.json和summary.md
+func (p *Processor) writeSummary(opts ProcessOptions, summary *BatchSummary) {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		p.logger.Warn("生成批量处理摘要失败", zap.Error(err))
+		return
+	}
+
+	if err := opts.mkdirAll(opts.OutputDir); err != nil {
+		p.logger.Warn("创建输出目录失败", zap.String("outputDir", opts.OutputDir), zap.Error(err))
+		return
+	}
+
+	jsonPath := filepath.Join(opts.OutputDir, SummaryJSONFileName)
+	if err := opts.writeFile(jsonPath, data); err != nil {
+		p.logger.Warn("写入批量处理摘要失败", zap.String("path", jsonPath), zap.Error(err))
+		return
+	}
+	mdPath := filepath.Join(opts.OutputDir, SummaryMarkdownFileName)
+	if err := opts.writeFile(mdPath, []byte(summary.Markdown())); err != nil {
+		p.logger.Warn("写入批量处理摘要失败", zap.String("path", mdPath), zap.Error(err))
+		return
+	}
+	p.logger.Info("保存了批量处理摘要",
+		zap.String("path", mdPath),
+		zap.Int("succeeded", summary.Succeeded),
+		zap.Int("skipped", summary.Skipped),
+		zap.Int("resumed", summary.Resumed),
+		zap.Int("failed", summary.Failed),
+		zap.Int("pages", summary.TotalPages),
+		zap.Int("images", summary.TotalImages),
+		zap.String("elapsed", summary.Elapsed))
+}
@@ -0,0 +1,237 @@
+package ocr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// textLayerMinCharsPerPage 平均每页文本字符数达到该值时认为PDF已包含文本层
+const textLayerMinCharsPerPage = 50
+
+// maxProbeStreamSize 探测时每个流解压后读取的最大字节数
+const maxProbeStreamSize = 4 * 1024 * 1024
+
+// PDFTextLayer 表示对PDF文本层的探测结果
+//
+// 探测只解析PDF中的流对象，不完整解析文档结构，字符数为估计值。
+type PDFTextLayer struct {
+	Pages     int  // 页数（页面树根节点的 /Count，无法读取时为0）
+	TextChars int  // 文本绘制操作中的字符数（估计值）
+	Images    int  // 图片对象数量
+	Encrypted bool // 是否加密，加密的PDF无法探测文本
+}
+
+// HasText 判断PDF是否已包含可提取的文本，即平均每页的文本字符数是否足够多
+//
+// 为true时说明PDF很可能是数字文档而非扫描件，直接提取文本即可，不一定需要OCR。
+func (l *PDFTextLayer) HasText() bool {
+	if l.Encrypted {
+		return false
+	}
+	pages := l.Pages
+	if pages < 1 {
+		pages = 1
+	}
+	return l.TextChars >= textLayerMinCharsPerPage*pages
+}
+
+var (
+	// pdfObjStmPattern 匹配对象流的类型声明
+	pdfObjStmPattern = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	// pdfImagePattern 匹配图片对象的类型声明
+	pdfImagePattern = regexp.MustCompile(`/Subtype\s*/Image\b`)
+	// pdfLiteralTextPattern 匹配以字面字符串绘制文本的操作：(text) Tj、(text) ' 和 (text) "
+	pdfLiteralTextPattern = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\)\s*(?:Tj|'|")`)
+	// pdfHexTextPattern 匹配以十六进制字符串绘制文本的操作：<hex> Tj
+	pdfHexTextPattern = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>\s*Tj`)
+	// pdfArrayTextPattern 匹配文本数组绘制操作：[(a) -20 (b)] TJ
+	pdfArrayTextPattern = regexp.MustCompile(`\[((?:\\.|[^\\\]])*)\]\s*TJ`)
+	// pdfArrayLiteralPattern 匹配文本数组中的字面字符串
+	pdfArrayLiteralPattern = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\)`)
+	// pdfArrayHexPattern 匹配文本数组中的十六进制字符串
+	pdfArrayHexPattern = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>`)
+)
+
+// ProbePDFTextLayer 探测PDF是否已包含文本层，用于在OCR前判断文件是否为扫描件
+//
+// 解压内容流（FlateDecode）并统计文本绘制操作中的字符数，同时统计页数和图片数量。
+// 加密的PDF只标记 Encrypted，不统计文本。
+func ProbePDFTextLayer(path string) (*PDFTextLayer, error) {
+	return probePDFTextLayerFile(path, nil)
+}
+
+// probePDFTextLayerFile 在文件操作上限内读取并探测PDF文件，见 ProbePDFTextLayer
+func probePDFTextLayerFile(path string, limit fileLimiter) (*PDFTextLayer, error) {
+	data, err := limit.readFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取PDF文件失败: %w", err)
+	}
+	if !isPDFData(data) {
+		return nil, fmt.Errorf("不是有效的PDF文件: %s", path)
+	}
+	return probePDFTextLayer(data), nil
+}
+
+// isPDFData 判断内容是否以PDF文件头开始
+func isPDFData(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-"))
+}
+
+// probePDFTextLayer 探测内存中的PDF内容，见 ProbePDFTextLayer
+func probePDFTextLayer(data []byte) *PDFTextLayer {
+	pages, _ := pdfPageCount(data, "")
+	layer := &PDFTextLayer{
+		Pages:     pages,
+		Images:    len(pdfImagePattern.FindAllIndex(data, -1)),
+		Encrypted: bytes.Contains(data, []byte("/Encrypt")),
+	}
+	if layer.Encrypted {
+		return layer
+	}
+
+	for _, stream := range pdfStreams(data) {
+		if stream.objectStream {
+			// 对象流中只有压缩的对象，没有文本绘制操作
+			continue
+		}
+		content, ok := decodeProbeStream(stream)
+		if !ok {
+			continue
+		}
+		layer.TextChars += countTextChars(content)
+	}
+	return layer
+}
+
+// pdfStream 表示PDF中的一个流对象
+type pdfStream struct {
+	dict         []byte // 流的字典
+	data         []byte // 流的原始数据
+	objectStream bool   // 是否为对象流（/Type /ObjStm）
+}
+
+// pdfStreams 查找PDF中的所有流对象，根据 stream/endstream 关键字定位数据，不依赖 /Length
+func pdfStreams(data []byte) []pdfStream {
+	var streams []pdfStream
+	for offset := 0; ; {
+		i := bytes.Index(data[offset:], []byte("stream"))
+		if i < 0 {
+			break
+		}
+		start := offset + i
+		offset = start + len("stream")
+
+		// 跳过 endstream
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+		// 字典在 stream 关键字之前，以 >> 结尾
+		before := bytes.TrimRight(data[:start], "\r\n\t ")
+		if !bytes.HasSuffix(before, []byte(">>")) {
+			continue
+		}
+		dictStart := bytes.LastIndex(before, []byte(" obj"))
+		if dictStart < 0 || len(before)-dictStart > 4096 {
+			dictStart = max(0, len(before)-4096)
+		}
+		dict := before[dictStart:]
+
+		// 数据从 stream 后的换行开始，到 endstream 为止
+		dataStart := offset
+		if dataStart < len(data) && data[dataStart] == '\r' {
+			dataStart++
+		}
+		if dataStart < len(data) && data[dataStart] == '\n' {
+			dataStart++
+		}
+		end := bytes.Index(data[dataStart:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		streams = append(streams, pdfStream{
+			dict:         dict,
+			data:         data[dataStart : dataStart+end],
+			objectStream: pdfObjStmPattern.Match(dict),
+		})
+		offset = dataStart + end + len("endstream")
+	}
+	return streams
+}
+
+// decodeProbeStream 解码可能包含文本的流，图片、字体等二进制流以及无法解码的流返回false
+func decodeProbeStream(stream pdfStream) ([]byte, bool) {
+	dict := stream.dict
+	// 只有表单XObject带有 /Subtype 时仍包含绘制内容，字体文件带有 /Length1
+	if bytes.Contains(dict, []byte("/Subtype")) && !bytes.Contains(dict, []byte("/Form")) {
+		return nil, false
+	}
+	if bytes.Contains(dict, []byte("/Length1")) || bytes.Contains(dict, []byte("/XRef")) || bytes.Contains(dict, []byte("/Metadata")) {
+		return nil, false
+	}
+
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		return stream.data, true
+	}
+	// 只处理单独使用FlateDecode的流
+	if !bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Count(dict, []byte("Decode")) > 1 {
+		return nil, false
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(stream.data))
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(io.LimitReader(reader, maxProbeStreamSize))
+	if err != nil && len(content) == 0 {
+		return nil, false
+	}
+	return content, true
+}
+
+// countTextChars 统计内容流中文本绘制操作的字符数，十六进制字符串按每两位一个字符计算
+func countTextChars(content []byte) int {
+	chars := 0
+	for _, m := range pdfLiteralTextPattern.FindAllSubmatch(content, -1) {
+		chars += literalTextLength(m[1])
+	}
+	for _, m := range pdfHexTextPattern.FindAllSubmatch(content, -1) {
+		chars += hexTextLength(m[1])
+	}
+	for _, m := range pdfArrayTextPattern.FindAllSubmatch(content, -1) {
+		for _, s := range pdfArrayLiteralPattern.FindAllSubmatch(m[1], -1) {
+			chars += literalTextLength(s[1])
+		}
+		for _, s := range pdfArrayHexPattern.FindAllSubmatch(m[1], -1) {
+			chars += hexTextLength(s[1])
+		}
+	}
+	return chars
+}
+
+// literalTextLength 返回字面字符串中的非空白字符数，转义序列按一个字符计算
+func literalTextLength(s []byte) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+		} else if s[i] == ' ' || s[i] == '\t' || s[i] == '\r' || s[i] == '\n' {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// hexTextLength 返回十六进制字符串表示的字节数
+func hexTextLength(s []byte) int {
+	digits := 0
+	for _, c := range s {
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			digits++
+		}
+	}
+	return digits / 2
+}
@@ -0,0 +1,78 @@
+package ocr
+
+import (
+	"context"
+	"time"
+)
+
+// adaptiveTimeout 表示按文件大小计算请求超时时间的参数：base + perMB*大小(MB)，不超过max
+type adaptiveTimeout struct {
+	base  time.Duration
+	perMB time.Duration
+	max   time.Duration // 非正数表示不限制
+}
+
+// SetAdaptiveTimeout 启用按文件大小计算的超时时间，超时时间为 base + perMB*文件大小(MB)，最长不超过max
+//
+// 用于上传请求和已知文档大小的OCR请求（见 WithDocumentSize），代替 SetTimeout 设置的固定超时时间，
+// 使小文件不必等待过长时间、大文件不会过早超时。通过 SetOCRTimeout 显式设置的OCR超时时间优先。
+// max为非正数表示不限制上限，base和perMB都为非正数时关闭。
+func (c *Client) SetAdaptiveTimeout(base time.Duration, perMB time.Duration, max time.Duration) {
+	if base <= 0 && perMB <= 0 {
+		c.adaptiveTimeout = nil
+		return
+	}
+	c.adaptiveTimeout = &adaptiveTimeout{base: base, perMB: perMB, max: max}
+}
+
+// AdaptiveTimeout 返回指定大小的文件使用的超时时间，未启用 SetAdaptiveTimeout 时返回false
+func (c *Client) AdaptiveTimeout(sizeBytes int64) (time.Duration, bool) {
+	if c.adaptiveTimeout == nil || sizeBytes < 0 {
+		return 0, false
+	}
+	at := c.adaptiveTimeout
+	sizeMB := float64(sizeBytes) / 1024 / 1024
+	timeout := at.base + time.Duration(float64(at.perMB)*sizeMB)
+	if at.max > 0 && timeout > at.max {
+		timeout = at.max
+	}
+	return timeout, true
+}
+
+type documentSizeKey struct{}
+
+// WithDocumentSize 返回携带文档大小（字节）的上下文，启用 SetAdaptiveTimeout 时OCR请求据此计算超时时间
+func WithDocumentSize(ctx context.Context, sizeBytes int64) context.Context {
+	return context.WithValue(ctx, documentSizeKey{}, sizeBytes)
+}
+
+// documentSizeFromContext 返回上下文中的文档大小，没有时返回false
+func documentSizeFromContext(ctx context.Context) (int64, bool) {
+	size, ok := ctx.Value(documentSizeKey{}).(int64)
+	return size, ok
+}
+
+// uploadTimeout 返回上传指定大小内容时使用的超时时间
+func (c *Client) uploadTimeout(sizeBytes int64) time.Duration {
+	if timeout, ok := c.AdaptiveTimeout(sizeBytes); ok {
+		c.printf("按文件大小计算的上传超时时间: %v\n", timeout)
+		return timeout
+	}
+	return c.httpTimeout
+}
+
+// ocrRequestTimeout 返回OCR请求使用的超时时间
+//
+// 优先使用 SetOCRTimeout 显式设置的超时时间，其次在上下文携带文档大小时按大小计算，否则与普通请求相同。
+func (c *Client) ocrRequestTimeout(ctx context.Context) time.Duration {
+	if c.ocrTimeout > 0 {
+		return c.ocrTimeout
+	}
+	if size, ok := documentSizeFromContext(ctx); ok {
+		if timeout, ok := c.AdaptiveTimeout(size); ok {
+			c.printf("按文件大小计算的OCR超时时间: %v\n", timeout)
+			return timeout
+		}
+	}
+	return c.httpTimeout
+}
@@ -0,0 +1,87 @@
+package ocr
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PhaseTimings 记录处理单个文件时各阶段的耗时，用于判断瓶颈在API还是本地磁盘
+//
+// 每个阶段包括该阶段的所有重试。metadata.json 在保存阶段写入，因此其中不包含保存耗时；
+// 处理结果和批量处理清单中的记录是完整的。
+type PhaseTimings struct {
+	Upload    time.Duration // 上传文件（包括下载URL指向的文件和本地解密）
+	SignedURL time.Duration // 获取签名URL
+	OCR       time.Duration // 等待OCR结果
+	Save      time.Duration // 生成并写入输出文件
+}
+
+// phaseTimingsJSON PhaseTimings 的JSON形式，耗时以可读的字符串表示，为0的阶段省略
+type phaseTimingsJSON struct {
+	Upload    string `json:"upload,omitempty"`
+	SignedURL string `json:"signed_url,omitempty"`
+	OCR       string `json:"ocr,omitempty"`
+	Save      string `json:"save,omitempty"`
+	Total     string `json:"total,omitempty"`
+}
+
+// Total 返回各阶段耗时之和
+func (t PhaseTimings) Total() time.Duration {
+	return t.Upload + t.SignedURL + t.OCR + t.Save
+}
+
+// Add 将other的各阶段耗时累加到t，用于汇总多个文件
+func (t *PhaseTimings) Add(other PhaseTimings) {
+	t.Upload += other.Upload
+	t.SignedURL += other.SignedURL
+	t.OCR += other.OCR
+	t.Save += other.Save
+}
+
+// MarshalJSON 实现 json.Marshaler 接口
+func (t PhaseTimings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(phaseTimingsJSON{
+		Upload:    formatPhaseDuration(t.Upload),
+		SignedURL: formatPhaseDuration(t.SignedURL),
+		OCR:       formatPhaseDuration(t.OCR),
+		Save:      formatPhaseDuration(t.Save),
+		Total:     formatPhaseDuration(t.Total()),
+	})
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 接口
+func (t *PhaseTimings) UnmarshalJSON(data []byte) error {
+	var raw phaseTimingsJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var err error
+	for _, field := range []struct {
+		value string
+		dst   *time.Duration
+	}{
+		{raw.Upload, &t.Upload},
+		{raw.SignedURL, &t.SignedURL},
+		{raw.OCR, &t.OCR},
+		{raw.Save, &t.Save},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if *field.dst, err = time.ParseDuration(field.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatPhaseDuration 将耗时格式化为精确到毫秒（不足1毫秒时精确到微秒）的字符串，0返回空字符串
+func formatPhaseDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,69 @@
+package ocr
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// SetCACertFile 信任指定PEM文件中的CA证书（在系统证书之外），用于企业CA签发证书的自托管网关
+func (c *Client) SetCACertFile(path string) error {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取CA证书文件失败: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemData) {
+		return fmt.Errorf("CA证书文件中没有有效的PEM证书: %s", path)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tlsConfigLocked().RootCAs = pool
+	return nil
+}
+
+// SetInsecureSkipVerify 设置是否跳过TLS证书校验，只应在测试环境中使用
+//
+// 本方法不输出任何提示，由调用方通过自己的日志提醒使用者连接可能被窃听或篡改。
+func (c *Client) SetInsecureSkipVerify(skip bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !skip && (c.transport == nil || c.transport.TLSClientConfig == nil) {
+		return
+	}
+	c.tlsConfigLocked().InsecureSkipVerify = skip
+}
+
+// tlsConfigLocked 返回客户端专用Transport的TLS配置，第一次调用时创建，调用方需持有c.mu
+func (c *Client) tlsConfigLocked() *tls.Config {
+	if c.transport == nil {
+		c.transport = newTransport()
+	}
+	if c.transport.TLSClientConfig == nil {
+		c.transport.TLSClientConfig = &tls.Config{}
+	}
+	return c.transport.TLSClientConfig
+}
+
+// httpClient 创建指定超时时间的HTTP客户端
+//
+// 每次请求的超时时间不同，因此每次创建新的 http.Client，但都使用客户端专用的Transport，
+// 连接（包括HTTP/2连接）在各请求和各文件之间复用。
+func (c *Client) httpClient(timeout time.Duration) *http.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client := &http.Client{Timeout: timeout}
+	if c.transport != nil {
+		client.Transport = c.transport
+	}
+	return client
+}
@@ -0,0 +1,105 @@
+package ocr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// TOCFileName 目录文件的文件名
+const TOCFileName = "toc.md"
+
+// headingPattern 匹配ATX风格的markdown标题，如 "## 标题"
+var headingPattern = regexp.MustCompile(`^(#{1,6})[ \t]+(.+?)(?:[ \t]+#+)?[ \t]*$`)
+
+// tocHeading 表示目录中的一个标题
+type tocHeading struct {
+	Level  int
+	Text   string
+	Anchor string
+}
+
+// parseHeadings 解析markdown中的标题，忽略代码块中的内容，并为每个标题生成唯一的锚点
+func parseHeadings(markdown string) []tocHeading {
+	var headings []tocHeading
+	usedAnchors := make(map[string]int)
+	inFence := false
+
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		match := headingPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+
+		text := strings.TrimSpace(match[2])
+		anchor := headingAnchor(text)
+
+		// 重复的标题按出现顺序添加 -1、-2 后缀，与常见markdown渲染器保持一致
+		if count, ok := usedAnchors[anchor]; ok {
+			for {
+				count++
+				candidate := fmt.Sprintf("%s-%d", anchor, count)
+				if _, exists := usedAnchors[candidate]; !exists {
+					usedAnchors[anchor] = count
+					anchor = candidate
+					break
+				}
+			}
+		}
+		usedAnchors[anchor] = 0
+
+		headings = append(headings, tocHeading{Level: len(match[1]), Text: text, Anchor: anchor})
+	}
+
+	return headings
+}
+
+// headingAnchor 按GitHub的规则将标题文本转换为锚点：转为小写，去除标点，空格替换为连字符
+func headingAnchor(text string) string {
+	var anchor strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '-' || r == '_':
+			anchor.WriteRune(r)
+		case r == ' ':
+			anchor.WriteRune('-')
+		}
+	}
+	return anchor.String()
+}
+
+// buildTOC 根据markdown中的标题生成嵌套的目录列表，链接指向target文件中的锚点
+//
+// 没有标题时返回空字符串。
+func buildTOC(markdown string, target string) string {
+	headings := parseHeadings(markdown)
+	if len(headings) == 0 {
+		return ""
+	}
+
+	// 以最高级别的标题作为顶层，避免文档没有一级标题时整体缩进
+	minLevel := headings[0].Level
+	for _, heading := range headings {
+		if heading.Level < minLevel {
+			minLevel = heading.Level
+		}
+	}
+
+	var toc strings.Builder
+	toc.WriteString("# 目录\n\n")
+	for _, heading := range headings {
+		indent := strings.Repeat("  ", heading.Level-minLevel)
+		toc.WriteString(fmt.Sprintf("%s- [%s](%s#%s)\n", indent, heading.Text, target, heading.Anchor))
+	}
+	return toc.String()
+}
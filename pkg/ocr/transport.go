@@ -0,0 +1,30 @@
+package ocr
+
+import (
+	"io"
+	"net/http"
+)
+
+// defaultMaxIdleConnsPerHost 每个端点保留的空闲连接数，默认Transport只保留2个，并发处理时连接会被频繁关闭重建
+const defaultMaxIdleConnsPerHost = 16
+
+// maxDrainBytes 关闭响应体前最多读取并丢弃的字节数，超过时直接关闭连接，避免为复用连接读取大量数据
+const maxDrainBytes = 256 << 10
+
+// newTransport 创建客户端专用的Transport
+//
+// 基于默认Transport，启用keep-alive和HTTP/2（ForceAttemptHTTP2，配置了TLS时同样生效），
+// 同一客户端的上传、获取签名URL和OCR请求以及批量处理中的各个文件共用连接池，
+// 对同一端点只需建立一次TCP连接和TLS握手。
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	return transport
+}
+
+// drainAndClose 读取并丢弃响应体中剩余的内容后关闭，使连接可以放回连接池复用
+func drainAndClose(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, maxDrainBytes))
+	body.Close()
+}
@@ -0,0 +1,71 @@
+package ocr
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Validate 校验选项的取值和组合，所有处理入口在开始处理前调用，也可以在创建选项后提前调用
+//
+// 取值超出范围（如负数的页数、未知的图片命名方式或输出格式）以及相互矛盾的组合
+// （如只改写图片链接却不保留链接）返回说明原因的错误，而不是静默地生成不符合预期的输出。
+func (o ProcessOptions) Validate() error {
+	if err := o.validateModes(); err != nil {
+		return err
+	}
+	if err := o.validateImageOptions(); err != nil {
+		return err
+	}
+	if err := o.validateBundleImages(); err != nil {
+		return err
+	}
+	if err := o.validateImageNaming(); err != nil {
+		return err
+	}
+	if err := o.validateOnImageError(); err != nil {
+		return err
+	}
+	if err := o.validateOutputFormats(); err != nil {
+		return err
+	}
+	if err := o.validateOutputFiles(); err != nil {
+		return err
+	}
+	if err := o.validateStartPosition(); err != nil {
+		return err
+	}
+	if err := o.validateSortOrder(); err != nil {
+		return err
+	}
+	if err := o.validateMarkdownTemplate(); err != nil {
+		return err
+	}
+	if err := o.validateArchiveSource(); err != nil {
+		return err
+	}
+	if o.MinPageTextLength < 0 {
+		return fmt.Errorf("无效的最短页面文本长度 %d，不能为负数", o.MinPageTextLength)
+	}
+	return o.validateChunkPages()
+}
+
+// validateImageOptions 校验细分的图片选项的组合
+//
+// NoImages 和 IncludeImages 按文档优先于细分选项，不视为矛盾。
+func (o ProcessOptions) validateImageOptions() error {
+	if o.NoImages || o.IncludeImages {
+		return nil
+	}
+	if o.LinkImages && !o.KeepImagesInText {
+		return fmt.Errorf("改写图片链接（LinkImages）需要同时保留图片链接（KeepImagesInText），否则所有图片链接都会被移除")
+	}
+	return nil
+}
+
+// validateOutputFiles 校验追加输出的文件，合并输出和语料不能写入同一个文件
+func (o ProcessOptions) validateOutputFiles() error {
+	if o.AppendTo != "" && o.CorpusFile != "" && filepath.Clean(o.AppendTo) == filepath.Clean(o.CorpusFile) {
+		return fmt.Errorf("合并输出文件（AppendTo）和语料文件（CorpusFile）不能是同一个文件: %s", o.AppendTo)
+	}
+	return nil
+}
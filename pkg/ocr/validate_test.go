@@ -0,0 +1,93 @@
+package ocr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessOptionsValidate(t *testing.T) {
+	dir := t.TempDir()
+	goodTemplate := filepath.Join(dir, "good.tmpl")
+	badTemplate := filepath.Join(dir, "bad.tmpl")
+	if err := os.WriteFile(goodTemplate, []byte("{{.Content}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(badTemplate, []byte("{{.Content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    ProcessOptions
+		wantErr string // 为空表示应通过校验
+	}{
+		{name: "零值", opts: ProcessOptions{}},
+
+		{name: "文件权限有效", opts: ProcessOptions{FileMode: 0o640, DirMode: 0o750}},
+		{name: "文件权限所有者不可写", opts: ProcessOptions{FileMode: 0o444}, wantErr: "无效的文件权限"},
+		{name: "文件权限超出范围", opts: ProcessOptions{FileMode: os.ModeDir | 0o644}, wantErr: "无效的文件权限"},
+		{name: "目录权限所有者不可进入", opts: ProcessOptions{DirMode: 0o600}, wantErr: "无效的目录权限"},
+
+		{name: "改写链接并保留链接", opts: ProcessOptions{LinkImages: true, KeepImagesInText: true}},
+		{name: "改写链接但不保留链接", opts: ProcessOptions{LinkImages: true}, wantErr: "LinkImages"},
+		{name: "NoImages优先于改写链接", opts: ProcessOptions{NoImages: true, LinkImages: true}},
+		{name: "IncludeImages优先于改写链接", opts: ProcessOptions{IncludeImages: true, LinkImages: true}},
+
+		{name: "合并图片为PDF", opts: ProcessOptions{BundleImages: BundleImagesPDF}},
+		{name: "未知的图片合并方式", opts: ProcessOptions{BundleImages: "zip"}, wantErr: "无效的图片合并方式"},
+
+		{name: "按位置命名图片", opts: ProcessOptions{ImageNaming: ImageNamingPositional}},
+		{name: "未知的图片命名方式", opts: ProcessOptions{ImageNaming: "random"}, wantErr: "无效的图片命名方式"},
+
+		{name: "图片解码失败时使用占位图", opts: ProcessOptions{OnImageError: OnImageErrorPlaceholder}},
+		{name: "未知的图片解码失败处理方式", opts: ProcessOptions{OnImageError: "abort"}, wantErr: "无效的图片解码失败处理方式"},
+
+		{name: "额外输出LaTeX", opts: ProcessOptions{OutputFormats: []string{OutputFormatLaTeX}}},
+		{name: "未知的输出格式", opts: ProcessOptions{OutputFormats: []string{OutputFormatLaTeX, "docx"}}, wantErr: "无效的输出格式"},
+
+		{name: "合并输出和语料为不同文件", opts: ProcessOptions{AppendTo: "all.md", CorpusFile: "corpus.jsonl"}},
+		{name: "合并输出和语料为同一文件", opts: ProcessOptions{AppendTo: "out/all.md", CorpusFile: "out/./all.md"}, wantErr: "不能是同一个文件"},
+
+		{name: "起始位置", opts: ProcessOptions{StartAt: 3}},
+		{name: "负数的起始位置", opts: ProcessOptions{StartAt: -1}, wantErr: "无效的起始位置"},
+		{name: "同时指定起始位置和起始文件", opts: ProcessOptions{StartAt: 2, StartAfter: "a.pdf"}, wantErr: "不能同时指定"},
+
+		{name: "自然排序", opts: ProcessOptions{SortOrder: SortOrderNatural}},
+		{name: "未知的排序方式", opts: ProcessOptions{SortOrder: "mtime"}, wantErr: "无效的排序方式"},
+
+		{name: "有效的markdown模板", opts: ProcessOptions{MarkdownTemplate: goodTemplate}},
+		{name: "不存在的markdown模板", opts: ProcessOptions{MarkdownTemplate: filepath.Join(dir, "missing.tmpl")}, wantErr: "读取markdown模板失败"},
+		{name: "无法解析的markdown模板", opts: ProcessOptions{MarkdownTemplate: badTemplate}, wantErr: "解析markdown模板失败"},
+
+		{name: "启用缓存时归档上传内容", opts: ProcessOptions{ArchiveSource: true, CacheDir: dir}},
+		{name: "未启用缓存时归档上传内容", opts: ProcessOptions{ArchiveSource: true}, wantErr: "需要启用缓存"},
+
+		{name: "负数的最短页面文本长度", opts: ProcessOptions{MinPageTextLength: -1}, wantErr: "无效的最短页面文本长度"},
+
+		{name: "分批和最大页数", opts: ProcessOptions{ChunkPages: 10, MaxPages: 25}},
+		{name: "负数的分批页数", opts: ProcessOptions{ChunkPages: -1}, wantErr: "无效的分批页数"},
+		{name: "负数的最大页数", opts: ProcessOptions{MaxPages: -1}, wantErr: "无效的最大页数"},
+		{name: "分批与流式解析", opts: ProcessOptions{ChunkPages: 10, StreamLargeResponses: true}, wantErr: "分批处理页面不能与流式解析同时使用"},
+		{name: "最大页数与流式解析", opts: ProcessOptions{MaxPages: 10, StreamLargeResponses: true}, wantErr: "限制最大页数不能与流式解析同时使用"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() 返回错误: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() 没有返回错误，期望包含 %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %q，期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package ocr
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// WordsDirName 保存每页单词位置文件的子目录名
+const WordsDirName = "words"
+
+// Word 表示页面中的单个单词及其位置，只有API返回单词级信息时才有
+type Word struct {
+	Text       string       `json:"text"`
+	BBox       *BoundingBox `json:"bbox,omitempty"`       // 单词在页面图像中的位置（像素）
+	Confidence *float64     `json:"confidence,omitempty"` // 识别置信度（0到1）
+}
+
+// BoundingBox 表示矩形区域，坐标与 Image 相同，以页面图像左上角为原点
+type BoundingBox struct {
+	TopLeftX     float64 `json:"top_left_x"`
+	TopLeftY     float64 `json:"top_left_y"`
+	BottomRightX float64 `json:"bottom_right_x"`
+	BottomRightY float64 `json:"bottom_right_y"`
+}
+
+// PageWords 表示写入 words/page-N.json 的单页单词信息，可用于在页面图像上叠加文本生成可搜索的PDF
+type PageWords struct {
+	Index      int      `json:"index"`                // OCR响应中的页面索引（从0开始）
+	Width      int      `json:"width,omitempty"`      // 页面宽度（像素）
+	Height     int      `json:"height,omitempty"`     // 页面高度（像素）
+	DPI        int      `json:"dpi,omitempty"`        // 页面分辨率
+	Confidence *float64 `json:"confidence,omitempty"` // 页面整体的识别置信度
+	Words      []Word   `json:"words"`
+}
+
+// saveWordFiles 将每页的单词、位置和置信度写入 words 子目录，返回写入的文件数
+//
+// 只为包含单词信息的页面生成文件，响应中没有单词信息时不创建目录。
+func (p *Processor) saveWordFiles(pages []Page, outputDir string, opts ProcessOptions) (int, error) {
+	var withWords []Page
+	for _, page := range pages {
+		if len(page.Words) > 0 {
+			withWords = append(withWords, page)
+		}
+	}
+	if len(withWords) == 0 {
+		p.logger.Debug("响应中没有单词级信息，跳过生成单词位置文件")
+		return 0, nil
+	}
+
+	wordsDir := filepath.Join(outputDir, WordsDirName)
+	if err := opts.mkdirAll(wordsDir); err != nil {
+		return 0, fmt.Errorf("创建words子目录错误: %w", err)
+	}
+
+	// 页码的位数与 savePageFiles 一致，保证文件名排序与页面顺序一致
+	width := len(strconv.Itoa(len(pages) - 1))
+	for _, page := range withWords {
+		data, err := json.MarshalIndent(PageWords{
+			Index:      page.Index,
+			Width:      page.Dimensions.Width,
+			Height:     page.Dimensions.Height,
+			DPI:        page.Dimensions.DPI,
+			Confidence: page.Confidence,
+			Words:      page.Words,
+		}, "", "  ")
+		if err != nil {
+			return 0, fmt.Errorf("生成单词位置数据失败: %w", err)
+		}
+		wordsPath := filepath.Join(wordsDir, fmt.Sprintf("page-%0*d.json", width, page.Index))
+		if err := opts.writeFile(wordsPath, data); err != nil {
+			return 0, fmt.Errorf("保存单词位置文件错误: %w", err)
+		}
+	}
+	p.logger.Debug("保存了单词位置文件", zap.String("dir", wordsDir), zap.Int("pages", len(withWords)))
+	return len(withWords), nil
+}
@@ -13,6 +13,7 @@ import (
 type ProgressTracker struct {
 	bar       *progressbar.ProgressBar
 	startTime time.Time
+	stepStart time.Time // 开始计数步数的时间，SetTotal 时重置，用于计算剩余时间和速度
 	title     string
 	steps     int
 	current   int
@@ -39,25 +40,69 @@ func NewProgressTracker(title string, steps int) *ProgressTracker {
 	return &ProgressTracker{
 		bar:       bar,
 		startTime: time.Now(),
+		stepStart: time.Now(),
 		title:     title,
 		steps:     steps,
 		current:   0,
 	}
 }
 
-// Step 进度前进一步
+// NewSpinnerTracker 创建一个总步数未知的进度跟踪器，以旋转指示器显示，
+// 得知总步数后可以通过 SetTotal 切换为进度条
+func NewSpinnerTracker(title string) *ProgressTracker {
+	return NewProgressTracker(title, -1)
+}
+
+// SetTotal 设置总步数并重置当前进度，用于从旋转指示器切换为进度条
+func (pt *ProgressTracker) SetTotal(total int) {
+	pt.steps = total
+	pt.current = 0
+	pt.stepStart = time.Now()
+	pt.bar.Reset()
+	pt.bar.ChangeMax(total)
+}
+
+// Describe 更新描述而不前进进度
+func (pt *ProgressTracker) Describe(description string) {
+	elapsed := time.Since(pt.startTime)
+	pt.bar.Describe(fmt.Sprintf("[cyan]%s[reset] - %s (%s)", pt.title, description, formatDuration(elapsed)))
+	if pt.steps < 0 {
+		// 旋转指示器需要渲染一次才会开始转动
+		pt.bar.Add(0)
+	}
+}
+
+// Step 进度前进一步，描述中包含已用时间，以及按平均每步耗时估计的剩余时间和每分钟完成的步数
 func (pt *ProgressTracker) Step(description string) {
 	pt.current++
 	elapsed := time.Since(pt.startTime)
-	descWithTime := fmt.Sprintf("%s (%s)", description, formatDuration(elapsed))
+	descWithTime := fmt.Sprintf("%s (%s%s)", description, formatDuration(elapsed), pt.rateAndETA())
 	pt.bar.Describe(fmt.Sprintf("[cyan]%s[reset] - %s", pt.title, descWithTime))
 	pt.bar.Add(1)
 }
 
+// rateAndETA 根据开始计数以来的平均每步耗时返回剩余时间和速度，如 ", 剩余2m10s, 12.5/min"，无法估计时返回空字符串
+func (pt *ProgressTracker) rateAndETA() string {
+	stepElapsed := time.Since(pt.stepStart)
+	if pt.current <= 0 || stepElapsed <= 0 {
+		return ""
+	}
+	rate := float64(pt.current) / stepElapsed.Minutes()
+	if pt.steps <= 0 || pt.current >= pt.steps {
+		return fmt.Sprintf(", %.1f/min", rate)
+	}
+	remaining := stepElapsed / time.Duration(pt.current) * time.Duration(pt.steps-pt.current)
+	return fmt.Sprintf(", 剩余%s, %.1f/min", formatDuration(remaining), rate)
+}
+
 // Complete 完成进度
 func (pt *ProgressTracker) Complete() time.Duration {
 	elapsed := time.Since(pt.startTime)
 	// 确保进度条显示完成
+	if pt.steps < 0 {
+		pt.bar.Finish()
+		return elapsed
+	}
 	for pt.current < pt.steps {
 		pt.Step("完成")
 	}